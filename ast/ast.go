@@ -1,10 +1,17 @@
 // Package ast declares the types used to represent abstract syntax trees of
 // Graphviz DOT graphs.
+//
+// IDs are printed as is if valid DOT identifiers, and double-quoted otherwise.
+// Keywords are always double-quoted. Thus the string representation of an
+// abstract syntax tree is a valid DOT file, even if constructed with unquoted
+// IDs.
 package ast
 
 import (
 	"bytes"
 	"fmt"
+
+	"github.com/graphism/dot/internal/enc"
 )
 
 // === [ File ] ================================================================
@@ -69,7 +76,7 @@ func (g *Graph) String() string {
 		buf.WriteString("graph ")
 	}
 	if len(g.ID) > 0 {
-		fmt.Fprintf(buf, "%s ", g.ID)
+		fmt.Fprintf(buf, "%s ", enc.Quote(g.ID))
 	}
 	buf.WriteString("{\n")
 	for _, stmt := range g.Stmts {
@@ -252,7 +259,7 @@ type Attr struct {
 
 // String returns the string representation of the attribute.
 func (a *Attr) String() string {
-	return fmt.Sprintf("%s=%s", a.Key, a.Val)
+	return fmt.Sprintf("%s=%s", enc.Quote(a.Key), enc.Quote(a.Val))
 }
 
 // --- [ Subgraph ] ------------------------------------------------------------
@@ -273,7 +280,7 @@ type Subgraph struct {
 func (s *Subgraph) String() string {
 	buf := new(bytes.Buffer)
 	if len(s.ID) > 0 {
-		fmt.Fprintf(buf, "subgraph %s ", s.ID)
+		fmt.Fprintf(buf, "subgraph %s ", enc.Quote(s.ID))
 	}
 	buf.WriteString("{")
 	for i, stmt := range s.Stmts {
@@ -324,9 +331,9 @@ type Node struct {
 // String returns the string representation of the node.
 func (n *Node) String() string {
 	if n.Port != nil {
-		return fmt.Sprintf("%s%s", n.ID, n.Port)
+		return fmt.Sprintf("%s%s", enc.Quote(n.ID), n.Port)
 	}
-	return n.ID
+	return enc.Quote(n.ID)
 }
 
 // A Port specifies where on a node an edge should be aimed.
@@ -335,20 +342,38 @@ type Port struct {
 	ID string
 	// Compass point.
 	CompassPoint CompassPoint
+	// Invalid compass point, which is ignored by Graphviz; or empty if none.
+	InvalidCompassPoint string
 }
 
 // String returns the string representation of the port.
 func (p *Port) String() string {
 	buf := new(bytes.Buffer)
 	if len(p.ID) > 0 {
-		fmt.Fprintf(buf, ":%s", p.ID)
+		fmt.Fprintf(buf, ":%s", enc.Quote(p.ID))
 	}
-	if p.CompassPoint != CompassPointDefault {
+	// The default compass point is printed if the port ID would otherwise be
+	// mistaken for a compass point.
+	switch {
+	case len(p.InvalidCompassPoint) > 0:
+		fmt.Fprintf(buf, ":%s", enc.Quote(p.InvalidCompassPoint))
+	case p.CompassPoint != CompassPointDefault || isCompassPoint(p.ID):
 		fmt.Fprintf(buf, ":%s", p.CompassPoint)
 	}
 	return buf.String()
 }
 
+// isCompassPoint reports whether the given port ID is a compass point.
+func isCompassPoint(id string) bool {
+	id = enc.Unquote(id)
+	for c := CompassPointDefault; c <= CompassPointCenter; c++ {
+		if id == c.String() {
+			return true
+		}
+	}
+	return false
+}
+
 // CompassPoint specifies the set of compass points.
 type CompassPoint uint
 
@@ -75,6 +75,94 @@ func TestParseFile(t *testing.T) {
 	}
 }
 
+func TestPrintQuote(t *testing.T) {
+	golden := []struct {
+		graph *ast.Graph
+		want  string
+	}{
+		{
+			graph: &ast.Graph{
+				ID: "my graph",
+				Stmts: []ast.Stmt{
+					&ast.Attr{Key: "label", Val: `a"b`},
+					&ast.NodeStmt{
+						Node:  &ast.Node{ID: "my node"},
+						Attrs: []*ast.Attr{{Key: "font name", Val: ""}},
+					},
+					&ast.EdgeStmt{
+						From: &ast.Node{ID: "node"},
+						To:   &ast.Edge{Vertex: &ast.Node{ID: "Graph"}},
+					},
+				},
+			},
+			want: "graph \"my graph\" {\n\tlabel=\"a\\\"b\"\n\t\"my node\" [\"font name\"=\"\"]\n\t\"node\" -- \"Graph\"\n}",
+		},
+		{
+			// Valid IDs are printed as is.
+			graph: &ast.Graph{
+				Directed: true,
+				ID:       `"G"`,
+				Stmts: []ast.Stmt{
+					&ast.NodeStmt{
+						Node:  &ast.Node{ID: "-1.5"},
+						Attrs: []*ast.Attr{{Key: "label", Val: "<<b>x</b>>"}},
+					},
+					&ast.Attr{Key: "_a1", Val: `"x\"y"`},
+				},
+			},
+			want: "digraph \"G\" {\n\t-1.5 [label=<<b>x</b>>]\n\t_a1=\"x\\\"y\"\n}",
+		},
+		{
+			// Backslashes.
+			graph: &ast.Graph{
+				Stmts: []ast.Stmt{
+					&ast.Attr{Key: "a", Val: `C:\`},
+					&ast.Attr{Key: "b", Val: `x\"y`},
+					&ast.Attr{Key: "c", Val: `\N\\`},
+					&ast.Attr{Key: "d", Val: `<a`},
+				},
+			},
+			want: "graph {\n\ta=\"C:\\\\\"\n\tb=\"x\\\\\\\"y\"\n\tc=\"\\N\\\\\"\n\td=\"<a\"\n}",
+		},
+		{
+			// Ports and subgraphs.
+			graph: &ast.Graph{
+				Stmts: []ast.Stmt{
+					&ast.EdgeStmt{
+						From: &ast.Node{ID: "a", Port: &ast.Port{ID: "n"}},
+						To: &ast.Edge{Vertex: &ast.Subgraph{
+							ID:    "sub graph",
+							Stmts: []ast.Stmt{&ast.NodeStmt{Node: &ast.Node{ID: "b", Port: &ast.Port{ID: "in put", CompassPoint: ast.CompassPointSouth}}}},
+						}},
+					},
+					&ast.Subgraph{ID: "subgraph"},
+					&ast.EdgeStmt{
+						From: &ast.Node{ID: "c", Port: &ast.Port{ID: "p", InvalidCompassPoint: "x"}},
+						To:   &ast.Edge{Vertex: &ast.Node{ID: "d"}},
+					},
+				},
+			},
+			want: "graph {\n\ta:n:_ -- subgraph \"sub graph\" {b:\"in put\":s}\n\tsubgraph \"subgraph\" {}\n\tc:p:x -- d\n}",
+		},
+	}
+	for _, g := range golden {
+		got := g.graph.String()
+		if got != g.want {
+			t.Errorf("graph mismatch; expected %q, got %q", g.want, got)
+			continue
+		}
+		// Verify that the output re-parses to the same graph.
+		file, err := dot.ParseString(got)
+		if err != nil {
+			t.Errorf("%q: unable to parse graph; %v", got, err)
+			continue
+		}
+		if len(file.Graphs) != 1 || file.Graphs[0].String() != got {
+			t.Errorf("%q: re-parsed graph mismatch; got %q", got, file)
+		}
+	}
+}
+
 // Verify that all statements implement the Stmt interface.
 var (
 	_ ast.Stmt = &ast.NodeStmt{}
@@ -0,0 +1,253 @@
+// Package builder implements a fluent API for building Graphviz DOT graphs.
+//
+// IDs, attribute keys and attribute values are text, and are double-quoted as
+// needed; thus never interpreted as double-quoted strings or HTML strings.
+// HTML-like labels are set explicitly through HTMLLabel. The resulting graphs
+// are emitted through the printer of the ast package.
+//
+//    g := builder.Digraph("G")
+//    a := g.Node("A").Attr("shape", "box")
+//    g.Cluster("c1", func(c *builder.Builder) {
+//       b := c.Node("my node")
+//       c.Edge(a, b).Label("x")
+//    })
+//    fmt.Println(g)
+//
+// Note, as backslashes escape the character following them in double-quoted
+// strings, text with an odd number of backslashes preceding a double quote, a
+// newline or the end of the text is not preserved.
+package builder
+
+import (
+	"strings"
+
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/internal/enc"
+)
+
+// === [ Graphs ] ==============================================================
+
+// A Builder builds the statements of a graph or subgraph.
+type Builder struct {
+	// Root graph.
+	graph *ast.Graph
+	// Statements of the graph or subgraph.
+	stmts *[]ast.Stmt
+	// nodes maps from unquoted node ID to the node declared in the graph or
+	// subgraph.
+	nodes map[string]*Node
+}
+
+// Graph returns a builder of an undirected graph with the given ID; or an
+// anonymous graph if the ID is empty.
+func Graph(id string) *Builder {
+	return newBuilder(id, false)
+}
+
+// Digraph returns a builder of a directed graph with the given ID; or an
+// anonymous graph if the ID is empty.
+func Digraph(id string) *Builder {
+	return newBuilder(id, true)
+}
+
+// newBuilder returns a builder of a graph with the given ID.
+func newBuilder(id string, directed bool) *Builder {
+	graph := &ast.Graph{Directed: directed}
+	if len(id) > 0 {
+		graph.ID = enc.QuoteText(id)
+	}
+	return &Builder{
+		graph: graph,
+		stmts: &graph.Stmts,
+		nodes: make(map[string]*Node),
+	}
+}
+
+// Strict marks the graph as strict, thus forbidding multi-edges.
+func (b *Builder) Strict() *Builder {
+	b.graph.Strict = true
+	return b
+}
+
+// Attr sets the given attribute of the graph or subgraph.
+func (b *Builder) Attr(key, val string) *Builder {
+	*b.stmts = append(*b.stmts, newAttr(key, val))
+	return b
+}
+
+// HTMLLabel sets the label of the graph or subgraph to the given HTML-like
+// label, as described by Node.HTMLLabel.
+func (b *Builder) HTMLLabel(html string) *Builder {
+	*b.stmts = append(*b.stmts, newHTMLAttr("label", html))
+	return b
+}
+
+// NodeAttr sets the given default attribute of nodes subsequently created in
+// the graph or subgraph.
+func (b *Builder) NodeAttr(key, val string) *Builder {
+	*b.stmts = append(*b.stmts, &ast.AttrStmt{Kind: ast.KindNode, Attrs: []*ast.Attr{newAttr(key, val)}})
+	return b
+}
+
+// EdgeAttr sets the given default attribute of edges subsequently created in
+// the graph or subgraph.
+func (b *Builder) EdgeAttr(key, val string) *Builder {
+	*b.stmts = append(*b.stmts, &ast.AttrStmt{Kind: ast.KindEdge, Attrs: []*ast.Attr{newAttr(key, val)}})
+	return b
+}
+
+// Node returns the node with the given ID, declaring the node in the graph or
+// subgraph unless already declared there.
+func (b *Builder) Node(id string) *Node {
+	if n, ok := b.nodes[id]; ok {
+		return n
+	}
+	n := &Node{id: id, stmt: &ast.NodeStmt{Node: &ast.Node{ID: enc.QuoteText(id)}}}
+	b.nodes[id] = n
+	*b.stmts = append(*b.stmts, n.stmt)
+	return n
+}
+
+// Edge adds an edge from the given source node to the given destination node
+// to the graph or subgraph, and returns the edge.
+func (b *Builder) Edge(from, to *Node) *Edge {
+	stmt := &ast.EdgeStmt{
+		From: &ast.Node{ID: from.stmt.Node.ID},
+		To: &ast.Edge{
+			Directed: b.graph.Directed,
+			Vertex:   &ast.Node{ID: to.stmt.Node.ID},
+		},
+	}
+	*b.stmts = append(*b.stmts, stmt)
+	return &Edge{stmt: stmt}
+}
+
+// Subgraph adds a subgraph with the given ID to the graph or subgraph; or an
+// anonymous subgraph if the ID is empty. The statements of the subgraph are
+// built by f.
+func (b *Builder) Subgraph(id string, f func(sub *Builder)) *Builder {
+	sub := &ast.Subgraph{}
+	if len(id) > 0 {
+		sub.ID = enc.QuoteText(id)
+	}
+	*b.stmts = append(*b.stmts, sub)
+	f(&Builder{
+		graph: b.graph,
+		stmts: &sub.Stmts,
+		nodes: make(map[string]*Node),
+	})
+	return b
+}
+
+// Cluster adds a cluster subgraph with the given ID to the graph or subgraph.
+// The ID is prefixed with "cluster_" unless already starting with "cluster",
+// as required by Graphviz for subgraphs drawn as clusters. The statements of
+// the cluster are built by f.
+func (b *Builder) Cluster(id string, f func(sub *Builder)) *Builder {
+	if !strings.HasPrefix(id, "cluster") {
+		id = "cluster_" + id
+	}
+	return b.Subgraph(id, f)
+}
+
+// AST returns the abstract syntax tree of the graph.
+func (b *Builder) AST() *ast.Graph {
+	return b.graph
+}
+
+// String returns the string representation of the graph.
+func (b *Builder) String() string {
+	return b.graph.String()
+}
+
+// === [ Nodes ] ===============================================================
+
+// A Node is a node declared in a graph or subgraph.
+type Node struct {
+	// Unquoted node ID.
+	id string
+	// Node statement of the node.
+	stmt *ast.NodeStmt
+}
+
+// ID returns the ID of the node.
+func (n *Node) ID() string {
+	return n.id
+}
+
+// Attr sets the given attribute of the node, replacing any previous value.
+func (n *Node) Attr(key, val string) *Node {
+	setAttr(&n.stmt.Attrs, key, val)
+	return n
+}
+
+// Label sets the label of the node.
+func (n *Node) Label(label string) *Node {
+	return n.Attr("label", label)
+}
+
+// HTMLLabel sets the label of the node to the given HTML-like label; e.g.
+// "<b>bold</b>", which is enclosed in angle brackets. The label is set as text
+// if not balanced with regards to angle brackets.
+func (n *Node) HTMLLabel(html string) *Node {
+	replaceAttr(&n.stmt.Attrs, newHTMLAttr("label", html))
+	return n
+}
+
+// === [ Edges ] ===============================================================
+
+// An Edge is an edge between two nodes.
+type Edge struct {
+	// Edge statement of the edge.
+	stmt *ast.EdgeStmt
+}
+
+// Attr sets the given attribute of the edge, replacing any previous value.
+func (e *Edge) Attr(key, val string) *Edge {
+	setAttr(&e.stmt.Attrs, key, val)
+	return e
+}
+
+// Label sets the label of the edge.
+func (e *Edge) Label(label string) *Edge {
+	return e.Attr("label", label)
+}
+
+// HTMLLabel sets the label of the edge to the given HTML-like label, as
+// described by Node.HTMLLabel.
+func (e *Edge) HTMLLabel(html string) *Edge {
+	replaceAttr(&e.stmt.Attrs, newHTMLAttr("label", html))
+	return e
+}
+
+// === [ Attributes ] ==========================================================
+
+// newAttr returns a new attribute with the given key and text value.
+func newAttr(key, val string) *ast.Attr {
+	return &ast.Attr{Key: enc.QuoteText(key), Val: enc.QuoteText(val)}
+}
+
+// newHTMLAttr returns a new attribute with the given key and HTML-like value.
+func newHTMLAttr(key, html string) *ast.Attr {
+	val := "<" + html + ">"
+	if !enc.IsID(val) {
+		val = enc.QuoteText(html)
+	}
+	return &ast.Attr{Key: enc.QuoteText(key), Val: val}
+}
+
+// setAttr sets the given attribute in attrs, replacing any previous value.
+func setAttr(attrs *[]*ast.Attr, key, val string) {
+	replaceAttr(attrs, newAttr(key, val))
+}
+
+// replaceAttr adds the given attribute to attrs, replacing any previous value.
+func replaceAttr(attrs *[]*ast.Attr, attr *ast.Attr) {
+	for i, a := range *attrs {
+		if enc.Unquote(a.Key) == enc.Unquote(attr.Key) {
+			(*attrs)[i] = attr
+			return
+		}
+	}
+	*attrs = append(*attrs, attr)
+}
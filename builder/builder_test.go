@@ -0,0 +1,91 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/builder"
+)
+
+func TestBuilder(t *testing.T) {
+	golden := []struct {
+		build func() *builder.Builder
+		want  string
+	}{
+		{
+			build: func() *builder.Builder {
+				g := builder.Digraph("G")
+				a := g.Node("A").Attr("shape", "box")
+				b := g.Node("B")
+				g.Edge(a, b).Label("x")
+				return g
+			},
+			want: "digraph G {\n\tA [shape=box]\n\tB\n\tA -> B [label=x]\n}",
+		},
+		{
+			// Quoted IDs, keys and values.
+			build: func() *builder.Builder {
+				g := builder.Graph("my graph").Strict()
+				a := g.Node("node").Label(`say "hi"`)
+				b := g.Node("C:\\dir").Attr("font name", "Times-Roman")
+				g.Edge(a, b).Attr("label", "<<b>bold</b>>").Label("3.14")
+				return g
+			},
+			want: "strict graph \"my graph\" {\n\t\"node\" [label=\"say \\\"hi\\\"\"]\n\t\"C:\\dir\" [\"font name\"=\"Times-Roman\"]\n\t\"node\" -- \"C:\\dir\" [label=3.14]\n}",
+		},
+		{
+			// Text is never interpreted as double-quoted strings or HTML strings;
+			// HTML-like labels are set explicitly.
+			build: func() *builder.Builder {
+				g := builder.Digraph(`"G"`).HTMLLabel("<i>title</i>")
+				a := g.Node(`"a"`).Label("<b>x</b>")
+				b := g.Node("<b>").Label("old").HTMLLabel("<b>bold</b>")
+				c := g.Node("c").HTMLLabel("a<b")
+				g.Edge(a, b).HTMLLabel("x &amp; y")
+				g.Edge(b, c).Attr("color", `"red"`)
+				return g
+			},
+			want: "digraph \"\\\"G\\\"\" {\n\tlabel=<<i>title</i>>\n\t\"\\\"a\\\"\" [label=\"<b>x</b>\"]\n\t\"<b>\" [label=<<b>bold</b>>]\n\tc [label=\"a<b\"]\n\t\"\\\"a\\\"\" -> \"<b>\" [label=<x &amp; y>]\n\t\"<b>\" -> c [color=\"\\\"red\\\"\"]\n}",
+		},
+		{
+			// Subgraphs, clusters and default attributes.
+			build: func() *builder.Builder {
+				g := builder.Digraph("").Attr("rankdir", "LR").NodeAttr("shape", "record")
+				a := g.Node("a")
+				g.Cluster("c1", func(c *builder.Builder) {
+					c.Attr("label", "first cluster")
+					b := c.Node("b")
+					c.EdgeAttr("color", "red")
+					c.Edge(a, b)
+					c.Subgraph("", func(s *builder.Builder) {
+						s.Attr("rank", "same")
+						s.Node("b")
+						s.Node("c")
+					})
+				})
+				g.Cluster("cluster2", func(c *builder.Builder) {
+					c.Node("a").Attr("color", "blue").Attr("color", "green")
+				})
+				return g
+			},
+			want: "digraph {\n\trankdir=LR\n\tnode [shape=record]\n\ta\n\tsubgraph cluster_c1 {label=\"first cluster\" b edge [color=red] a -> b {rank=same b c}}\n\tsubgraph cluster2 {a [color=green]}\n}",
+		},
+	}
+	for _, g := range golden {
+		b := g.build()
+		got := b.String()
+		if got != g.want {
+			t.Errorf("graph mismatch; expected `%s`, got `%s`", g.want, got)
+			continue
+		}
+		// Verify that the graph is valid.
+		file, err := dot.ParseString(got)
+		if err != nil {
+			t.Errorf("%q: unable to parse graph; %v", got, err)
+			continue
+		}
+		if file.String() != got {
+			t.Errorf("%q: re-parsed graph mismatch; got `%s`", got, file)
+		}
+	}
+}
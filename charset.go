@@ -0,0 +1,207 @@
+package dot
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/internal/enc"
+	"github.com/graphism/dot/internal/scanner"
+	"github.com/pkg/errors"
+)
+
+// === [ Character encodings ] =================================================
+
+// Character encodings of DOT files, as specified by the charset attribute of
+// graphs.
+const (
+	// UTF-8; the default character encoding.
+	CharsetUTF8 = "UTF-8"
+	// ISO-8859-1.
+	CharsetLatin1 = "latin1"
+)
+
+// bom is the UTF-8 byte order mark.
+var bom = []byte("\xEF\xBB\xBF")
+
+// lookupCharset returns the character encoding of the given charset name, and
+// a boolean value indicating if the character encoding is supported. Charset
+// names are case-insensitive, and include the aliases recognized by Graphviz.
+func lookupCharset(name string) (string, bool) {
+	switch strings.ToLower(name) {
+	case "utf-8", "utf8":
+		return CharsetUTF8, true
+	case "latin1", "latin-1", "l1", "iso-8859-1", "iso_8859-1", "iso8859-1", "iso-ir-100":
+		return CharsetLatin1, true
+	}
+	return "", false
+}
+
+// fileCharset returns the character encoding of the given DOT file, as
+// specified by the charset attribute of its first graph. Unsupported charsets
+// default to UTF-8, as by Graphviz.
+func fileCharset(file *ast.File) string {
+	if len(file.Graphs) == 0 {
+		return CharsetUTF8
+	}
+	name := ""
+	for _, stmt := range file.Graphs[0].Stmts {
+		var attrs []*ast.Attr
+		switch stmt := stmt.(type) {
+		case *ast.Attr:
+			attrs = []*ast.Attr{stmt}
+		case *ast.AttrStmt:
+			if stmt.Kind == ast.KindGraph {
+				attrs = stmt.Attrs
+			}
+		}
+		for _, attr := range attrs {
+			if enc.Unquote(attr.Key) == "charset" {
+				name = enc.Unquote(attr.Val)
+			}
+		}
+	}
+	if charset, ok := lookupCharset(name); ok {
+		return charset
+	}
+	return CharsetUTF8
+}
+
+// parseCharset parses the given DOT file, which is encoded in the charset of
+// the given parse options. If the charset is empty, the DOT file is UTF-8
+// encoded if starting with a byte order mark, and otherwise encoded as
+// specified by the charset attribute of its first graph.
+func parseCharset(ctx context.Context, b []byte, opts ParseOptions) (*ast.File, error) {
+	charset := opts.Charset
+	if len(charset) == 0 && bytes.HasPrefix(b, bom) {
+		charset = CharsetUTF8
+	}
+	b = bytes.TrimPrefix(b, bom)
+	if len(charset) > 0 {
+		cs, ok := lookupCharset(charset)
+		if !ok {
+			return nil, errors.Errorf("unsupported charset %q; expected UTF-8 or latin1", charset)
+		}
+		if cs == CharsetLatin1 {
+			b = latin1ToUTF8(b)
+		}
+		return parseSource(ctx, b, opts, opts.Positions)
+	}
+	// The charset attribute is read from the DOT file parsed as UTF-8, which is
+	// the same as parsed as Latin-1 if the DOT file is ASCII.
+	if !hasNonASCII(b) {
+		return parseSource(ctx, b, opts, opts.Positions)
+	}
+	// DOT files with non-ASCII characters are parsed again as Latin-1, if so
+	// specified, or if invalid as UTF-8 and specified as Latin-1 by a charset
+	// attribute after the first non-ASCII character. Positions are recorded
+	// for the parse of the returned AST only.
+	positions := newPositions(opts)
+	file, err := parseSource(ctx, b, opts, positions)
+	if err == nil && fileCharset(file) != CharsetLatin1 {
+		copyPositions(opts.Positions, positions)
+		return file, nil
+	}
+	latinPositions := newPositions(opts)
+	latin, latinErr := parseSource(ctx, latin1ToUTF8(b), opts, latinPositions)
+	if err != nil && (latinErr != nil || fileCharset(latin) != CharsetLatin1) {
+		return nil, errors.WithStack(err)
+	}
+	if latinErr != nil {
+		return nil, errors.WithStack(latinErr)
+	}
+	copyPositions(opts.Positions, latinPositions)
+	return latin, nil
+}
+
+// parseSource parses the given UTF-8 encoded DOT file, using the given context
+// and parse options, and recording positions in the given map if non-nil.
+func parseSource(ctx context.Context, src []byte, opts ParseOptions, positions Positions) (*ast.File, error) {
+	opts.Positions = positions
+	p := newParser(ctx, scanner.New(src), opts)
+	file, err := p.parseFile()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return file, nil
+}
+
+// newPositions returns a new map of positions if positions are recorded by the
+// given parse options; or nil otherwise.
+func newPositions(opts ParseOptions) Positions {
+	if opts.Positions == nil {
+		return nil
+	}
+	return make(Positions)
+}
+
+// copyPositions copies the positions of src into dst.
+func copyPositions(dst, src Positions) {
+	for node, span := range src {
+		dst[node] = span
+	}
+}
+
+// hasNonASCII reports whether the given text contains non-ASCII characters.
+func hasNonASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			return true
+		}
+	}
+	return false
+}
+
+// latin1ToUTF8 returns the UTF-8 encoding of the given Latin-1 encoded text.
+func latin1ToUTF8(b []byte) []byte {
+	n := 0
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			n++
+		}
+	}
+	if n == 0 {
+		return b
+	}
+	buf := make([]byte, 0, len(b)+n)
+	for _, c := range b {
+		if c < utf8.RuneSelf {
+			buf = append(buf, c)
+			continue
+		}
+		// Two-byte encoding of U+0080 through U+00FF.
+		buf = append(buf, 0xC0|c>>6, 0x80|c&0x3F)
+	}
+	return buf
+}
+
+// Encode returns the string representation of the given DOT file, encoded in
+// the given charset. If the charset is empty, the DOT file is encoded as
+// specified by the charset attribute of its first graph.
+func Encode(file *ast.File, charset string) ([]byte, error) {
+	if len(charset) == 0 {
+		charset = fileCharset(file)
+	}
+	cs, ok := lookupCharset(charset)
+	if !ok {
+		return nil, errors.Errorf("unsupported charset %q; expected UTF-8 or latin1", charset)
+	}
+	s := file.String()
+	if cs == CharsetUTF8 {
+		return []byte(s), nil
+	}
+	buf := make([]byte, 0, len(s))
+	line := 1
+	for _, r := range s {
+		if r > 0xFF {
+			return nil, errors.Errorf("unable to encode %q at line %d in charset %s", r, line, charset)
+		}
+		if r == '\n' {
+			line++
+		}
+		buf = append(buf, byte(r))
+	}
+	return buf, nil
+}
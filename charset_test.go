@@ -0,0 +1,153 @@
+package dot_test
+
+import (
+	"testing"
+
+	"github.com/graphism/dot"
+)
+
+func TestParseCharset(t *testing.T) {
+	golden := []struct {
+		in      string
+		charset string
+		want    string
+	}{
+		// UTF-8 byte order mark.
+		{
+			in:   "\xEF\xBB\xBFgraph { a }",
+			want: "graph {\n\ta\n}",
+		},
+		// UTF-8 encoded.
+		{
+			in:   "graph { a [label=\"caf\xC3\xA9\"] }",
+			want: "graph {\n\ta [label=\"café\"]\n}",
+		},
+		// Latin-1 encoded; charset attribute.
+		{
+			in:   "graph { charset=latin1; a [label=\"caf\xE9\"] }",
+			want: "graph {\n\tcharset=latin1\n\ta [label=\"café\"]\n}",
+		},
+		// Latin-1 encoded; charset alias of graph attribute statement.
+		{
+			in:   "graph { graph [charset=\"ISO-8859-1\"]; \xE9t\xE9 -- b }",
+			want: "graph {\n\tgraph [charset=\"ISO-8859-1\"]\n\tété -- b\n}",
+		},
+		// Latin-1 encoded; charset attribute after first non-ASCII character.
+		{
+			in:   "graph { a [label=\"caf\xE9\"]; charset=latin1 }",
+			want: "graph {\n\ta [label=\"café\"]\n\tcharset=latin1\n}",
+		},
+		// Latin-1 encoded, valid as UTF-8.
+		{
+			in:   "graph { charset=latin1; a [label=\"caf\xC3\xA9\"] }",
+			want: "graph {\n\tcharset=latin1\n\ta [label=\"cafÃ©\"]\n}",
+		},
+		// Charset attributes other than of the top-level graph attribute
+		// statements of the first graph are ignored.
+		{
+			in:   "graph { a [charset=latin1 label=\"caf\xC3\xA9\"]; subgraph { charset=latin1 } } graph { charset=latin1 }",
+			want: "graph {\n\ta [charset=latin1 label=\"café\"]\n\t{charset=latin1}\n}\ngraph {\n\tcharset=latin1\n}",
+		},
+		// Unsupported charset, which defaults to UTF-8.
+		{
+			in:   "graph { charset=big5; a [label=\"caf\xC3\xA9\"] }",
+			want: "graph {\n\tcharset=big5\n\ta [label=\"café\"]\n}",
+		},
+		// Charset option.
+		{
+			in:      "graph { a [label=\"caf\xE9\"] }",
+			charset: "Latin-1",
+			want:    "graph {\n\ta [label=\"café\"]\n}",
+		},
+		// Charset option, which takes precedence over the byte order mark.
+		{
+			in:      "\xEF\xBB\xBFgraph { a [label=\"caf\xE9\"] }",
+			charset: "latin1",
+			want:    "graph {\n\ta [label=\"café\"]\n}",
+		},
+	}
+	for _, g := range golden {
+		file, err := dot.ParseBytesWithOptions([]byte(g.in), dot.ParseOptions{Charset: g.charset})
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", g.in, err)
+			continue
+		}
+		if got := file.String(); got != g.want {
+			t.Errorf("%q: file mismatch; expected `%s`, got `%s`", g.in, g.want, got)
+		}
+	}
+}
+
+func TestParseCharsetError(t *testing.T) {
+	golden := []struct {
+		in      string
+		charset string
+		want    string
+	}{
+		{
+			in:      "graph {}",
+			charset: "big5",
+			want:    `unsupported charset "big5"; expected UTF-8 or latin1`,
+		},
+		// Invalid UTF-8 is reported as such, unless specified as Latin-1.
+		{
+			in:   "graph { a [label=\"caf\xE9\"]; subgraph { charset=latin1 } }",
+			want: "syntax error at line 1, column 18: missing value of attribute label; got unterminated or invalid string \"caf\xE9",
+		},
+	}
+	for _, g := range golden {
+		_, err := dot.ParseBytesWithOptions([]byte(g.in), dot.ParseOptions{Charset: g.charset})
+		if err == nil || err.Error() != g.want {
+			t.Errorf("%q: error mismatch; expected %q, got %q", g.in, g.want, err)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	golden := []struct {
+		in      string
+		charset string
+		want    string
+		err     string
+	}{
+		{
+			in:   "graph {\n\tcharset=latin1\n\ta [label=\"caf\xE9\"]\n}",
+			want: "graph {\n\tcharset=latin1\n\ta [label=\"caf\xE9\"]\n}",
+		},
+		{
+			in:      "graph {\n\ta [label=\"caf\xC3\xA9\"]\n}",
+			charset: "iso-8859-1",
+			want:    "graph {\n\ta [label=\"caf\xE9\"]\n}",
+		},
+		{
+			in:   "graph {\n\ta [label=\"caf\xC3\xA9\"]\n}",
+			want: "graph {\n\ta [label=\"caf\xC3\xA9\"]\n}",
+		},
+		{
+			in:      "graph {\n\ta [label=\"\xE2\x82\xAC\"]\n}",
+			charset: "latin1",
+			err:     "unable to encode '€' at line 2 in charset latin1",
+		},
+	}
+	for _, g := range golden {
+		file, err := dot.ParseString(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", g.in, err)
+			continue
+		}
+		buf, err := dot.Encode(file, g.charset)
+		if err != nil {
+			if err.Error() != g.err {
+				t.Errorf("%q: error mismatch; expected `%v`, got `%v`", g.in, g.err, err)
+			}
+			continue
+		}
+		if len(g.err) > 0 {
+			t.Errorf("%q: expected error, got nil", g.in)
+			continue
+		}
+		if got := string(buf); got != g.want {
+			t.Errorf("%q: output mismatch; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+}
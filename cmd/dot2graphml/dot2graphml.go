@@ -0,0 +1,69 @@
+// dot2graphml is a tool which converts Graphviz DOT files to GraphML.
+//
+// Usage: dot2graphml [OPTION]... FILE...
+//
+//   -o string
+//         output path
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/graphml"
+	"github.com/pkg/errors"
+)
+
+func main() {
+	// Parse command line flags.
+	var (
+		// output specifies the output path.
+		output string
+	)
+	flag.StringVar(&output, "o", "", "output path")
+	flag.Parse()
+
+	// Convert input files.
+	for _, path := range flag.Args() {
+		if err := dot2graphml(path, output); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// dot2graphml converts the given Graphviz DOT file to GraphML.
+func dot2graphml(path, output string) error {
+	// Parse input file.
+	file, err := dot.ParseFile(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(file.Graphs) != 1 {
+		return errors.Errorf("invalid number of graphs in %q; expected 1, got %d", path, len(file.Graphs))
+	}
+	buf, err := graphml.Marshal(file.Graphs[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Write to standard output.
+	w := os.Stdout
+
+	// Write to output file.
+	if len(output) > 0 {
+		f, err := os.Create(output)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	// Write to output stream.
+	if _, err := w.Write(buf); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
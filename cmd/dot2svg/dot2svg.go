@@ -0,0 +1,80 @@
+// dot2svg is a tool which renders Graphviz DOT files to SVG. Graphs without
+// layout are laid out before rendering.
+//
+// Usage: dot2svg [OPTION]... FILE...
+//
+//   -o string
+//         output path
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/layout"
+	"github.com/graphism/dot/render/svg"
+	"github.com/pkg/errors"
+)
+
+func main() {
+	// Parse command line flags.
+	var (
+		// output specifies the output path.
+		output string
+	)
+	flag.StringVar(&output, "o", "", "output path")
+	flag.Parse()
+
+	// Render input files.
+	for _, path := range flag.Args() {
+		if err := dot2svg(path, output); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// dot2svg renders the given Graphviz DOT file to SVG.
+func dot2svg(path, output string) error {
+	// Parse input file.
+	file, err := dot.ParseFile(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(file.Graphs) != 1 {
+		return errors.Errorf("invalid number of graphs in %q; expected 1, got %d", path, len(file.Graphs))
+	}
+	g, err := dot.Resolve(file.Graphs[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, ok := g.Attrs.Get("bb"); !ok {
+		if err := layout.Layout(g); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	buf, err := svg.RenderGraph(g)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Write to standard output.
+	w := os.Stdout
+
+	// Write to output file.
+	if len(output) > 0 {
+		f, err := os.Create(output)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	// Write to output stream.
+	if _, err := w.Write(buf); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -66,8 +66,12 @@ func dotfmt(path, output string, inplace bool) error {
 		w = f
 	}
 
-	// Write to output stream.
-	if _, err := fmt.Fprintln(w, file); err != nil {
+	// Write to output stream, in the charset of the input file.
+	buf, err := dot.Encode(file, "")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := fmt.Fprintf(w, "%s\n", buf); err != nil {
 		return errors.WithStack(err)
 	}
 
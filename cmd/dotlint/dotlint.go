@@ -0,0 +1,271 @@
+// dotlint is a tool which reports likely problems of Graphviz DOT files.
+//
+// Usage: dotlint [OPTION]... FILE...
+//
+//   -disable string
+//         comma-separated list of rules to disable
+//   -enable string
+//         comma-separated list of rules to enable (default all)
+//   -json
+//         output problems in JSON format
+//   -rules
+//         list rules
+//
+// Rules are disabled for a file by comments of the form
+//
+//    // dotlint:ignore [RULE[,RULE]...]
+//
+// which disable all rules if no rule is given. Files which cannot be parsed,
+// including files with semantic errors, are reported regardless of the enabled
+// rules.
+//
+// The exit status is 1 if any error or warning is reported.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/internal/scanner"
+	"github.com/pkg/errors"
+)
+
+func main() {
+	// Parse command line flags.
+	var (
+		// disable specifies the rules to disable.
+		disable string
+		// enable specifies the rules to enable.
+		enable string
+		// jsonOutput specifies whether to output problems in JSON format.
+		jsonOutput bool
+		// listRules specifies whether to list rules.
+		listRules bool
+	)
+	flag.StringVar(&disable, "disable", "", "comma-separated list of rules to disable")
+	flag.StringVar(&enable, "enable", "", "comma-separated list of rules to enable (default all)")
+	flag.BoolVar(&jsonOutput, "json", false, "output problems in JSON format")
+	flag.BoolVar(&listRules, "rules", false, "list rules")
+	flag.Parse()
+	if listRules {
+		for _, r := range rules {
+			fmt.Printf("%-20s %s\n", r.name, r.desc)
+		}
+		return
+	}
+	enabled, err := enabledRules(enable, disable)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Lint input files.
+	var problems []*problem
+	for _, path := range flag.Args() {
+		ps, err := dotlint(path, enabled)
+		if err != nil {
+			log.Fatal(err)
+		}
+		problems = append(problems, ps...)
+	}
+
+	// Output problems.
+	if err := writeProblems(os.Stdout, problems, jsonOutput); err != nil {
+		log.Fatal(err)
+	}
+	for _, p := range problems {
+		if p.Severity != dot.SeverityInfo.String() {
+			os.Exit(1)
+		}
+	}
+}
+
+// enabledRules returns the enabled rules, as specified by the comma-separated
+// lists of rules to enable and disable.
+func enabledRules(enable, disable string) (map[string]bool, error) {
+	enabled := make(map[string]bool)
+	if len(enable) == 0 {
+		for _, r := range rules {
+			enabled[r.name] = true
+		}
+	}
+	for _, name := range splitRules(enable) {
+		if _, ok := findRule(name); !ok {
+			return nil, errors.Errorf("unknown rule %q of -enable flag", name)
+		}
+		enabled[name] = true
+	}
+	for _, name := range splitRules(disable) {
+		if _, ok := findRule(name); !ok {
+			return nil, errors.Errorf("unknown rule %q of -disable flag", name)
+		}
+		delete(enabled, name)
+	}
+	return enabled, nil
+}
+
+// splitRules returns the rule names of the given comma-separated list.
+func splitRules(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// A problem is a problem reported for a DOT file.
+type problem struct {
+	// File path.
+	File string `json:"file"`
+	// Line and column of the problem, starting at 1; or 0 if unknown. Columns
+	// are counted in characters.
+	Line int `json:"line,omitempty"`
+	Col  int `json:"column,omitempty"`
+	// Rule name; or "parse" if the file cannot be parsed.
+	Rule string `json:"rule"`
+	// Severity of the problem.
+	Severity string `json:"severity"`
+	// Problem description.
+	Msg string `json:"message"`
+}
+
+// String returns the string representation of the problem.
+func (p *problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s: %s (%s)", p.File, p.Line, p.Col, p.Severity, p.Msg, p.Rule)
+	}
+	return fmt.Sprintf("%s: %s: %s (%s)", p.File, p.Severity, p.Msg, p.Rule)
+}
+
+// writeProblems writes the given problems to w, one per line or in JSON format.
+func writeProblems(w io.Writer, problems []*problem, jsonOutput bool) error {
+	if !jsonOutput {
+		for _, p := range problems {
+			if _, err := fmt.Fprintln(w, p); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		return nil
+	}
+	if problems == nil {
+		problems = []*problem{}
+	}
+	buf, err := json.MarshalIndent(problems, "", "\t")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := fmt.Fprintln(w, string(buf)); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// dotlint returns the problems of the given Graphviz DOT file, as reported by
+// the enabled rules.
+func dotlint(path string, enabled map[string]bool) ([]*problem, error) {
+	// Parse input file.
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	positions := make(dot.Positions)
+	file, err := dot.ParseBytesWithOptions(buf, dot.ParseOptions{Positions: positions})
+	if err != nil {
+		p := &problem{File: path, Rule: "parse", Severity: dot.SeverityError.String(), Msg: err.Error()}
+		return []*problem{p}, nil
+	}
+	ignored, err := ignoredRules(buf)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid ignore comment of %q", path)
+	}
+
+	// Apply rules to graphs.
+	f := &lintFile{file: file}
+	src := source(buf)
+	var problems []*problem
+	for _, graph := range file.Graphs {
+		g, err := dot.Resolve(graph)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, r := range rules {
+			if !enabled[r.name] || ignored[r.name] || ignored[""] {
+				continue
+			}
+			for _, diag := range r.check(f, graph, g) {
+				p := &problem{File: path, Rule: r.name, Severity: diag.Severity.String(), Msg: diag.Msg}
+				if span, ok := positions[diag.Node]; ok {
+					p.Line, p.Col = position(src, span.Offset)
+				}
+				problems = append(problems, p)
+			}
+		}
+	}
+	return problems, nil
+}
+
+// source returns the source of the given DOT file as parsed, of which positions
+// are byte offsets; i.e. without byte order mark, and transcoded from Latin-1
+// to UTF-8 if not valid UTF-8.
+func source(buf []byte) []byte {
+	buf = bytes.TrimPrefix(buf, []byte("\xEF\xBB\xBF"))
+	if utf8.Valid(buf) {
+		return buf
+	}
+	src := make([]byte, 0, len(buf))
+	for _, b := range buf {
+		src = append(src, string(rune(b))...)
+	}
+	return src
+}
+
+// position returns the line and column, starting at 1, of the given byte
+// offset of src. Columns are counted in characters.
+func position(src []byte, offset int) (line, col int) {
+	if offset > len(src) {
+		offset = len(src)
+	}
+	before := src[:offset]
+	line = 1 + bytes.Count(before, []byte("\n"))
+	col = 1 + utf8.RuneCount(before[bytes.LastIndexByte(before, '\n')+1:])
+	return line, col
+}
+
+// ignoreComment matches comments which disable rules for a file.
+var ignoreComment = regexp.MustCompile(`^//[ \t]*dotlint:ignore\b([^\n]*)`)
+
+// ignoredRules returns the rules disabled for the given DOT file by ignore
+// comments. The empty rule name indicates that all rules are disabled. Only
+// comments as tokenized by the scanner are considered; not comment delimiters
+// of IDs (e.g. of URLs).
+func ignoredRules(buf []byte) (map[string]bool, error) {
+	ignored := make(map[string]bool)
+	for _, comment := range scanner.Comments(buf) {
+		m := ignoreComment.FindSubmatch(comment)
+		if m == nil {
+			continue
+		}
+		names := splitRules(string(m[1]))
+		if len(names) == 0 {
+			ignored[""] = true
+		}
+		for _, name := range names {
+			if _, ok := findRule(name); !ok {
+				return nil, errors.Errorf("unknown rule %q", name)
+			}
+			ignored[name] = true
+		}
+	}
+	return ignored, nil
+}
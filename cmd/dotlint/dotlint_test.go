@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// lint returns the problems reported by the given rules for the DOT file with
+// the given contents.
+func lint(t *testing.T, in string, names ...string) []string {
+	path := filepath.Join(t.TempDir(), "in.dot")
+	if err := ioutil.WriteFile(path, []byte(in), 0644); err != nil {
+		t.Fatal(err)
+	}
+	enabled, err := enabledRules(strings.Join(names, ","), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems, err := dotlint(path, enabled)
+	if err != nil {
+		t.Fatalf("%q: unable to lint file; %+v", in, err)
+	}
+	var got []string
+	for _, p := range problems {
+		got = append(got, strings.TrimSpace(strings.TrimPrefix(p.String(), path+":")))
+	}
+	return got
+}
+
+func TestRules(t *testing.T) {
+	golden := []struct {
+		in   string
+		rule string
+		want []string
+	}{
+		// check
+		{in: "graph { a [color=nocolor] }", rule: "check", want: []string{`1:12: warning: invalid color nocolor of node "a"; unknown colour "nocolor" in colour scheme "x11" (check)`}},
+		// Columns are counted in characters, of the DOT file transcoded to UTF-8.
+		{in: "graph { \"é\" [color=nocolor] }", rule: "check", want: []string{`1:14: warning: invalid color nocolor of node "\"é\""; unknown colour "nocolor" in colour scheme "x11" (check)`}},
+		{in: "graph { charset=latin1\n\"\xE9\" [color=nocolor] }", rule: "check", want: []string{`2:6: warning: invalid color nocolor of node "\"é\""; unknown colour "nocolor" in colour scheme "x11" (check)`}},
+		// unused-defaults
+		{in: "graph { a; node [shape=box] }", rule: "unused-defaults", want: []string{"1:18: warning: unused defaults node [shape=box]; no node created in scope after the statement (unused-defaults)"}},
+		{in: "graph { node [shape=box]; a; edge [color=red] }", rule: "unused-defaults", want: []string{"1:36: warning: unused defaults edge [color=red]; no edge created in scope after the statement (unused-defaults)"}},
+		// unconnected-nodes
+		{in: "graph { a; b; c -- d }", rule: "unconnected-nodes", want: []string{`1:9: warning: node "a" not connected to any edge (unconnected-nodes)`, `1:12: warning: node "b" not connected to any edge (unconnected-nodes)`}},
+		{in: "graph { a; b }", rule: "unconnected-nodes", want: nil},
+		// undeclared-nodes
+		{in: "graph { a; b; a -- c; a -- b }", rule: "undeclared-nodes", want: []string{`1:20: warning: node "c" of edge not declared in any node statement; possible typo (undeclared-nodes)`}},
+		{in: "graph { a -- c }", rule: "undeclared-nodes", want: nil},
+		// redundant-attrs
+		{in: "graph { node [shape=box]; a [shape=box]; b [shape=\"box\" color=red] }", rule: "redundant-attrs", want: []string{`1:30: warning: redundant attribute shape=box of node "a"; value already in effect (redundant-attrs)`, `1:45: warning: redundant attribute shape="box" of node "b"; value already in effect (redundant-attrs)`}},
+		// unlabeled-clusters
+		{in: "graph { subgraph cluster_a { a }; subgraph cluster_b { label=B; b }; subgraph c { c } }", rule: "unlabeled-clusters", want: []string{`1:18: warning: cluster "cluster_a" without label (unlabeled-clusters)`}},
+		// html-labels
+		{in: "graph { a [label=<<B>a</I>>]; b [label=<<B>b</B>>] }", rule: "html-labels", want: []string{`1:12: error: invalid HTML-like label of node "a"; offset 4: mismatched end tag </I>; expected </B> (html-labels)`}},
+		// Parse errors are reported regardless of enabled rules.
+		{in: "graph { a -> b }", rule: "html-labels", want: []string{`error: undirected graph "" contains directed edge from "a" to "b" (parse)`}},
+		// Problems of multiple graphs are reported once per graph.
+		{in: "graph { a [color=nocolor] } graph { b [color=nocolor] }", rule: "check", want: []string{`1:12: warning: invalid color nocolor of node "a"; unknown colour "nocolor" in colour scheme "x11" (check)`, `1:40: warning: invalid color nocolor of node "b"; unknown colour "nocolor" in colour scheme "x11" (check)`}},
+	}
+	for _, g := range golden {
+		got := lint(t, g.in, g.rule)
+		if strings.Join(got, "\n") != strings.Join(g.want, "\n") {
+			t.Errorf("%q: problems of rule %s mismatch; expected %q, got %q", g.in, g.rule, g.want, got)
+		}
+	}
+}
+
+func TestIgnoredRules(t *testing.T) {
+	golden := []struct {
+		in   string
+		want []string
+	}{
+		{in: "graph { a; b; c -- d; subgraph cluster_a { e -- f } }", want: []string{
+			`1:9: warning: node "a" not connected to any edge (unconnected-nodes)`,
+			`1:12: warning: node "b" not connected to any edge (unconnected-nodes)`,
+			`1:32: warning: cluster "cluster_a" without label (unlabeled-clusters)`,
+		}},
+		{in: "// dotlint:ignore unconnected-nodes\ngraph { a; b; c -- d; subgraph cluster_a { e -- f } }", want: []string{
+			`2:32: warning: cluster "cluster_a" without label (unlabeled-clusters)`,
+		}},
+		{in: "graph { a; b; c -- d; subgraph cluster_a { e -- f } } //dotlint:ignore unlabeled-clusters, unconnected-nodes\n", want: nil},
+		{in: "graph {\n\t// dotlint:ignore\n\ta; b; c -- d; subgraph cluster_a { e -- f }\n}", want: nil},
+		// Parse errors are not ignored.
+		{in: "// dotlint:ignore\ngraph { a -> b }", want: []string{`error: undirected graph "" contains directed edge from "a" to "b" (parse)`}},
+		// Ignore comments are only recognized in comments.
+		{in: "graph { a [URL=\"http://x/*// dotlint:ignore*/\"]; b -- c }", want: []string{`1:9: warning: node "a" not connected to any edge (unconnected-nodes)`}},
+		{in: "graph { a [label=\"// dotlint:ignore\"]; b -- c }", want: []string{`1:9: warning: node "a" not connected to any edge (unconnected-nodes)`}},
+		// Rule names must be delimited.
+		{in: "// dotlint:ignored\ngraph { a; b -- c }", want: []string{`2:9: warning: node "a" not connected to any edge (unconnected-nodes)`}},
+	}
+	for _, g := range golden {
+		got := lint(t, g.in, "unconnected-nodes", "unlabeled-clusters")
+		if strings.Join(got, "\n") != strings.Join(g.want, "\n") {
+			t.Errorf("%q: problems mismatch; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+
+	// Unknown rules of ignore comments are reported as errors.
+	if _, err := ignoredRules([]byte("// dotlint:ignore check,foo\ngraph {}")); err == nil || err.Error() != `unknown rule "foo"` {
+		t.Errorf("error mismatch; expected `unknown rule \"foo\"`, got `%v`", err)
+	}
+}
+
+func TestEnabledRules(t *testing.T) {
+	golden := []struct {
+		enable, disable string
+		want            []string
+	}{
+		{want: []string{"check", "unused-defaults", "unconnected-nodes", "undeclared-nodes", "redundant-attrs", "unlabeled-clusters", "html-labels"}},
+		{enable: "check, html-labels", want: []string{"check", "html-labels"}},
+		{disable: "check,redundant-attrs,unlabeled-clusters", want: []string{"unused-defaults", "unconnected-nodes", "undeclared-nodes", "html-labels"}},
+		{enable: "check,html-labels", disable: "check", want: []string{"html-labels"}},
+	}
+	for _, g := range golden {
+		enabled, err := enabledRules(g.enable, g.disable)
+		if err != nil {
+			t.Errorf("-enable=%q -disable=%q: unexpected error; %v", g.enable, g.disable, err)
+			continue
+		}
+		var got []string
+		for _, r := range rules {
+			if enabled[r.name] {
+				got = append(got, r.name)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(g.want, ",") {
+			t.Errorf("-enable=%q -disable=%q: enabled rules mismatch; expected %q, got %q", g.enable, g.disable, g.want, got)
+		}
+	}
+	if _, err := enabledRules("", "foo"); err == nil || err.Error() != `unknown rule "foo" of -disable flag` {
+		t.Errorf("error mismatch; expected `unknown rule \"foo\" of -disable flag`, got `%v`", err)
+	}
+}
+
+func TestWriteProblems(t *testing.T) {
+	problems := []*problem{
+		{File: "a.dot", Line: 3, Col: 5, Rule: "check", Severity: "warning", Msg: `invalid color nocolor of node "a"`},
+		{File: "b.dot", Rule: "parse", Severity: "error", Msg: "syntax error"},
+	}
+	golden := []struct {
+		problems   []*problem
+		jsonOutput bool
+		want       string
+	}{
+		{problems: problems, want: "a.dot:3:5: warning: invalid color nocolor of node \"a\" (check)\nb.dot: error: syntax error (parse)\n"},
+		{problems: nil, want: ""},
+		{problems: problems, jsonOutput: true, want: `[
+	{
+		"file": "a.dot",
+		"line": 3,
+		"column": 5,
+		"rule": "check",
+		"severity": "warning",
+		"message": "invalid color nocolor of node \"a\""
+	},
+	{
+		"file": "b.dot",
+		"rule": "parse",
+		"severity": "error",
+		"message": "syntax error"
+	}
+]
+`},
+		// No problems are output as an empty JSON array.
+		{problems: nil, jsonOutput: true, want: "[]\n"},
+	}
+	for _, g := range golden {
+		buf := &bytes.Buffer{}
+		if err := writeProblems(buf, g.problems, g.jsonOutput); err != nil {
+			t.Errorf("unable to write problems; %v", err)
+			continue
+		}
+		if got := buf.String(); got != g.want {
+			t.Errorf("output mismatch (json=%v); expected `%s`, got `%s`", g.jsonOutput, g.want, got)
+		}
+	}
+}
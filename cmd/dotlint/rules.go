@@ -0,0 +1,414 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/htmllabel"
+	"github.com/graphism/dot/internal/enc"
+)
+
+// === [ Rules ] ===============================================================
+
+// A rule is a lint rule, which reports likely problems of a graph.
+type rule struct {
+	// Rule name.
+	name string
+	// Rule description.
+	desc string
+	// check returns the problems of the given graph of the DOT file, and its
+	// resolved graph.
+	check func(f *lintFile, graph *ast.Graph, g *dot.Graph) []*dot.Diagnostic
+}
+
+// rules specifies the lint rules, in order of application.
+var rules = []*rule{
+	{name: "check", desc: "warnings and infos of the semantic checker", check: checkRule},
+	{name: "unused-defaults", desc: "node and edge defaults not applied to any node or edge", check: unusedDefaults},
+	{name: "unconnected-nodes", desc: "nodes not connected to any edge, in graphs with edges", check: unconnectedNodes},
+	{name: "undeclared-nodes", desc: "edges to nodes without node statement, in graphs with node statements", check: undeclaredNodes},
+	{name: "redundant-attrs", desc: "attributes set to the value already in effect", check: redundantAttrs},
+	{name: "unlabeled-clusters", desc: "cluster subgraphs without label", check: unlabeledClusters},
+	{name: "html-labels", desc: "malformed HTML-like labels (e.g. unbalanced tags)", check: htmlLabels},
+}
+
+// findRule returns the lint rule with the given name, and a boolean value
+// indicating if such a rule exists.
+func findRule(name string) (*rule, bool) {
+	for _, r := range rules {
+		if r.name == name {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// warnf returns a warning diagnostic of the given AST node with the given
+// message.
+func warnf(node interface{}, format string, args ...interface{}) *dot.Diagnostic {
+	return &dot.Diagnostic{Severity: dot.SeverityWarning, Msg: fmt.Sprintf(format, args...), Node: node}
+}
+
+// A lintFile is a parsed DOT file being linted.
+type lintFile struct {
+	// AST of the DOT file.
+	file *ast.File
+	// diags maps from graph to the diagnostics of the semantic checker; or nil
+	// if not yet checked.
+	diags map[*ast.Graph][]*dot.Diagnostic
+}
+
+// checkGraph returns the diagnostics of the semantic checker of the given
+// graph. The DOT file is checked once, on first use.
+func (f *lintFile) checkGraph(graph *ast.Graph) []*dot.Diagnostic {
+	if f.diags == nil {
+		f.diags = make(map[*ast.Graph][]*dot.Diagnostic)
+		for _, diag := range dot.Check(f.file) {
+			f.diags[diag.Graph] = append(f.diags[diag.Graph], diag)
+		}
+	}
+	return f.diags[graph]
+}
+
+// --- [ check ] ---------------------------------------------------------------
+
+// checkRule reports the diagnostics of the semantic checker. Errors of the
+// semantic checker (e.g. directed edges in undirected graphs) prevent parsing,
+// and are thus reported regardless of the enabled rules.
+func checkRule(f *lintFile, graph *ast.Graph, g *dot.Graph) []*dot.Diagnostic {
+	return f.checkGraph(graph)
+}
+
+// --- [ unused-defaults ] -----------------------------------------------------
+
+// unusedDefaults reports node and edge attribute statements after which no node
+// or edge is created in their scope.
+func unusedDefaults(f *lintFile, graph *ast.Graph, g *dot.Graph) []*dot.Diagnostic {
+	w := walk(graph)
+	var diags []*dot.Diagnostic
+	for _, stmt := range w.defaults {
+		if !w.used[stmt] {
+			// Attribute statements are located by their first attribute.
+			var node interface{}
+			if len(stmt.Attrs) > 0 {
+				node = stmt.Attrs[0]
+			}
+			diags = append(diags, warnf(node, "unused defaults %s; no %s created in scope after the statement", stmt, stmt.Kind))
+		}
+	}
+	return diags
+}
+
+// --- [ unconnected-nodes ] ---------------------------------------------------
+
+// unconnectedNodes reports nodes which are not connected to any edge, in graphs
+// with edges.
+func unconnectedNodes(f *lintFile, graph *ast.Graph, g *dot.Graph) []*dot.Diagnostic {
+	if len(g.Edges) == 0 {
+		return nil
+	}
+	connected := make(map[*dot.Node]bool)
+	for _, e := range g.Edges {
+		connected[e.From] = true
+		connected[e.To] = true
+	}
+	w := walk(graph)
+	var diags []*dot.Diagnostic
+	for _, n := range g.Nodes {
+		if !connected[n] {
+			diags = append(diags, warnf(w.first[enc.Unquote(n.ID)], "node %q not connected to any edge", n.ID))
+		}
+	}
+	return diags
+}
+
+// --- [ undeclared-nodes ] ----------------------------------------------------
+
+// undeclaredNodes reports nodes of edges which are not declared in any node
+// statement, as they may be typos, in graphs with node statements.
+func undeclaredNodes(f *lintFile, graph *ast.Graph, g *dot.Graph) []*dot.Diagnostic {
+	w := walk(graph)
+	if len(w.declared) == 0 {
+		return nil
+	}
+	var diags []*dot.Diagnostic
+	reported := make(map[string]bool)
+	for _, node := range w.connected {
+		key := enc.Unquote(node.ID)
+		if w.declared[key] || reported[key] {
+			continue
+		}
+		reported[key] = true
+		diags = append(diags, warnf(node, "node %q of edge not declared in any node statement; possible typo", node.ID))
+	}
+	return diags
+}
+
+// --- [ redundant-attrs ] -----------------------------------------------------
+
+// redundantAttrs reports attributes set to the value already in effect; either
+// inherited from the defaults in scope, or previously set.
+func redundantAttrs(f *lintFile, graph *ast.Graph, g *dot.Graph) []*dot.Diagnostic {
+	return walk(graph).redundant
+}
+
+// --- [ unlabeled-clusters ] --------------------------------------------------
+
+// unlabeledClusters reports cluster subgraphs without label.
+func unlabeledClusters(f *lintFile, graph *ast.Graph, g *dot.Graph) []*dot.Diagnostic {
+	subgraphs := astSubgraphs(graph, g)
+	var diags []*dot.Diagnostic
+	var visit func(subs []*dot.Subgraph)
+	visit = func(subs []*dot.Subgraph) {
+		for _, sub := range subs {
+			if strings.HasPrefix(enc.Unquote(sub.ID), "cluster") {
+				if _, ok := sub.Attrs.Get("label"); !ok {
+					diags = append(diags, warnf(subgraphs[sub], "cluster %q without label", sub.ID))
+				}
+			}
+			visit(sub.Subgraphs)
+		}
+	}
+	visit(g.Subgraphs)
+	return diags
+}
+
+// astSubgraphs maps from the resolved subgraphs of the given graph to the AST
+// subgraphs by which they were created. As in dot.Resolve, named subgraphs are
+// reopened by later subgraphs with the same ID in the same scope.
+func astSubgraphs(graph *ast.Graph, g *dot.Graph) map[*dot.Subgraph]*ast.Subgraph {
+	m := make(map[*dot.Subgraph]*ast.Subgraph)
+	// created maps from scope to the number of subgraphs created in the scope;
+	// nil for the graph.
+	created := make(map[*dot.Subgraph]int)
+	var stmts func(scope *dot.Subgraph, subs []*dot.Subgraph, stmts []ast.Stmt)
+	subgraph := func(scope *dot.Subgraph, subs []*dot.Subgraph, sub *ast.Subgraph) {
+		n := created[scope]
+		var child *dot.Subgraph
+		if len(sub.ID) > 0 {
+			for _, s := range subs[:n] {
+				if enc.Unquote(s.ID) == enc.Unquote(sub.ID) {
+					child = s
+					break
+				}
+			}
+		}
+		if child == nil {
+			if n >= len(subs) {
+				return
+			}
+			child = subs[n]
+			created[scope]++
+			m[child] = sub
+		}
+		stmts(child, child.Subgraphs, sub.Stmts)
+	}
+	stmts = func(scope *dot.Subgraph, subs []*dot.Subgraph, ss []ast.Stmt) {
+		for _, stmt := range ss {
+			switch stmt := stmt.(type) {
+			case *ast.Subgraph:
+				subgraph(scope, subs, stmt)
+			case *ast.EdgeStmt:
+				if sub, ok := stmt.From.(*ast.Subgraph); ok {
+					subgraph(scope, subs, sub)
+				}
+				for to := stmt.To; to != nil; to = to.To {
+					if sub, ok := to.Vertex.(*ast.Subgraph); ok {
+						subgraph(scope, subs, sub)
+					}
+				}
+			}
+		}
+	}
+	stmts(nil, g.Subgraphs, graph.Stmts)
+	return m
+}
+
+// --- [ html-labels ] ---------------------------------------------------------
+
+// labelKeys specifies the attribute keys with label values.
+var labelKeys = []string{"label", "xlabel", "headlabel", "taillabel"}
+
+// htmlLabels reports malformed HTML-like labels of the graph, and its
+// subgraphs, nodes and edges, as set by the statements of the graph.
+func htmlLabels(f *lintFile, graph *ast.Graph, g *dot.Graph) []*dot.Diagnostic {
+	return walk(graph).invalidHTML
+}
+
+// checkHTML returns a diagnostic of the given attribute of the named component
+// if a malformed HTML-like label; or nil otherwise.
+func checkHTML(name string, attr *ast.Attr) *dot.Diagnostic {
+	key := enc.Unquote(attr.Key)
+	if !isLabelKey(key) || !enc.IsHTML(attr.Val) {
+		return nil
+	}
+	if _, err := htmllabel.Parse(attr.Val); err != nil {
+		return &dot.Diagnostic{Severity: dot.SeverityError, Msg: fmt.Sprintf("invalid HTML-like %s of %s; %v", key, name, err), Node: attr}
+	}
+	return nil
+}
+
+// isLabelKey reports whether key is the attribute key of a label.
+func isLabelKey(key string) bool {
+	for _, k := range labelKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// === [ AST walker ] ==========================================================
+
+// A walker tracks the default attributes and nodes of a graph, in order of
+// statements.
+type walker struct {
+	// nodes maps from unquoted node ID to the attributes of the node.
+	nodes map[string]dot.Attrs
+	// Node and edge attribute statements, in order of occurrence.
+	defaults []*ast.AttrStmt
+	// used tracks the attribute statements applied to a node or edge.
+	used map[*ast.AttrStmt]bool
+	// first maps from unquoted node ID to the first occurrence of the node.
+	first map[string]*ast.Node
+	// declared tracks the unquoted IDs of nodes declared in node statements.
+	declared map[string]bool
+	// Nodes of edges, in order of occurrence.
+	connected []*ast.Node
+	// Redundant attributes.
+	redundant []*dot.Diagnostic
+	// Malformed HTML-like labels.
+	invalidHTML []*dot.Diagnostic
+	// Nesting depth of subgraph vertices of edges.
+	edgeDepth int
+}
+
+// A scope tracks the default attributes of a graph or subgraph.
+type scope struct {
+	// Graph attributes.
+	graphAttrs dot.Attrs
+	// Default node attributes.
+	nodeAttrs dot.Attrs
+	// Default edge attributes.
+	edgeAttrs dot.Attrs
+	// Node and edge attribute statements in scope.
+	defaults []*ast.AttrStmt
+}
+
+// clone returns a copy of the scope, as inherited by a subgraph.
+func (s *scope) clone() *scope {
+	return &scope{
+		graphAttrs: append(dot.Attrs(nil), s.graphAttrs...),
+		nodeAttrs:  append(dot.Attrs(nil), s.nodeAttrs...),
+		edgeAttrs:  append(dot.Attrs(nil), s.edgeAttrs...),
+		defaults:   append([]*ast.AttrStmt(nil), s.defaults...),
+	}
+}
+
+// walk walks the statements of the given graph.
+func walk(graph *ast.Graph) *walker {
+	w := &walker{
+		nodes:    make(map[string]dot.Attrs),
+		used:     make(map[*ast.AttrStmt]bool),
+		first:    make(map[string]*ast.Node),
+		declared: make(map[string]bool),
+	}
+	w.stmts(&scope{}, graph.Stmts)
+	return w
+}
+
+// stmts walks the given statements in scope s.
+func (w *walker) stmts(s *scope, stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.NodeStmt:
+			key := w.node(s, stmt.Node)
+			if w.edgeDepth > 0 {
+				// Node statements of subgraph vertices are edge endpoints.
+				w.connected = append(w.connected, stmt.Node)
+			} else {
+				w.declared[key] = true
+			}
+			attrs := w.nodes[key]
+			w.setAttrs(fmt.Sprintf("node %q", stmt.Node.ID), &attrs, stmt.Attrs)
+			w.nodes[key] = attrs
+		case *ast.EdgeStmt:
+			w.vertex(s, stmt.From)
+			for to := stmt.To; to != nil; to = to.To {
+				w.vertex(s, to.Vertex)
+			}
+			w.use(s, ast.KindEdge)
+			// Edges are created with the default attributes in scope.
+			attrs := append(dot.Attrs(nil), s.edgeAttrs...)
+			edges := &ast.EdgeStmt{From: stmt.From, To: stmt.To}
+			w.setAttrs(fmt.Sprintf("edges %q", edges), &attrs, stmt.Attrs)
+		case *ast.AttrStmt:
+			switch stmt.Kind {
+			case ast.KindGraph:
+				w.setAttrs("graph attributes", &s.graphAttrs, stmt.Attrs)
+			case ast.KindNode:
+				w.setAttrs("node defaults", &s.nodeAttrs, stmt.Attrs)
+				s.defaults = append(s.defaults, stmt)
+				w.defaults = append(w.defaults, stmt)
+			case ast.KindEdge:
+				w.setAttrs("edge defaults", &s.edgeAttrs, stmt.Attrs)
+				s.defaults = append(s.defaults, stmt)
+				w.defaults = append(w.defaults, stmt)
+			}
+		case *ast.Attr:
+			w.setAttrs("graph attributes", &s.graphAttrs, []*ast.Attr{stmt})
+		case *ast.Subgraph:
+			w.stmts(s.clone(), stmt.Stmts)
+		}
+	}
+}
+
+// vertex walks the given edge vertex in scope s.
+func (w *walker) vertex(s *scope, vertex ast.Vertex) {
+	switch vertex := vertex.(type) {
+	case *ast.Node:
+		w.node(s, vertex)
+		w.connected = append(w.connected, vertex)
+	case *ast.Subgraph:
+		w.edgeDepth++
+		w.stmts(s.clone(), vertex.Stmts)
+		w.edgeDepth--
+	}
+}
+
+// node returns the unquoted ID of the given node, creating the node with the
+// default attributes in scope s if not yet present.
+func (w *walker) node(s *scope, node *ast.Node) string {
+	key := enc.Unquote(node.ID)
+	if _, ok := w.nodes[key]; !ok {
+		w.nodes[key] = append(dot.Attrs(nil), s.nodeAttrs...)
+		w.first[key] = node
+		w.use(s, ast.KindNode)
+	}
+	return key
+}
+
+// use marks the attribute statements in scope s of the given kind as applied.
+func (w *walker) use(s *scope, kind ast.Kind) {
+	for _, stmt := range s.defaults {
+		if stmt.Kind == kind {
+			w.used[stmt] = true
+		}
+	}
+}
+
+// setAttrs sets the given attributes of the named component, reporting the
+// attributes set to the value already in effect and malformed HTML-like labels.
+func (w *walker) setAttrs(name string, attrs *dot.Attrs, as []*ast.Attr) {
+	for _, a := range as {
+		if diag := checkHTML(name, a); diag != nil {
+			w.invalidHTML = append(w.invalidHTML, diag)
+		}
+		if val, ok := attrs.Get(a.Key); ok && enc.Unquote(val) == enc.Unquote(a.Val) {
+			w.redundant = append(w.redundant, warnf(a, "redundant attribute %s of %s; value already in effect", a, name))
+		}
+		attrs.Set(a.Key, a.Val)
+	}
+}
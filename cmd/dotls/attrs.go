@@ -0,0 +1,230 @@
+package main
+
+import "strings"
+
+// An attr is an attribute of Graphviz DOT files.
+type attr struct {
+	// Attribute name.
+	name string
+	// Component kinds using the attribute; any of "G" (graph), "S" (subgraph),
+	// "C" (cluster), "N" (node) and "E" (edge).
+	usedBy string
+}
+
+// attrs specifies the attributes of Graphviz DOT files.
+//
+// ref: https://graphviz.org/doc/info/attrs.html
+var attrs = []attr{
+	{"_background", "G"},
+	{"area", "NC"},
+	{"arrowhead", "E"},
+	{"arrowsize", "E"},
+	{"arrowtail", "E"},
+	{"bb", "GC"},
+	{"beautify", "G"},
+	{"bgcolor", "GC"},
+	{"center", "G"},
+	{"charset", "G"},
+	{"class", "EGCN"},
+	{"cluster", "C"},
+	{"clusterrank", "G"},
+	{"color", "ENC"},
+	{"colorscheme", "ENCG"},
+	{"comment", "ENG"},
+	{"compound", "G"},
+	{"concentrate", "G"},
+	{"constraint", "E"},
+	{"Damping", "G"},
+	{"decorate", "E"},
+	{"defaultdist", "G"},
+	{"dim", "G"},
+	{"dimen", "G"},
+	{"dir", "E"},
+	{"diredgeconstraints", "G"},
+	{"distortion", "N"},
+	{"dpi", "G"},
+	{"edgehref", "E"},
+	{"edgetarget", "E"},
+	{"edgetooltip", "E"},
+	{"edgeURL", "E"},
+	{"epsilon", "G"},
+	{"esep", "G"},
+	{"fillcolor", "NEC"},
+	{"fixedsize", "N"},
+	{"fontcolor", "ENGC"},
+	{"fontname", "ENGC"},
+	{"fontnames", "G"},
+	{"fontpath", "G"},
+	{"fontsize", "ENGC"},
+	{"forcelabels", "G"},
+	{"gradientangle", "NCG"},
+	{"group", "N"},
+	{"head_lp", "E"},
+	{"headclip", "E"},
+	{"headhref", "E"},
+	{"headlabel", "E"},
+	{"headport", "E"},
+	{"headtarget", "E"},
+	{"headtooltip", "E"},
+	{"headURL", "E"},
+	{"height", "N"},
+	{"href", "GCNE"},
+	{"id", "GCNE"},
+	{"image", "N"},
+	{"imagepath", "G"},
+	{"imagepos", "N"},
+	{"imagescale", "N"},
+	{"inputscale", "G"},
+	{"K", "GC"},
+	{"label", "ENGC"},
+	{"label_scheme", "G"},
+	{"labelangle", "E"},
+	{"labeldistance", "E"},
+	{"labelfloat", "E"},
+	{"labelfontcolor", "E"},
+	{"labelfontname", "E"},
+	{"labelfontsize", "E"},
+	{"labelhref", "E"},
+	{"labeljust", "GC"},
+	{"labelloc", "NGC"},
+	{"labeltarget", "E"},
+	{"labeltooltip", "E"},
+	{"labelURL", "E"},
+	{"landscape", "G"},
+	{"layer", "ENC"},
+	{"layerlistsep", "G"},
+	{"layers", "G"},
+	{"layerselect", "G"},
+	{"layersep", "G"},
+	{"layout", "G"},
+	{"len", "E"},
+	{"levels", "G"},
+	{"levelsgap", "G"},
+	{"lhead", "E"},
+	{"lheight", "GC"},
+	{"linelength", "G"},
+	{"lp", "EGC"},
+	{"ltail", "E"},
+	{"lwidth", "GC"},
+	{"margin", "NCG"},
+	{"maxiter", "G"},
+	{"mclimit", "G"},
+	{"mindist", "G"},
+	{"minlen", "E"},
+	{"mode", "G"},
+	{"model", "G"},
+	{"newrank", "G"},
+	{"nodesep", "G"},
+	{"nojustify", "GCN"},
+	{"normalize", "G"},
+	{"notranslate", "G"},
+	{"nslimit", "G"},
+	{"nslimit1", "G"},
+	{"oneblock", "G"},
+	{"ordering", "GN"},
+	{"orientation", "NG"},
+	{"outputorder", "G"},
+	{"overlap", "G"},
+	{"overlap_scaling", "G"},
+	{"overlap_shrink", "G"},
+	{"pack", "G"},
+	{"packmode", "G"},
+	{"pad", "G"},
+	{"page", "G"},
+	{"pagedir", "G"},
+	{"pencolor", "C"},
+	{"penwidth", "CNE"},
+	{"peripheries", "NC"},
+	{"pin", "N"},
+	{"pos", "EN"},
+	{"quadtree", "G"},
+	{"quantum", "G"},
+	{"rank", "S"},
+	{"rankdir", "G"},
+	{"ranksep", "G"},
+	{"ratio", "G"},
+	{"rects", "N"},
+	{"regular", "N"},
+	{"remincross", "G"},
+	{"repulsiveforce", "G"},
+	{"resolution", "G"},
+	{"root", "GN"},
+	{"rotate", "G"},
+	{"rotation", "G"},
+	{"samehead", "E"},
+	{"sametail", "E"},
+	{"samplepoints", "N"},
+	{"scale", "G"},
+	{"searchsize", "G"},
+	{"sep", "G"},
+	{"shape", "N"},
+	{"shapefile", "N"},
+	{"showboxes", "ENG"},
+	{"sides", "N"},
+	{"size", "G"},
+	{"skew", "N"},
+	{"smoothing", "G"},
+	{"sortv", "GCN"},
+	{"splines", "G"},
+	{"start", "G"},
+	{"style", "ENCG"},
+	{"stylesheet", "G"},
+	{"tail_lp", "E"},
+	{"tailclip", "E"},
+	{"tailhref", "E"},
+	{"taillabel", "E"},
+	{"tailport", "E"},
+	{"tailtarget", "E"},
+	{"tailtooltip", "E"},
+	{"tailURL", "E"},
+	{"target", "ENGC"},
+	{"TBbalance", "G"},
+	{"tooltip", "NEC"},
+	{"truecolor", "G"},
+	{"URL", "ENGC"},
+	{"vertices", "N"},
+	{"viewport", "G"},
+	{"voro_margin", "G"},
+	{"weight", "E"},
+	{"width", "N"},
+	{"xdotversion", "G"},
+	{"xlabel", "EN"},
+	{"xlp", "NE"},
+	{"z", "N"},
+}
+
+// findAttr returns the attribute with the given name, and a boolean value
+// indicating if such an attribute exists.
+func findAttr(name string) (attr, bool) {
+	for _, a := range attrs {
+		if a.name == name {
+			return a, true
+		}
+	}
+	return attr{}, false
+}
+
+// kindNames maps from component kind to its plural name, in order of
+// description.
+var kindNames = []struct {
+	kind string
+	name string
+}{
+	{"G", "graphs"},
+	{"S", "subgraphs"},
+	{"C", "clusters"},
+	{"N", "nodes"},
+	{"E", "edges"},
+}
+
+// describeKinds returns a description of the given component kinds; e.g.
+// "graphs, nodes".
+func describeKinds(kinds string) string {
+	var names []string
+	for _, k := range kindNames {
+		if strings.Contains(kinds, k.kind) {
+			names = append(names, k.name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
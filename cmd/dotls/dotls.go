@@ -0,0 +1,512 @@
+// dotls is a language server for Graphviz DOT files, which speaks the Language
+// Server Protocol over standard input and output.
+//
+// Usage: dotls [OPTION]...
+//
+//   -log string
+//         log file path
+//
+// The language server provides diagnostics of the parser and semantic checker,
+// formatting, document symbols (graphs, clusters and nodes), go to definition
+// and find references of nodes, renaming of nodes, and completion and hover
+// descriptions of attribute names.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/internal/enc"
+	"github.com/pkg/errors"
+)
+
+func main() {
+	// Parse command line flags.
+	var (
+		// logPath specifies the log file path.
+		logPath string
+	)
+	flag.StringVar(&logPath, "log", "", "log file path")
+	flag.Parse()
+	log.SetPrefix("dotls: ")
+	// Standard output is reserved for the protocol.
+	log.SetOutput(ioutil.Discard)
+	if len(logPath) > 0 {
+		f, err := os.Create(logPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		log.SetOutput(f)
+	}
+
+	// Serve requests.
+	s := newServer(os.Stdout)
+	if err := s.serve(bufio.NewReader(os.Stdin)); err != nil {
+		log.Print(err)
+		os.Exit(1)
+	}
+	if !s.shutdown {
+		// Exit without shutdown request.
+		os.Exit(1)
+	}
+}
+
+// === [ Server ] ==============================================================
+
+// A server is a DOT language server.
+type server struct {
+	// Output stream of the protocol.
+	w io.Writer
+	// Open documents, by URI.
+	docs map[string]*document
+	// Shutdown requested.
+	shutdown bool
+}
+
+// newServer returns a new language server, writing to w.
+func newServer(w io.Writer) *server {
+	return &server{w: w, docs: make(map[string]*document)}
+}
+
+// serve serves the requests and notifications read from r, until the exit
+// notification or the end of input.
+func (s *server) serve(r *bufio.Reader) error {
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			if e, ok := err.(*rpcError); ok {
+				if err := writeMessage(s.w, &errorResponse{JSONRPC: "2.0", Error: e}); err != nil {
+					return errors.WithStack(err)
+				}
+				continue
+			}
+			if errors.Cause(err) == io.EOF {
+				return nil
+			}
+			return errors.WithStack(err)
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		result, err := s.handle(msg)
+		if msg.ID == nil {
+			// Notifications have no response.
+			if err != nil {
+				log.Printf("%s: %v", msg.Method, err)
+			}
+			continue
+		}
+		if err != nil {
+			e, ok := errors.Cause(err).(*rpcError)
+			if !ok {
+				e = &rpcError{Code: codeInternalError, Message: err.Error()}
+			}
+			if err := writeMessage(s.w, &errorResponse{JSONRPC: "2.0", ID: msg.ID, Error: e}); err != nil {
+				return errors.WithStack(err)
+			}
+			continue
+		}
+		if err := writeMessage(s.w, &response{JSONRPC: "2.0", ID: msg.ID, Result: result}); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+}
+
+// handle handles the given request or notification, and returns its result.
+func (s *server) handle(msg *message) (interface{}, error) {
+	switch msg.Method {
+	// Lifecycle.
+	case "initialize":
+		return s.initialize()
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	// Synchronization.
+	case "textDocument/didOpen":
+		return s.didOpen(msg.Params)
+	case "textDocument/didChange":
+		return s.didChange(msg.Params)
+	case "textDocument/didClose":
+		return s.didClose(msg.Params)
+	// Language features.
+	case "textDocument/formatting":
+		return s.formatting(msg.Params)
+	case "textDocument/documentSymbol":
+		return s.documentSymbol(msg.Params)
+	case "textDocument/definition":
+		return s.definition(msg.Params)
+	case "textDocument/references":
+		return s.references(msg.Params)
+	case "textDocument/rename":
+		return s.rename(msg.Params)
+	case "textDocument/completion":
+		return s.completion(msg.Params)
+	case "textDocument/hover":
+		return s.hover(msg.Params)
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", msg.Method)}
+}
+
+// decode decodes the given parameters into v.
+func decode(params json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// doc returns the open document with the given URI.
+func (s *server) doc(uri string) (*document, error) {
+	d, ok := s.docs[uri]
+	if !ok {
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("document %q not open", uri)}
+	}
+	return d, nil
+}
+
+// --- [ Lifecycle ] -----------------------------------------------------------
+
+// initialize handles the initialize request.
+func (s *server) initialize() (interface{}, error) {
+	result := &initializeResult{
+		Capabilities: serverCapabilities{
+			TextDocumentSync:           syncFull,
+			DocumentFormattingProvider: true,
+			DocumentSymbolProvider:     true,
+			DefinitionProvider:         true,
+			ReferencesProvider:         true,
+			RenameProvider:             true,
+			HoverProvider:              true,
+			CompletionProvider: &completionOptions{
+				TriggerCharacters: []string{"[", ",", ";"},
+			},
+		},
+		ServerInfo: serverInfo{Name: "dotls"},
+	}
+	return result, nil
+}
+
+// --- [ Synchronization ] -----------------------------------------------------
+
+// didOpen handles the textDocument/didOpen notification.
+func (s *server) didOpen(params json.RawMessage) (interface{}, error) {
+	var p didOpenParams
+	if err := decode(params, &p); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return nil, s.update(p.TextDocument.URI, p.TextDocument.Text)
+}
+
+// didChange handles the textDocument/didChange notification.
+func (s *server) didChange(params json.RawMessage) (interface{}, error) {
+	var p didChangeParams
+	if err := decode(params, &p); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(p.ContentChanges) == 0 {
+		return nil, nil
+	}
+	// Full content, as per syncFull.
+	text := p.ContentChanges[len(p.ContentChanges)-1].Text
+	return nil, s.update(p.TextDocument.URI, text)
+}
+
+// didClose handles the textDocument/didClose notification.
+func (s *server) didClose(params json.RawMessage) (interface{}, error) {
+	var p didCloseParams
+	if err := decode(params, &p); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	delete(s.docs, p.TextDocument.URI)
+	// Clear the diagnostics of the closed document.
+	return nil, s.publish(p.TextDocument.URI, []diagnostic{})
+}
+
+// update updates the contents of the given document, and publishes its
+// diagnostics.
+func (s *server) update(uri, text string) error {
+	d := newDocument(uri, []byte(text))
+	s.docs[uri] = d
+	return s.publish(uri, diagnostics(d))
+}
+
+// publish publishes the diagnostics of the given document.
+func (s *server) publish(uri string, diags []diagnostic) error {
+	n := &notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  &publishDiagnosticsParams{URI: uri, Diagnostics: diags},
+	}
+	return writeMessage(s.w, n)
+}
+
+// --- [ Diagnostics ] ---------------------------------------------------------
+
+// parseOptions returns the options of the parser for documents, recording
+// positions in the given map if non-nil. Documents are UTF-8 encoded, as per
+// the protocol, and not semantically checked by the parser.
+func parseOptions(positions dot.Positions) dot.ParseOptions {
+	return dot.ParseOptions{Charset: dot.CharsetUTF8, Check: dot.CheckNone, Positions: positions}
+}
+
+// diagnostics returns the diagnostics of the parser and semantic checker for
+// the given document.
+func diagnostics(d *document) []diagnostic {
+	diags := []diagnostic{}
+	// Semantic checking is done separately to report warnings.
+	positions := make(dot.Positions)
+	file, err := dot.ParseBytesWithOptions(d.src, parseOptions(positions))
+	if err != nil {
+		return append(diags, syntaxDiagnostic(d, err))
+	}
+	for _, diag := range dot.Check(file) {
+		// Semantic problems are reported at the AST node of the problem, or at
+		// the ID of their graph if unknown.
+		span, ok := positions[diag.Node]
+		if !ok {
+			span = positions[diag.Graph]
+		}
+		severity := severityInformation
+		switch diag.Severity {
+		case dot.SeverityError:
+			severity = severityError
+		case dot.SeverityWarning:
+			severity = severityWarning
+		}
+		r := d.span(span.Offset, span.Offset+span.Len)
+		diags = append(diags, diagnostic{Range: r, Severity: severity, Source: "dot", Message: diag.Msg})
+	}
+	return diags
+}
+
+// syntaxDiagnostic returns the diagnostic of the given parse error, located at
+// the erroneous token.
+func syntaxDiagnostic(d *document, err error) diagnostic {
+	diag := diagnostic{Severity: severityError, Source: "dot", Message: err.Error()}
+	e, ok := errors.Cause(err).(*dot.SyntaxError)
+	if !ok {
+		return diag
+	}
+	diag.Range = d.span(e.Offset, e.Offset+e.Len)
+	diag.Message = e.Msg
+	return diag
+}
+
+// --- [ Formatting ] ----------------------------------------------------------
+
+// formatting handles the textDocument/formatting request. Documents are
+// formatted as by dotfmt, unless they contain errors.
+func (s *server) formatting(params json.RawMessage) (interface{}, error) {
+	var p documentFormattingParams
+	if err := decode(params, &p); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	d, err := s.doc(p.TextDocument.URI)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	file, err := dot.ParseBytesWithOptions(d.src, parseOptions(nil))
+	if err != nil {
+		return nil, nil
+	}
+	text := file.String() + "\n"
+	if text == string(d.src) {
+		return []textEdit{}, nil
+	}
+	return []textEdit{{Range: d.span(0, len(d.src)), NewText: text}}, nil
+}
+
+// --- [ Document symbols ] ----------------------------------------------------
+
+// documentSymbol handles the textDocument/documentSymbol request.
+func (s *server) documentSymbol(params json.RawMessage) (interface{}, error) {
+	var p documentSymbolParams
+	if err := decode(params, &p); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	d, err := s.doc(p.TextDocument.URI)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	syms := []*documentSymbol{}
+	for _, g := range d.idx.graphs {
+		syms = append(syms, d.documentSymbol(g.sym))
+	}
+	return syms, nil
+}
+
+// documentSymbol returns the document symbol of the given symbol.
+func (d *document) documentSymbol(sym *symbol) *documentSymbol {
+	ds := &documentSymbol{
+		Name:           sym.name,
+		Kind:           sym.kind,
+		Range:          d.span(sym.start, sym.end),
+		SelectionRange: d.span(sym.idStart, sym.idEnd),
+	}
+	for _, child := range sym.children {
+		ds.Children = append(ds.Children, d.documentSymbol(child))
+	}
+	return ds
+}
+
+// --- [ Definitions and references ] ------------------------------------------
+
+// definition handles the textDocument/definition request, locating the first
+// node statement of the node at the given position.
+func (s *server) definition(params json.RawMessage) (interface{}, error) {
+	var p textDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	d, err := s.doc(p.TextDocument.URI)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	occ, g := d.idx.occurrence(d.offset(p.Position))
+	if occ == nil {
+		return nil, nil
+	}
+	def := g.definition(occ)
+	return &location{URI: d.uri, Range: d.span(def.start, def.end)}, nil
+}
+
+// references handles the textDocument/references request, locating the
+// occurrences of the node at the given position.
+func (s *server) references(params json.RawMessage) (interface{}, error) {
+	var p referenceParams
+	if err := decode(params, &p); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	d, err := s.doc(p.TextDocument.URI)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	locs := []location{}
+	occ, g := d.idx.occurrence(d.offset(p.Position))
+	if occ == nil {
+		return locs, nil
+	}
+	def := g.definition(occ)
+	for _, ref := range g.references(occ) {
+		if ref == def && !p.Context.IncludeDeclaration {
+			continue
+		}
+		locs = append(locs, location{URI: d.uri, Range: d.span(ref.start, ref.end)})
+	}
+	return locs, nil
+}
+
+// rename handles the textDocument/rename request, renaming the occurrences of
+// the node at the given position.
+func (s *server) rename(params json.RawMessage) (interface{}, error) {
+	var p renameParams
+	if err := decode(params, &p); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	d, err := s.doc(p.TextDocument.URI)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(p.NewName) == 0 {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "invalid node ID; empty name"}
+	}
+	occ, g := d.idx.occurrence(d.offset(p.Position))
+	if occ == nil {
+		return nil, nil
+	}
+	var edits []textEdit
+	for _, ref := range g.references(occ) {
+		edits = append(edits, textEdit{Range: d.span(ref.start, ref.end), NewText: enc.Quote(p.NewName)})
+	}
+	return &workspaceEdit{Changes: map[string][]textEdit{d.uri: edits}}, nil
+}
+
+// --- [ Completion ] ----------------------------------------------------------
+
+// completion handles the textDocument/completion request, proposing the names
+// of the attributes of the component kind of the attribute list at the given
+// position.
+func (s *server) completion(params json.RawMessage) (interface{}, error) {
+	var p textDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	d, err := s.doc(p.TextDocument.URI)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	items := []completionItem{}
+	offset := d.offset(p.Position)
+	for _, list := range d.idx.attrLists {
+		if offset < list.start || offset > list.end || !isKeyPos(d.src[list.start:offset]) {
+			continue
+		}
+		for _, a := range attrs {
+			if strings.ContainsAny(a.usedBy, list.kinds) {
+				items = append(items, completionItem{Label: a.name, Kind: completionProperty, Detail: "used by " + a.usedBy})
+			}
+		}
+		break
+	}
+	return items, nil
+}
+
+// isKeyPos reports whether the end of the given attribute list prefix is at an
+// attribute name, rather than an attribute value.
+func isKeyPos(prefix []byte) bool {
+	i := len(prefix)
+	// Skip the partial attribute name.
+	for i > 0 && isAlnum(prefix[i-1]) {
+		i--
+	}
+	for i > 0 && (prefix[i-1] == ' ' || prefix[i-1] == '\t' || prefix[i-1] == '\n' || prefix[i-1] == '\r') {
+		i--
+	}
+	return i == 0 || prefix[i-1] != '='
+}
+
+// isAlnum reports whether the given character is part of an alphanumeric ID.
+func isAlnum(c byte) bool {
+	return c == '_' || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c >= 0x80
+}
+
+// --- [ Hover ] ---------------------------------------------------------------
+
+// hover handles the textDocument/hover request, describing the attribute at the
+// given position.
+func (s *server) hover(params json.RawMessage) (interface{}, error) {
+	var p textDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	d, err := s.doc(p.TextDocument.URI)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	key := d.idx.key(d.offset(p.Position))
+	if key == nil {
+		return nil, nil
+	}
+	a, ok := findAttr(key.name)
+	if !ok {
+		return nil, nil
+	}
+	text := fmt.Sprintf("**%s**: attribute of %s", a.name, describeKinds(a.usedBy))
+	if !strings.ContainsAny(a.usedBy, key.kinds) {
+		text += fmt.Sprintf("; not used by %s", describeKinds(key.kinds))
+	}
+	text += fmt.Sprintf("\n\nhttps://graphviz.org/docs/attrs/%s/", a.name)
+	return &hover{Contents: markupContent{Kind: "markdown", Value: text}, Range: d.span(key.start, key.end)}, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSession(t *testing.T) {
+	const src = "digraph G {\n\ta [color=nocolor]\n\tb [rankdir=LR]\n\ta -> b\n}\n"
+	const uri = "file:///g.dot"
+	// Requests and notifications of the client, and the expected messages of
+	// the server in response.
+	script := []struct {
+		in   string
+		want []string
+	}{
+		{
+			in:   `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+			want: []string{`{"jsonrpc":"2.0","id":1,"result":{"capabilities":{"textDocumentSync":1,"documentFormattingProvider":true,"documentSymbolProvider":true,"definitionProvider":true,"referencesProvider":true,"renameProvider":true,"hoverProvider":true,"completionProvider":{"triggerCharacters":["[",",",";"]}},"serverInfo":{"name":"dotls"}}}`},
+		},
+		{
+			in: `{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		},
+		{
+			in:   fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":%q,"languageId":"dot","version":1,"text":%q}}}`, uri, src),
+			want: []string{`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"file:///g.dot","diagnostics":[{"range":{"start":{"line":1,"character":4},"end":{"line":1,"character":17}},"severity":2,"source":"dot","message":"invalid color nocolor of node \"a\"; unknown colour \"nocolor\" in colour scheme \"x11\""}]}}`},
+		},
+		// Hover of attribute key.
+		{
+			in:   fmt.Sprintf(`{"jsonrpc":"2.0","id":2,"method":"textDocument/hover","params":{"textDocument":{"uri":%q},"position":{"line":1,"character":5}}}`, uri),
+			want: []string{`{"jsonrpc":"2.0","id":2,"result":{"contents":{"kind":"markdown","value":"**color**: attribute of clusters, nodes, edges\n\nhttps://graphviz.org/docs/attrs/color/"},"range":{"start":{"line":1,"character":4},"end":{"line":1,"character":9}}}}`},
+		},
+		// Hover of attribute not used by the component kind.
+		{
+			in:   fmt.Sprintf(`{"jsonrpc":"2.0","id":3,"method":"textDocument/hover","params":{"textDocument":{"uri":%q},"position":{"line":2,"character":4}}}`, uri),
+			want: []string{`{"jsonrpc":"2.0","id":3,"result":{"contents":{"kind":"markdown","value":"**rankdir**: attribute of graphs; not used by nodes\n\nhttps://graphviz.org/docs/attrs/rankdir/"},"range":{"start":{"line":2,"character":4},"end":{"line":2,"character":11}}}}`},
+		},
+		// Hover of attribute value.
+		{
+			in:   fmt.Sprintf(`{"jsonrpc":"2.0","id":4,"method":"textDocument/hover","params":{"textDocument":{"uri":%q},"position":{"line":1,"character":12}}}`, uri),
+			want: []string{`{"jsonrpc":"2.0","id":4,"result":null}`},
+		},
+		// Syntax error.
+		{
+			in:   fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":%q,"version":2},"contentChanges":[{"text":"graph {\n\ta -- \n}"}]}}`, uri),
+			want: []string{`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"file:///g.dot","diagnostics":[{"range":{"start":{"line":2,"character":0},"end":{"line":2,"character":1}},"severity":1,"source":"dot","message":"missing node or subgraph after \"--\"; got \"}\""}]}}`},
+		},
+		// Semantic error.
+		{
+			in:   fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":%q,"version":3},"contentChanges":[{"text":"graph {\n\ta -> b\n}"}]}}`, uri),
+			want: []string{`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"file:///g.dot","diagnostics":[{"range":{"start":{"line":1,"character":3},"end":{"line":1,"character":5}},"severity":1,"source":"dot","message":"undirected graph \"\" contains directed edge from \"a\" to \"b\""}]}}`},
+		},
+		// Formatting of documents, which are UTF-8 encoded regardless of the
+		// charset attribute.
+		{
+			in:   fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":%q,"version":4},"contentChanges":[{"text":"graph { charset=latin1; café }"}]}}`, uri),
+			want: []string{`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"file:///g.dot","diagnostics":[]}}`},
+		},
+		{
+			in:   fmt.Sprintf(`{"jsonrpc":"2.0","id":6,"method":"textDocument/formatting","params":{"textDocument":{"uri":%q},"options":{"tabSize":4,"insertSpaces":false}}}`, uri),
+			want: []string{`{"jsonrpc":"2.0","id":6,"result":[{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":30}},"newText":"graph {\n\tcharset=latin1\n\tcafé\n}\n"}]}`},
+		},
+		{
+			in:   `{"jsonrpc":"2.0","id":7,"method":"shutdown"}`,
+			want: []string{`{"jsonrpc":"2.0","id":7,"result":null}`},
+		},
+		{
+			in: `{"jsonrpc":"2.0","method":"exit"}`,
+		},
+	}
+	in := &bytes.Buffer{}
+	for _, step := range script {
+		fmt.Fprintf(in, "Content-Length: %d\r\n\r\n%s", len(step.in), step.in)
+	}
+	out := &bytes.Buffer{}
+	s := newServer(out)
+	if err := s.serve(bufio.NewReader(in)); err != nil {
+		t.Fatalf("unable to serve requests; %+v", err)
+	}
+	if !s.shutdown {
+		t.Errorf("shutdown mismatch; expected shutdown request to be handled")
+	}
+	got, err := readOutput(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	for _, step := range script {
+		want = append(want, step.want...)
+	}
+	for i := 0; i < len(got) || i < len(want); i++ {
+		var g, w string
+		if i < len(got) {
+			g = got[i]
+		}
+		if i < len(want) {
+			w = want[i]
+		}
+		if g != w {
+			t.Errorf("message %d mismatch; expected `%s`, got `%s`", i, w, g)
+		}
+	}
+}
+
+// readOutput returns the messages written by the server, as framed by
+// Content-Length headers.
+func readOutput(r io.Reader) ([]string, error) {
+	var msgs []string
+	br := bufio.NewReader(r)
+	for {
+		header, err := br.ReadString('\n')
+		if err == io.EOF {
+			return msgs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "Content-Length:")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid header %q", header)
+		}
+		if _, err := br.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, string(buf))
+	}
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/graphism/dot/internal/enc"
+	"github.com/graphism/dot/internal/scanner"
+	"github.com/graphism/dot/internal/token"
+)
+
+// === [ Documents ] ===========================================================
+
+// A document is an open DOT file.
+type document struct {
+	// Document URI.
+	uri string
+	// Document contents.
+	src []byte
+	// Offsets of the start of each line.
+	lines []int
+	// Index of the document.
+	idx *index
+}
+
+// newDocument returns a new indexed document of the given contents.
+func newDocument(uri string, src []byte) *document {
+	d := &document{uri: uri, src: src, lines: []int{0}}
+	for i, b := range src {
+		if b == '\n' {
+			d.lines = append(d.lines, i+1)
+		}
+	}
+	d.idx = newIndex(src)
+	return d
+}
+
+// position returns the position of the given byte offset.
+func (d *document) position(offset int) position {
+	line := sort.Search(len(d.lines), func(i int) bool { return d.lines[i] > offset }) - 1
+	character := 0
+	for _, r := range string(d.src[d.lines[line]:offset]) {
+		character += len(utf16.Encode([]rune{r}))
+	}
+	return position{Line: line, Character: character}
+}
+
+// offset returns the byte offset of the given position, clamped to the line of
+// the position.
+func (d *document) offset(pos position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(d.lines) {
+		return len(d.src)
+	}
+	offset := d.lines[pos.Line]
+	for character := 0; character < pos.Character && offset < len(d.src) && d.src[offset] != '\n'; {
+		r, size := utf8.DecodeRune(d.src[offset:])
+		character += len(utf16.Encode([]rune{r}))
+		offset += size
+	}
+	return offset
+}
+
+// span returns the range between the given byte offsets.
+func (d *document) span(start, end int) lspRange {
+	return lspRange{Start: d.position(start), End: d.position(end)}
+}
+
+// === [ Index ] ===============================================================
+
+// An index locates the graphs, clusters, nodes and attribute lists of a DOT
+// file. The index is built from the tokens of the file, and is thus available
+// also for files with syntax errors, as while editing.
+type index struct {
+	// Graphs of the file.
+	graphs []*graphIndex
+	// Attribute lists of the file.
+	attrLists []*attrList
+	// Attribute keys of the file, in order of occurrence.
+	keys []*attrKey
+}
+
+// A graphIndex locates the symbols and node occurrences of a graph.
+type graphIndex struct {
+	// Graph symbol, with nested cluster and node symbols.
+	sym *symbol
+	// Occurrences of node IDs, in order of occurrence.
+	occs []*occurrence
+}
+
+// A symbol is a graph, cluster or node of a DOT file.
+type symbol struct {
+	// Symbol name.
+	name string
+	// Symbol kind.
+	kind int
+	// Byte offsets of the symbol.
+	start, end int
+	// Byte offsets of the ID of the symbol.
+	idStart, idEnd int
+	// Nested symbols.
+	children []*symbol
+}
+
+// An occurrence is an occurrence of a node ID.
+type occurrence struct {
+	// Unquoted node ID.
+	id string
+	// Byte offsets of the node ID.
+	start, end int
+	// Node ID of a node statement.
+	decl bool
+	// Innermost graph or cluster symbol containing the occurrence.
+	parent *symbol
+}
+
+// An attrList is an attribute list of a DOT file.
+type attrList struct {
+	// Byte offsets of the attribute list, exclusive of the brackets.
+	start, end int
+	// Component kinds of the attributes; any of "G" (graph), "S" (subgraph),
+	// "C" (cluster), "N" (node) and "E" (edge).
+	kinds string
+}
+
+// An attrKey is an occurrence of an attribute key.
+type attrKey struct {
+	// Unquoted attribute key.
+	name string
+	// Byte offsets of the attribute key.
+	start, end int
+	// Component kinds of the attribute; any of "G" (graph), "S" (subgraph),
+	// "C" (cluster), "N" (node) and "E" (edge).
+	kinds string
+}
+
+// key returns the attribute key at the given byte offset; or nil if not
+// present.
+func (idx *index) key(offset int) *attrKey {
+	for _, key := range idx.keys {
+		if key.start <= offset && offset <= key.end {
+			return key
+		}
+	}
+	return nil
+}
+
+// occurrence returns the occurrence of a node ID at the given byte offset; or
+// nil if not present, and the graph of the occurrence.
+func (idx *index) occurrence(offset int) (*occurrence, *graphIndex) {
+	for _, g := range idx.graphs {
+		for _, occ := range g.occs {
+			if occ.start <= offset && offset <= occ.end {
+				return occ, g
+			}
+		}
+	}
+	return nil, nil
+}
+
+// definition returns the definition of the given node occurrence; the first
+// node statement of the node, or its first occurrence if none.
+func (g *graphIndex) definition(occ *occurrence) *occurrence {
+	var first *occurrence
+	for _, o := range g.occs {
+		if o.id != occ.id {
+			continue
+		}
+		if o.decl {
+			return o
+		}
+		if first == nil {
+			first = o
+		}
+	}
+	return first
+}
+
+// references returns the occurrences of the node of the given node occurrence.
+func (g *graphIndex) references(occ *occurrence) []*occurrence {
+	var refs []*occurrence
+	for _, o := range g.occs {
+		if o.id == occ.id {
+			refs = append(refs, o)
+		}
+	}
+	return refs
+}
+
+// Token types.
+var (
+	tokLBrace   = token.TokMap.Type("{")
+	tokRBrace   = token.TokMap.Type("}")
+	tokStrict   = token.TokMap.Type("strict")
+	tokGraph    = token.TokMap.Type("graphx")
+	tokDigraph  = token.TokMap.Type("digraph")
+	tokSemi     = token.TokMap.Type(";")
+	tokUndirect = token.TokMap.Type("--")
+	tokDirect   = token.TokMap.Type("->")
+	tokNode     = token.TokMap.Type("node")
+	tokEdge     = token.TokMap.Type("edge")
+	tokLBrack   = token.TokMap.Type("[")
+	tokRBrack   = token.TokMap.Type("]")
+	tokComma    = token.TokMap.Type(",")
+	tokEqual    = token.TokMap.Type("=")
+	tokSubgraph = token.TokMap.Type("subgraph")
+	tokColon    = token.TokMap.Type(":")
+	tokID       = token.TokMap.Type("id")
+)
+
+// An indexer indexes the tokens of a DOT file, as specified by the DOT
+// grammar. Unexpected tokens are skipped.
+type indexer struct {
+	// Tokens of the file, terminated by an EOF token.
+	toks []*token.Token
+	// Current token.
+	cur int
+	// Index of the file.
+	idx *index
+	// Current graph.
+	g *graphIndex
+}
+
+// A scope is a graph or subgraph scope.
+type scope struct {
+	// Innermost graph or cluster symbol.
+	sym *symbol
+	// Component kinds of graph attributes in scope.
+	kinds string
+}
+
+// newIndex returns the index of the given DOT file.
+func newIndex(src []byte) *index {
+	ix := &indexer{idx: &index{}}
+	s := scanner.New(src)
+	for {
+		tok := s.Scan()
+		ix.toks = append(ix.toks, tok)
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+	for !ix.at(token.EOF) {
+		ix.graph()
+	}
+	for _, g := range ix.idx.graphs {
+		addNodeSymbols(g)
+	}
+	return ix.idx
+}
+
+// tok returns the current token.
+func (ix *indexer) tok() *token.Token {
+	return ix.toks[ix.cur]
+}
+
+// at reports whether the current token is of the given type.
+func (ix *indexer) at(typ token.Type) bool {
+	return ix.tok().Type == typ
+}
+
+// next advances to the next token, and returns the current token.
+func (ix *indexer) next() *token.Token {
+	tok := ix.tok()
+	if tok.Type != token.EOF {
+		ix.cur++
+	}
+	return tok
+}
+
+// endOf returns the end offset of the given token.
+func endOf(tok *token.Token) int {
+	return tok.Offset + len(tok.Lit)
+}
+
+// graph indexes a graph.
+//
+//    graph : [ 'strict' ] ( 'graph' | 'digraph' ) [ ID ] '{' stmt_list '}'
+func (ix *indexer) graph() {
+	start := ix.tok().Offset
+	if ix.at(tokStrict) {
+		ix.next()
+	}
+	if !ix.at(tokGraph) && !ix.at(tokDigraph) {
+		ix.next()
+		return
+	}
+	keyword := ix.next()
+	sym := &symbol{name: string(keyword.Lit), kind: symbolNamespace, start: start, idStart: keyword.Offset, idEnd: endOf(keyword)}
+	if ix.at(tokID) {
+		id := ix.next()
+		sym.name += " " + string(id.Lit)
+		sym.idStart, sym.idEnd = id.Offset, endOf(id)
+	}
+	ix.g = &graphIndex{sym: sym}
+	ix.idx.graphs = append(ix.idx.graphs, ix.g)
+	sym.end = ix.body(&scope{sym: sym, kinds: "G"})
+}
+
+// body indexes the statements of a graph or subgraph, enclosed in braces, and
+// returns the end offset of the body.
+func (ix *indexer) body(s *scope) int {
+	if !ix.at(tokLBrace) {
+		return ix.tok().Offset
+	}
+	ix.next()
+	for !ix.at(tokRBrace) && !ix.at(token.EOF) {
+		ix.stmt(s)
+	}
+	return endOf(ix.next())
+}
+
+// stmt indexes a statement.
+//
+//    stmt : ( 'graph' | 'node' | 'edge' ) attr_list
+//         | ID '=' ID
+//         | ( node_id | subgraph ) [ edgeRHS ] [ attr_list ]
+func (ix *indexer) stmt(s *scope) {
+	switch {
+	case ix.at(tokGraph):
+		ix.next()
+		ix.attrLists(s.kinds)
+	case ix.at(tokNode):
+		ix.next()
+		ix.attrLists("N")
+	case ix.at(tokEdge):
+		ix.next()
+		ix.attrLists("E")
+	case ix.at(tokID) && ix.toks[ix.cur+1].Type == tokEqual:
+		ix.key(s.kinds)
+		ix.next()
+		if ix.at(tokID) {
+			ix.next()
+		}
+	case ix.at(tokID):
+		occ := ix.nodeID(s)
+		if ix.at(tokDirect) || ix.at(tokUndirect) {
+			ix.edgeRHS(s)
+			ix.attrLists("E")
+		} else {
+			occ.decl = true
+			ix.attrLists("N")
+		}
+	case ix.at(tokSubgraph) || ix.at(tokLBrace):
+		ix.subgraph(s)
+		if ix.at(tokDirect) || ix.at(tokUndirect) {
+			ix.edgeRHS(s)
+			ix.attrLists("E")
+		}
+	default:
+		// Skip separators and unexpected tokens.
+		ix.next()
+	}
+}
+
+// nodeID indexes a node ID, and returns its occurrence.
+//
+//    node_id : ID [ ':' ID [ ':' ID ] ]
+func (ix *indexer) nodeID(s *scope) *occurrence {
+	id := ix.next()
+	occ := &occurrence{id: enc.Unquote(string(id.Lit)), start: id.Offset, end: endOf(id), parent: s.sym}
+	ix.g.occs = append(ix.g.occs, occ)
+	for i := 0; i < 2 && ix.at(tokColon); i++ {
+		ix.next()
+		if ix.at(tokID) {
+			ix.next()
+		}
+	}
+	return occ
+}
+
+// edgeRHS indexes the right-hand side of an edge statement.
+//
+//    edgeRHS : ( ( '--' | '->' ) ( node_id | subgraph ) )+
+func (ix *indexer) edgeRHS(s *scope) {
+	for ix.at(tokDirect) || ix.at(tokUndirect) {
+		ix.next()
+		switch {
+		case ix.at(tokID):
+			ix.nodeID(s)
+		case ix.at(tokSubgraph) || ix.at(tokLBrace):
+			ix.subgraph(s)
+		}
+	}
+}
+
+// subgraph indexes a subgraph.
+//
+//    subgraph : [ 'subgraph' [ ID ] ] '{' stmt_list '}'
+func (ix *indexer) subgraph(s *scope) {
+	start := ix.tok().Offset
+	child := &scope{sym: s.sym, kinds: "SC"}
+	if ix.at(tokSubgraph) {
+		ix.next()
+		if ix.at(tokID) {
+			id := ix.next()
+			if strings.HasPrefix(enc.Unquote(string(id.Lit)), "cluster") {
+				sym := &symbol{name: string(id.Lit), kind: symbolModule, start: start, idStart: id.Offset, idEnd: endOf(id)}
+				s.sym.children = append(s.sym.children, sym)
+				child = &scope{sym: sym, kinds: "C"}
+			}
+		}
+	}
+	end := ix.body(child)
+	if child.sym != s.sym {
+		child.sym.end = end
+	}
+}
+
+// attrLists indexes attribute lists of the given component kinds.
+//
+//    attr_list : ( '[' [ a_list ] ']' )+
+func (ix *indexer) attrLists(kinds string) {
+	for ix.at(tokLBrack) {
+		list := &attrList{start: endOf(ix.next()), kinds: kinds}
+		ix.idx.attrLists = append(ix.idx.attrLists, list)
+		for ix.at(tokID) || ix.at(tokEqual) || ix.at(tokComma) || ix.at(tokSemi) {
+			if ix.at(tokID) && ix.toks[ix.cur+1].Type == tokEqual {
+				ix.key(kinds)
+				continue
+			}
+			ix.next()
+		}
+		// The attribute list of an incomplete file ends at the first token not
+		// part of the list.
+		list.end = ix.tok().Offset
+		if ix.at(tokRBrack) {
+			ix.next()
+		}
+	}
+}
+
+// key indexes an attribute key of the given component kinds.
+func (ix *indexer) key(kinds string) {
+	id := ix.next()
+	key := &attrKey{name: enc.Unquote(string(id.Lit)), start: id.Offset, end: endOf(id), kinds: kinds}
+	ix.idx.keys = append(ix.idx.keys, key)
+}
+
+// addNodeSymbols adds the symbols of the nodes of the given graph, nested in
+// the graph or cluster of their definition.
+func addNodeSymbols(g *graphIndex) {
+	done := make(map[string]bool)
+	for _, occ := range g.occs {
+		if done[occ.id] {
+			continue
+		}
+		done[occ.id] = true
+		def := g.definition(occ)
+		sym := &symbol{name: occ.id, kind: symbolObject, start: def.start, end: def.end, idStart: def.start, idEnd: def.end}
+		def.parent.children = append(def.parent.children, sym)
+	}
+	sortSymbols(g.sym)
+}
+
+// sortSymbols sorts the nested symbols of the given symbol by offset.
+func sortSymbols(sym *symbol) {
+	sort.SliceStable(sym.children, func(i, j int) bool {
+		return sym.children[i].start < sym.children[j].start
+	})
+	for _, child := range sym.children {
+		sortSymbols(child)
+	}
+}
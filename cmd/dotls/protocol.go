@@ -0,0 +1,258 @@
+package main
+
+import "encoding/json"
+
+// === [ JSON-RPC ] ============================================================
+
+// A message is a JSON-RPC request or notification, sent by the client.
+type message struct {
+	// Request ID; or nil if notification.
+	ID *json.RawMessage `json:"id,omitempty"`
+	// Method name.
+	Method string `json:"method"`
+	// Method parameters.
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// A response is a JSON-RPC response to a successful request.
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+}
+
+// An errorResponse is a JSON-RPC response to a failed request.
+type errorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   *rpcError        `json:"error"`
+}
+
+// An rpcError is a JSON-RPC error.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error returns the error message.
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// A notification is a JSON-RPC notification, sent by the server.
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// === [ Language Server Protocol ] ============================================
+
+// ref: https://microsoft.github.io/language-server-protocol/specification
+
+// --- [ Basic structures ] ----------------------------------------------------
+
+// A position is a zero-based line and character offset within a document. The
+// character offset is measured in UTF-16 code units.
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// A lspRange is a range within a document; the end position is exclusive.
+type lspRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+// A location is a range within a document.
+type location struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+// A textEdit replaces a range of a document with new text.
+type textEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+// A workspaceEdit specifies the text edits of documents, by URI.
+type workspaceEdit struct {
+	Changes map[string][]textEdit `json:"changes"`
+}
+
+// textDocumentIdentifier identifies a document.
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+// textDocumentPositionParams specifies a position within a document.
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     position               `json:"position"`
+}
+
+// --- [ Lifecycle ] -----------------------------------------------------------
+
+// initializeResult is the result of the initialize request.
+type initializeResult struct {
+	Capabilities serverCapabilities `json:"capabilities"`
+	ServerInfo   serverInfo         `json:"serverInfo"`
+}
+
+// serverInfo specifies the name of the server.
+type serverInfo struct {
+	Name string `json:"name"`
+}
+
+// serverCapabilities specifies the capabilities of the server.
+type serverCapabilities struct {
+	// Synchronization of documents; full content on change.
+	TextDocumentSync           int                `json:"textDocumentSync"`
+	DocumentFormattingProvider bool               `json:"documentFormattingProvider"`
+	DocumentSymbolProvider     bool               `json:"documentSymbolProvider"`
+	DefinitionProvider         bool               `json:"definitionProvider"`
+	ReferencesProvider         bool               `json:"referencesProvider"`
+	RenameProvider             bool               `json:"renameProvider"`
+	HoverProvider              bool               `json:"hoverProvider"`
+	CompletionProvider         *completionOptions `json:"completionProvider"`
+}
+
+// Text document synchronization kinds.
+const (
+	syncFull = 1
+)
+
+// completionOptions specifies the characters which trigger completion.
+type completionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters"`
+}
+
+// --- [ Synchronization ] -----------------------------------------------------
+
+// didOpenParams are the parameters of the textDocument/didOpen notification.
+type didOpenParams struct {
+	TextDocument struct {
+		URI  string `json:"uri"`
+		Text string `json:"text"`
+	} `json:"textDocument"`
+}
+
+// didChangeParams are the parameters of the textDocument/didChange
+// notification.
+type didChangeParams struct {
+	TextDocument   textDocumentIdentifier `json:"textDocument"`
+	ContentChanges []struct {
+		// Full content of the document, as per syncFull.
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+// didCloseParams are the parameters of the textDocument/didClose notification.
+type didCloseParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+// --- [ Diagnostics ] ---------------------------------------------------------
+
+// publishDiagnosticsParams are the parameters of the
+// textDocument/publishDiagnostics notification.
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+// A diagnostic is a problem of a document.
+type diagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+// Diagnostic severities.
+const (
+	severityError       = 1
+	severityWarning     = 2
+	severityInformation = 3
+)
+
+// --- [ Language features ] ---------------------------------------------------
+
+// documentFormattingParams are the parameters of the textDocument/formatting
+// request.
+type documentFormattingParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+// documentSymbolParams are the parameters of the textDocument/documentSymbol
+// request.
+type documentSymbolParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+// A documentSymbol is a symbol of a document, with nested symbols.
+type documentSymbol struct {
+	Name           string            `json:"name"`
+	Detail         string            `json:"detail,omitempty"`
+	Kind           int               `json:"kind"`
+	Range          lspRange          `json:"range"`
+	SelectionRange lspRange          `json:"selectionRange"`
+	Children       []*documentSymbol `json:"children,omitempty"`
+}
+
+// Symbol kinds.
+const (
+	symbolModule    = 2
+	symbolNamespace = 3
+	symbolObject    = 19
+)
+
+// referenceParams are the parameters of the textDocument/references request.
+type referenceParams struct {
+	textDocumentPositionParams
+	Context struct {
+		IncludeDeclaration bool `json:"includeDeclaration"`
+	} `json:"context"`
+}
+
+// renameParams are the parameters of the textDocument/rename request.
+type renameParams struct {
+	textDocumentPositionParams
+	NewName string `json:"newName"`
+}
+
+// A completionItem is a completion proposal.
+type completionItem struct {
+	Label  string `json:"label"`
+	Kind   int    `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Completion item kinds.
+const (
+	completionProperty = 10
+)
+
+// A hover is the result of the textDocument/hover request.
+type hover struct {
+	Contents markupContent `json:"contents"`
+	Range    lspRange      `json:"range"`
+}
+
+// markupContent is formatted text.
+type markupContent struct {
+	// Format of the text; "plaintext" or "markdown".
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// readMessage reads a JSON-RPC message from r, framed by a Content-Length
+// header.
+func readMessage(r *bufio.Reader) (*message, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if len(line) == 0 {
+			break
+		}
+		pos := strings.IndexByte(line, ':')
+		if pos == -1 {
+			return nil, errors.Errorf("invalid header %q", line)
+		}
+		key, val := strings.TrimSpace(line[:pos]), strings.TrimSpace(line[pos+1:])
+		if strings.EqualFold(key, "Content-Length") {
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 0 {
+				return nil, errors.Errorf("invalid Content-Length header %q", val)
+			}
+			length = n
+		}
+	}
+	if length == -1 {
+		return nil, errors.New("missing Content-Length header")
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	msg := &message{}
+	if err := json.Unmarshal(buf, msg); err != nil {
+		return nil, &rpcError{Code: codeParseError, Message: err.Error()}
+	}
+	return msg, nil
+}
+
+// writeMessage writes the given JSON-RPC message to w, framed by a
+// Content-Length header.
+func writeMessage(w io.Writer, v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(buf), buf); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// graphml2dot is a tool which converts GraphML files to Graphviz DOT.
+//
+// Usage: graphml2dot [OPTION]... FILE...
+//
+//   -o string
+//         output path
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/graphism/dot/graphml"
+	"github.com/pkg/errors"
+)
+
+func main() {
+	// Parse command line flags.
+	var (
+		// output specifies the output path.
+		output string
+	)
+	flag.StringVar(&output, "o", "", "output path")
+	flag.Parse()
+
+	// Convert input files.
+	for _, path := range flag.Args() {
+		if err := graphml2dot(path, output); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// graphml2dot converts the given GraphML file to Graphviz DOT.
+func graphml2dot(path, output string) error {
+	// Parse input file.
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	graph, err := graphml.Unmarshal(buf)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Write to standard output.
+	w := os.Stdout
+
+	// Write to output file.
+	if len(output) > 0 {
+		f, err := os.Create(output)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	// Write to output stream.
+	if _, err := fmt.Fprintln(w, graph); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
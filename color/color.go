@@ -0,0 +1,264 @@
+// Package color implements parsing of Graphviz colour values.
+//
+// A colour is specified by one of the following forms.
+//
+//    #rrggbb     RGB colour
+//    #rrggbbaa   RGBA colour
+//    H,S,V       HSV colour; in the range [0, 1], separated by commas or spaces
+//    name        colour name of the colour scheme in scope
+//    /scheme/name  colour name of the given colour scheme
+//    //name      colour name of the default colour scheme (X11)
+//
+// Colour names are case-insensitive, and spaces within names are ignored. The
+// colour schemes are X11 ("x11"), SVG ("svg") and the Brewer colour schemes
+// (e.g. "blues9"), the colours of which are named "1" through "9".
+//
+// ref: https://graphviz.org/docs/attr-types/color/
+package color
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/graphism/dot/internal/enc"
+	"github.com/pkg/errors"
+)
+
+// DefaultScheme is the default colour scheme, used when no colour scheme is in
+// scope.
+const DefaultScheme = "x11"
+
+// Keys specifies the attribute keys with colour values, or colour list values.
+var Keys = []string{"bgcolor", "color", "fillcolor", "fontcolor", "labelfontcolor", "pencolor"}
+
+// IsKey reports whether the given attribute key has colour values.
+func IsKey(key string) bool {
+	key = enc.Unquote(key)
+	for _, k := range Keys {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
+
+// IsScheme reports whether the given colour scheme exists.
+func IsScheme(scheme string) bool {
+	scheme = canon(scheme)
+	if scheme == "" || scheme == "x11" || scheme == "svg" {
+		return true
+	}
+	_, ok := brewer[scheme]
+	return ok
+}
+
+// === [ Colours ] =============================================================
+
+// Parse parses the given colour, resolving colour names in the given colour
+// scheme; or the default colour scheme if empty. The colour may be quoted, as
+// in the value of an attribute.
+//
+// The returned colour has alpha-premultiplied colour components, as per
+// color.RGBA.
+func Parse(s, scheme string) (color.RGBA, error) {
+	if enc.IsQuoted(s) {
+		s = enc.Unquote(s)
+	}
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return color.RGBA{}, errors.New("empty colour")
+	case s[0] == '#':
+		return parseRGB(s)
+	case (s[0] == '.' || ('0' <= s[0] && s[0] <= '9')) && len(hsvFields(s)) == 3:
+		return parseHSV(s)
+	}
+	return lookup(s, scheme)
+}
+
+// parseRGB parses the given RGB or RGBA colour; "#rrggbb" or "#rrggbbaa".
+func parseRGB(s string) (color.RGBA, error) {
+	hex := s[1:]
+	if len(hex) != 6 && len(hex) != 8 {
+		return color.RGBA{}, errors.Errorf("invalid RGB colour %q; expected #rrggbb or #rrggbbaa", s)
+	}
+	x, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, errors.Errorf("invalid RGB colour %q; invalid hexadecimal digits", s)
+	}
+	if len(hex) == 6 {
+		return rgb(uint32(x)), nil
+	}
+	c := color.NRGBA{R: uint8(x >> 24), G: uint8(x >> 16), B: uint8(x >> 8), A: uint8(x)}
+	return color.RGBAModel.Convert(c).(color.RGBA), nil
+}
+
+// parseHSV parses the given HSV colour; "H,S,V" or "H S V".
+//
+// pre-condition: s has 3 components.
+func parseHSV(s string) (color.RGBA, error) {
+	var hsv [3]float64
+	for i, f := range hsvFields(s) {
+		x, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return color.RGBA{}, errors.Errorf("invalid HSV colour %q; invalid component %q", s, f)
+		}
+		// Components outside of [0, 1] are clamped, as by Graphviz.
+		hsv[i] = math.Max(0, math.Min(1, x))
+	}
+	return HSV(hsv[0], hsv[1], hsv[2]), nil
+}
+
+// hsvFields returns the components of the given HSV colour, separated by
+// commas or spaces.
+func hsvFields(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// HSV returns the RGB colour of the given HSV colour, the components of which
+// are in the range [0, 1].
+func HSV(h, s, v float64) color.RGBA {
+	h = math.Mod(h*6, 6)
+	i := math.Floor(h)
+	f := h - i
+	p, q, t := v*(1-s), v*(1-s*f), v*(1-s*(1-f))
+	var r, g, b float64
+	switch int(i) {
+	case 0:
+		r, g, b = v, t, p
+	case 1:
+		r, g, b = q, v, p
+	case 2:
+		r, g, b = p, v, t
+	case 3:
+		r, g, b = p, q, v
+	case 4:
+		r, g, b = t, p, v
+	default:
+		r, g, b = v, p, q
+	}
+	return color.RGBA{R: uint8(math.Round(r * 255)), G: uint8(math.Round(g * 255)), B: uint8(math.Round(b * 255)), A: 0xFF}
+}
+
+// lookup returns the colour of the given colour name, resolved in the given
+// colour scheme unless the name specifies its scheme.
+func lookup(name, scheme string) (color.RGBA, error) {
+	if strings.HasPrefix(name, "/") {
+		// "/scheme/name" or "//name".
+		parts := strings.SplitN(name[1:], "/", 2)
+		if len(parts) != 2 {
+			return color.RGBA{}, errors.Errorf("invalid colour %q; expected /scheme/name", name)
+		}
+		scheme, name = parts[0], parts[1]
+	}
+	scheme, key := canon(scheme), canon(name)
+	if scheme == "" {
+		scheme = DefaultScheme
+	}
+	switch key {
+	case "transparent", "none", "invis":
+		return color.RGBA{}, nil
+	case "black", "white", "lightgrey":
+		// Available in every colour scheme, as by Graphviz.
+		return rgb(x11[key]), nil
+	}
+	switch scheme {
+	case "x11":
+		if x, ok := x11[key]; ok {
+			return rgb(x), nil
+		}
+	case "svg":
+		if x, ok := svg[key]; ok {
+			return rgb(x), nil
+		}
+	default:
+		colors, ok := brewer[scheme]
+		if !ok {
+			return color.RGBA{}, errors.Errorf("unknown colour scheme %q", scheme)
+		}
+		if i, err := strconv.Atoi(key); err == nil && i >= 1 && i <= len(colors) {
+			return rgb(colors[i-1]), nil
+		}
+	}
+	return color.RGBA{}, errors.Errorf("unknown colour %q in colour scheme %q", name, scheme)
+}
+
+// canon returns the canonical form of the given colour or colour scheme name;
+// in lower case and without spaces.
+func canon(name string) string {
+	return strings.ToLower(strings.Replace(name, " ", "", -1))
+}
+
+// rgb returns the opaque colour of the given RGB colour value.
+func rgb(x uint32) color.RGBA {
+	return color.RGBA{R: uint8(x >> 16), G: uint8(x >> 8), B: uint8(x), A: 0xFF}
+}
+
+// === [ Colour lists ] ========================================================
+
+// A Weighted is a colour of a colour list, with an optional weight.
+type Weighted struct {
+	// Colour.
+	Color color.RGBA
+	// Fraction of the area covered by the colour; or 0 if unspecified, in which
+	// case the remaining area is shared by the colours without weight.
+	Weight float64
+}
+
+// String returns the string representation of the weighted colour, as an
+// RGBA colour optionally followed by its weight.
+func (w Weighted) String() string {
+	s := Format(w.Color)
+	if w.Weight != 0 {
+		s += ";" + strconv.FormatFloat(w.Weight, 'f', -1, 64)
+	}
+	return s
+}
+
+// ParseList parses the given colour list; colours separated by ':', each
+// optionally followed by ';' and its weight (e.g. "red;0.3:blue"). Colour names
+// are resolved in the given colour scheme; or the default colour scheme if
+// empty. The colour list may be quoted, as in the value of an attribute.
+func ParseList(s, scheme string) ([]Weighted, error) {
+	if enc.IsQuoted(s) {
+		s = enc.Unquote(s)
+	}
+	var list []Weighted
+	total := 0.0
+	for _, item := range strings.Split(s, ":") {
+		var w Weighted
+		if pos := strings.LastIndex(item, ";"); pos != -1 {
+			weight, err := strconv.ParseFloat(strings.TrimSpace(item[pos+1:]), 64)
+			if err != nil || weight < 0 || weight > 1 {
+				return nil, errors.Errorf("invalid weight %q of colour %q; expected number in range [0, 1]", item[pos+1:], item[:pos])
+			}
+			w.Weight = weight
+			total += weight
+			item = item[:pos]
+		}
+		c, err := Parse(item, scheme)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		w.Color = c
+		list = append(list, w)
+	}
+	// Allow for rounding errors of weights specified with limited precision.
+	if total > 1+1e-6 {
+		return nil, errors.Errorf("invalid colour list %q; sum of weights (%g) exceeds 1", s, total)
+	}
+	return list, nil
+}
+
+// Format returns the string representation of the given colour, as "#rrggbb";
+// or "#rrggbbaa" if not opaque.
+func Format(c color.RGBA) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if n.A == 0xFF {
+		return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B)
+	}
+	return fmt.Sprintf("#%02x%02x%02x%02x", n.R, n.G, n.B, n.A)
+}
@@ -0,0 +1,125 @@
+package color_test
+
+import (
+	imgcolor "image/color"
+	"reflect"
+	"testing"
+
+	"github.com/graphism/dot/color"
+)
+
+func TestParse(t *testing.T) {
+	golden := []struct {
+		in     string
+		scheme string
+		want   imgcolor.RGBA
+	}{
+		{in: `red`, want: imgcolor.RGBA{R: 0xFF, A: 0xFF}},
+		{in: `"Light Goldenrod Yellow"`, want: imgcolor.RGBA{R: 0xFA, G: 0xFA, B: 0xD2, A: 0xFF}},
+		{in: `gray`, want: imgcolor.RGBA{R: 0xBE, G: 0xBE, B: 0xBE, A: 0xFF}},
+		{in: `gray`, scheme: "svg", want: imgcolor.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xFF}},
+		{in: `/svg/gray`, scheme: "blues9", want: imgcolor.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xFF}},
+		{in: `//gray`, scheme: "svg", want: imgcolor.RGBA{R: 0xBE, G: 0xBE, B: 0xBE, A: 0xFF}},
+		{in: `3`, scheme: "blues3", want: imgcolor.RGBA{R: 0x31, G: 0x82, B: 0xBD, A: 0xFF}},
+		{in: `/accent3/1`, want: imgcolor.RGBA{R: 0x7F, G: 0xC9, B: 0x7F, A: 0xFF}},
+		{in: `white`, scheme: "set312", want: imgcolor.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}},
+		{in: `#FF8000`, want: imgcolor.RGBA{R: 0xFF, G: 0x80, A: 0xFF}},
+		{in: `#ff000080`, want: imgcolor.RGBA{R: 0x80, A: 0x80}},
+		{in: `transparent`, want: imgcolor.RGBA{}},
+		{in: `"0.000 1.000 1.000"`, want: imgcolor.RGBA{R: 0xFF, A: 0xFF}},
+		{in: `.5,1,.5`, want: imgcolor.RGBA{G: 0x80, B: 0x80, A: 0xFF}},
+	}
+	for _, g := range golden {
+		got, err := color.Parse(g.in, g.scheme)
+		if err != nil {
+			t.Errorf("%q: unable to parse colour; %v", g.in, err)
+			continue
+		}
+		if got != g.want {
+			t.Errorf("%q: colour mismatch; expected %v, got %v", g.in, g.want, got)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	golden := []struct {
+		in     string
+		scheme string
+		want   string
+	}{
+		{
+			in:   `reddish`,
+			want: `unknown colour "reddish" in colour scheme "x11"`,
+		},
+		{
+			in:     `red`,
+			scheme: "blues9",
+			want:   `unknown colour "red" in colour scheme "blues9"`,
+		},
+		{
+			in:     `10`,
+			scheme: "blues9",
+			want:   `unknown colour "10" in colour scheme "blues9"`,
+		},
+		{
+			in:     `1`,
+			scheme: "blues",
+			want:   `unknown colour scheme "blues"`,
+		},
+		{
+			in:   `/red`,
+			want: `invalid colour "/red"; expected /scheme/name`,
+		},
+		{
+			in:   `#ff00`,
+			want: `invalid RGB colour "#ff00"; expected #rrggbb or #rrggbbaa`,
+		},
+		{
+			in:   `#gg0000`,
+			want: `invalid RGB colour "#gg0000"; invalid hexadecimal digits`,
+		},
+		{
+			in:   `0.5 0.5`,
+			want: `unknown colour "0.5 0.5" in colour scheme "x11"`,
+		},
+		{
+			in:   `0.5 x 0.5`,
+			want: `invalid HSV colour "0.5 x 0.5"; invalid component "x"`,
+		},
+		{
+			in:   ``,
+			want: "empty colour",
+		},
+	}
+	for _, g := range golden {
+		_, err := color.Parse(g.in, g.scheme)
+		if err == nil {
+			t.Errorf("%q: expected error, got nil", g.in)
+			continue
+		}
+		if got := err.Error(); got != g.want {
+			t.Errorf("%q: error mismatch; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+}
+
+func TestParseList(t *testing.T) {
+	const in = `"red;0.25:/blues3/2:#0000ff80;0.5"`
+	want := []color.Weighted{
+		{Color: imgcolor.RGBA{R: 0xFF, A: 0xFF}, Weight: 0.25},
+		{Color: imgcolor.RGBA{R: 0x9E, G: 0xCA, B: 0xE1, A: 0xFF}},
+		{Color: imgcolor.RGBA{B: 0x80, A: 0x80}, Weight: 0.5},
+	}
+	got, err := color.ParseList(in, "")
+	if err != nil {
+		t.Fatalf("%q: unable to parse colour list; %v", in, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%q: colour list mismatch; expected %v, got %v", in, want, got)
+	}
+	for _, in := range []string{`red;0.6:blue;0.6`, `red;x`, `red:`} {
+		if _, err := color.ParseList(in, ""); err == nil {
+			t.Errorf("%q: expected error, got nil", in)
+		}
+	}
+}
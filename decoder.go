@@ -0,0 +1,106 @@
+package dot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/internal/scanner"
+	"github.com/graphism/dot/internal/token"
+)
+
+// A Decoder reads Graphviz DOT files from an input stream, one top-level
+// statement at a time. Only the current statement is held in memory, which
+// enables processing of DOT files larger than the available memory.
+//
+// Statements are parsed but not semantically checked, as the semantics of a
+// statement depend on the entire graph. DOT files are expected to be UTF-8
+// encoded, and any byte order mark is skipped. The charset attribute is not
+// applied, as statements preceding it are already decoded; Latin-1 encoded DOT
+// files are to be transcoded to UTF-8 before decoding.
+type Decoder struct {
+	// Reader of the DOT file.
+	r io.Reader
+	// Parser of the DOT file; or nil before the first graph is read.
+	p *parser
+	// Current graph; or nil if outside of graph.
+	graph *ast.Graph
+	// Line of the current graph.
+	line int
+	// First error of the decoder.
+	err error
+}
+
+// NewDecoder returns a new decoder of the given DOT file, reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// NextGraph parses the header of the next graph of the DOT file, and returns
+// the graph without statements. The statements of the graph are returned by
+// Next. Any remaining statements of the preceding graph are skipped.
+//
+// At the end of the DOT file, NextGraph returns io.EOF.
+func (d *Decoder) NextGraph() (*ast.Graph, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	if d.p == nil {
+		br := bufio.NewReader(d.r)
+		if buf, _ := br.Peek(len(bom)); bytes.Equal(buf, bom) {
+			br.Discard(len(bom))
+		}
+		d.p = newParser(context.Background(), scanner.NewReader(br), ParseOptions{})
+		d.p.next()
+	}
+	for d.graph != nil {
+		if _, err := d.Next(); err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+	if d.p.tok.Type == token.EOF {
+		return nil, d.fail(io.EOF)
+	}
+	line := d.p.tok.Line
+	graph, err := d.p.parseGraphHeader()
+	if err != nil {
+		return nil, d.fail(err)
+	}
+	d.graph, d.line = graph, line
+	return graph, nil
+}
+
+// Next parses and returns the next top-level statement of the current graph.
+// Subgraphs are returned as a whole, including their statements.
+//
+// At the end of the current graph, or if no graph has been read, Next returns
+// io.EOF.
+func (d *Decoder) Next() (ast.Stmt, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	if d.graph == nil {
+		return nil, io.EOF
+	}
+	stmt, err := d.p.parseListStmt("graph", d.line)
+	if err != nil {
+		return nil, d.fail(err)
+	}
+	if stmt == nil {
+		d.graph = nil
+		return nil, io.EOF
+	}
+	return stmt, nil
+}
+
+// fail records and returns the given error of the decoder; or the read error
+// of the underlying reader, which ended the DOT file prematurely.
+func (d *Decoder) fail(err error) error {
+	if rerr := d.p.s.Err(); rerr != nil {
+		err = rerr
+	}
+	d.err = err
+	return err
+}
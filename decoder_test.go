@@ -0,0 +1,131 @@
+package dot_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/ast"
+)
+
+func TestDecoder(t *testing.T) {
+	paths, err := filepath.Glob("internal/testdata/*.dot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		if filepath.Base(path) == "error.dot" {
+			continue
+		}
+		want, err := dot.ParseFile(path)
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", path, err)
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Errorf("%q: unable to open file; %v", path, err)
+			continue
+		}
+		got, err := decode(dot.NewDecoder(iotest.OneByteReader(f)))
+		f.Close()
+		if err != nil {
+			t.Errorf("%q: unable to decode file; %v", path, err)
+			continue
+		}
+		if got.String() != want.String() {
+			t.Errorf("%q: file mismatch; expected `%v`, got `%v`", path, want, got)
+		}
+	}
+}
+
+func TestDecoderSkip(t *testing.T) {
+	// Graphs are skipped by successive calls to NextGraph. The byte order mark
+	// is skipped.
+	d := dot.NewDecoder(strings.NewReader("\xEF\xBB\xBFgraph A { a -- b; subgraph { c } } digraph B { d }"))
+	var ids []string
+	for {
+		graph, err := d.NextGraph()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, graph.ID)
+	}
+	if got, want := strings.Join(ids, ","), "A,B"; got != want {
+		t.Errorf("graph IDs mismatch; expected %q, got %q", want, got)
+	}
+}
+
+func TestDecoderError(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		{
+			in:   "digraph {\n\tA ~ B\n}",
+			want: `syntax error at line 2, column 7: unexpected invalid token "~"; expected statement or "}"`,
+		},
+		{
+			in:   "graph { a -- b",
+			want: `syntax error at line 1, column 15: unterminated graph opened at line 1; expected "}"`,
+		},
+		{
+			in:   "graph { node }",
+			want: `syntax error at line 1, column 14: missing attribute list of "node" statement; got "}"`,
+		},
+		{
+			in:   "graph { a [b c] }",
+			want: `syntax error at line 1, column 14: missing "=" in attribute b; got ID c`,
+		},
+		{
+			in:   "node { a }",
+			want: `syntax error at line 1, column 1: unexpected keyword "node"; expected graph`,
+		},
+		{
+			in:   "graph { a:b: }",
+			want: `syntax error at line 1, column 14: missing compass point after ":" of port b; got "}"`,
+		},
+	}
+	for _, g := range golden {
+		_, err := decode(dot.NewDecoder(strings.NewReader(g.in)))
+		if err == nil {
+			t.Errorf("%q: expected error, got nil", g.in)
+			continue
+		}
+		if got := err.Error(); got != g.want {
+			t.Errorf("%q: error mismatch; expected `%v`, got `%v`", g.in, g.want, got)
+		}
+	}
+}
+
+// decode decodes the DOT file of the given decoder into an AST.
+func decode(d *dot.Decoder) (*ast.File, error) {
+	file := &ast.File{}
+	for {
+		graph, err := d.NextGraph()
+		if err == io.EOF {
+			return file, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for {
+			stmt, err := d.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			graph.Stmts = append(graph.Stmts, stmt)
+		}
+		file.Graphs = append(file.Graphs, graph)
+	}
+}
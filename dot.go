@@ -2,12 +2,11 @@
 package dot
 
 import (
+	"context"
 	"io"
 	"io/ioutil"
 
 	"github.com/graphism/dot/ast"
-	"github.com/graphism/dot/internal/lexer"
-	"github.com/graphism/dot/internal/parser"
 	"github.com/pkg/errors"
 )
 
@@ -31,20 +30,50 @@ func Parse(r io.Reader) (*ast.File, error) {
 
 // ParseBytes parses the given Graphviz DOT file into an AST, reading from b.
 func ParseBytes(b []byte) (*ast.File, error) {
-	l := lexer.NewLexer(b)
-	p := parser.NewParser()
-	file, err := p.Parse(l)
+	return ParseBytesWithOptions(b, ParseOptions{})
+}
+
+// ParseBytesWithOptions parses the given Graphviz DOT file into an AST, reading
+// from b, using the given options. The DOT file is transcoded to UTF-8, which
+// is the encoding of IDs of the AST.
+func ParseBytesWithOptions(b []byte, opts ParseOptions) (*ast.File, error) {
+	return parseBytes(context.Background(), b, opts)
+}
+
+// ParseWithOptions parses the given Graphviz DOT file into an AST, reading from
+// r, using the given options. Parsing stops with the error of the context when
+// the context is cancelled.
+func ParseWithOptions(ctx context.Context, r io.Reader, opts ParseOptions) (*ast.File, error) {
+	buf, err := readAll(ctx, r, opts.MaxSize)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	f, ok := file.(*ast.File)
-	if !ok {
-		return nil, errors.Errorf("invalid file type; expected *ast.File, got %T", file)
+	return parseBytes(ctx, buf, opts)
+}
+
+// parseBytes parses the given Graphviz DOT file into an AST, reading from b,
+// using the given context and options.
+func parseBytes(ctx context.Context, b []byte, opts ParseOptions) (*ast.File, error) {
+	if opts.MaxSize > 0 && int64(len(b)) > opts.MaxSize {
+		return nil, errors.WithStack(&LimitError{Limit: "MaxSize", Max: opts.MaxSize})
 	}
-	if err := check(f); err != nil {
+	file, err := parseCharset(ctx, b, opts)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	switch opts.Check {
+	case CheckErrors:
+		err = check(ctx, file, opts.resolveLimits())
+	case CheckStrict:
+		err = checkStrict(ctx, file, opts.resolveLimits())
+	}
+	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	return f, nil
+	return file, nil
 }
 
 // ParseString parses the given Graphviz DOT file into an AST, reading from s.
@@ -0,0 +1,56 @@
+package dot_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/graphism/dot"
+)
+
+// FuzzParseBytes verifies that DOT files either fail to parse with an error or
+// are printed as DOT files which parse into the same AST; i.e.
+// Parse(Print(Parse(x))) == Parse(x).
+//
+// The corpus is seeded from the DOT files of internal/testdata, and of testdata
+// if the Graphviz corpus has been fetched (see testdata/Makefile).
+func FuzzParseBytes(f *testing.F) {
+	for _, pattern := range []string{"internal/testdata/*.dot", "testdata/*.dot"} {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			f.Fatal(err)
+		}
+		for _, path := range paths {
+			buf, err := ioutil.ReadFile(path)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(buf)
+		}
+	}
+	f.Fuzz(func(t *testing.T, in []byte) {
+		file, err := dot.ParseBytes(in)
+		if err != nil {
+			return
+		}
+		// Resolve and validate the graphs.
+		for _, graph := range file.Graphs {
+			if _, err := dot.Resolve(graph); err != nil {
+				t.Errorf("unable to resolve graph; %v", err)
+			}
+		}
+		dot.Check(file)
+		// Print the file in its charset.
+		out, err := dot.Encode(file, "")
+		if err != nil {
+			t.Fatalf("unable to encode file; %v", err)
+		}
+		file2, err := dot.ParseBytes(out)
+		if err != nil {
+			t.Fatalf("unable to parse printed file %q; %v", out, err)
+		}
+		if got, want := file2.String(), file.String(); got != want {
+			t.Fatalf("printed file mismatch; expected %q, got %q", want, got)
+		}
+	})
+}
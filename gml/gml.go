@@ -0,0 +1,496 @@
+// Package gml implements conversion between Graphviz DOT graphs and the Graph
+// Modelling Language (GML).
+//
+// The key-value pairs of GML graph, node and edge lists are mapped onto DOT
+// attributes. Nested lists are flattened, with the keys of a nested list joined
+// by a dot; e.g.
+//
+//    graphics [ x 1.0 y 2.0 ]
+//
+// is mapped onto the attributes
+//
+//    "graphics.x"=1.0 "graphics.y"=2.0
+//
+// Nested lists sharing the same key are distinguished by an index in brackets;
+// e.g. "point[0].x" and "point[1].x".
+//
+// GML node IDs are integers. Nodes of DOT graphs with non-integer node IDs are
+// assigned unused integer IDs when encoded, and their names are stored as node
+// labels unless already labelled. Subgraphs have no GML counterpart; only their
+// nodes and edges are encoded.
+//
+// ref: https://web.archive.org/web/20190207140002/http://www.fim.uni-passau.de/index.php?id=17297&L=1
+package gml
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/internal/enc"
+	"github.com/pkg/errors"
+)
+
+// === [ Encoding ] ============================================================
+
+// Marshal returns the GML encoding of the given graph.
+func Marshal(graph *ast.Graph) ([]byte, error) {
+	g, err := dot.Resolve(graph)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	root := list{}
+	if g.Directed {
+		root = append(root, &pair{key: "directed", val: &value{num: "1"}})
+	}
+	if len(g.ID) > 0 {
+		root = append(root, &pair{key: "name", val: &value{str: enc.Unquote(g.ID), isStr: true}})
+	}
+	root = appendAttrs(root, g.Attrs)
+
+	// Assign integer IDs to nodes.
+	ids := make(map[*dot.Node]int64)
+	used := make(map[int64]bool)
+	for _, n := range g.Nodes {
+		if id, err := strconv.ParseInt(enc.Unquote(n.ID), 10, 64); err == nil && !used[id] {
+			ids[n] = id
+			used[id] = true
+		}
+	}
+	next := int64(0)
+	for _, n := range g.Nodes {
+		if _, ok := ids[n]; ok {
+			continue
+		}
+		for used[next] {
+			next++
+		}
+		ids[n] = next
+		used[next] = true
+	}
+	for _, n := range g.Nodes {
+		node := list{{key: "id", val: &value{num: strconv.FormatInt(ids[n], 10)}}}
+		name := enc.Unquote(n.ID)
+		if _, ok := n.Attrs.Get("label"); !ok && name != strconv.FormatInt(ids[n], 10) {
+			node = append(node, &pair{key: "label", val: &value{str: name, isStr: true}})
+		}
+		node = appendAttrs(node, n.Attrs)
+		root = append(root, &pair{key: "node", val: &value{list: node}})
+	}
+	for _, e := range g.Edges {
+		edge := list{
+			{key: "source", val: &value{num: strconv.FormatInt(ids[e.From], 10)}},
+			{key: "target", val: &value{num: strconv.FormatInt(ids[e.To], 10)}},
+		}
+		attrs := e.Attrs
+		// Ports are stored as tailport and headport attributes.
+		if e.FromPort != nil {
+			attrs = append(attrs[:len(attrs):len(attrs)], &ast.Attr{Key: "tailport", Val: strings.TrimPrefix(e.FromPort.String(), ":")})
+		}
+		if e.ToPort != nil {
+			attrs = append(attrs[:len(attrs):len(attrs)], &ast.Attr{Key: "headport", Val: strings.TrimPrefix(e.ToPort.String(), ":")})
+		}
+		edge = appendAttrs(edge, attrs)
+		root = append(root, &pair{key: "edge", val: &value{list: edge}})
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteString("graph [\n")
+	root.write(buf, "\t")
+	buf.WriteString("]\n")
+	return buf.Bytes(), nil
+}
+
+// appendAttrs appends the given attributes to the GML list. Attributes with
+// dot-separated keys are appended to nested lists.
+func appendAttrs(l list, attrs []*ast.Attr) list {
+	for _, attr := range attrs {
+		path := strings.Split(enc.Unquote(attr.Key), ".")
+		val := &value{str: enc.Unquote(attr.Val), isStr: true}
+		if isNumber(val.str) {
+			val = &value{num: val.str}
+		}
+		l = l.insert(path, val)
+	}
+	return l
+}
+
+// insert inserts the value at the given key path of the list. A key of the
+// path may be suffixed by an index in brackets, to distinguish between nested
+// lists of the same key; e.g. "point[1]". Without index, the value is inserted
+// into the first nested list of the key.
+func (l list) insert(path []string, val *value) list {
+	key, index := splitIndex(path[0])
+	if len(path) == 1 {
+		return append(l, &pair{key: key, val: val})
+	}
+	// Locate nested list.
+	i := 0
+	for _, p := range l {
+		if p.key != key || p.val.list == nil {
+			continue
+		}
+		if index == -1 || i == index {
+			p.val.list = p.val.list.insert(path[1:], val)
+			return l
+		}
+		i++
+	}
+	return append(l, &pair{key: key, val: &value{list: list{}.insert(path[1:], val)}})
+}
+
+// splitIndex splits the given key into key and index; or -1 if the key has no
+// index.
+func splitIndex(key string) (string, int) {
+	if !strings.HasSuffix(key, "]") {
+		return key, -1
+	}
+	pos := strings.LastIndex(key, "[")
+	if pos == -1 {
+		return key, -1
+	}
+	index, err := strconv.Atoi(key[pos+1 : len(key)-1])
+	if err != nil || index < 0 {
+		return key, -1
+	}
+	return key[:pos], index
+}
+
+// write writes the list to buf, with the given indentation.
+func (l list) write(buf *bytes.Buffer, indent string) {
+	for _, p := range l {
+		switch {
+		case p.val.list != nil:
+			fmt.Fprintf(buf, "%s%s [\n", indent, p.key)
+			p.val.list.write(buf, indent+"\t")
+			fmt.Fprintf(buf, "%s]\n", indent)
+		case p.val.isStr:
+			fmt.Fprintf(buf, "%s%s \"%s\"\n", indent, p.key, escape(p.val.str))
+		default:
+			fmt.Fprintf(buf, "%s%s %s\n", indent, p.key, p.val.num)
+		}
+	}
+}
+
+// escape escapes the given string using character entities, as GML strings
+// may not contain double quotes.
+func escape(s string) string {
+	s = strings.Replace(s, "&", "&amp;", -1)
+	return strings.Replace(s, `"`, "&quot;", -1)
+}
+
+// === [ Decoding ] ============================================================
+
+// Unmarshal parses the GML-encoded data into a graph.
+func Unmarshal(data []byte) (*ast.Graph, error) {
+	p := &parser{s: string(data), line: 1}
+	top, err := p.parseList(false)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var root list
+	for _, pair := range top {
+		if pair.key == "graph" && pair.val.list != nil {
+			if root != nil {
+				return nil, errors.New("invalid GML input; multiple graphs not supported")
+			}
+			root = pair.val.list
+		}
+	}
+	if root == nil {
+		return nil, errors.New("invalid GML input; no graph found")
+	}
+	graph := &ast.Graph{}
+	var rest list
+	var stmts []ast.Stmt
+	for _, pair := range root {
+		switch pair.key {
+		case "directed":
+			graph.Directed = pair.val.num == "1"
+		case "node":
+			if pair.val.list == nil {
+				return nil, errors.Errorf("invalid node; expected list, got %v", pair.val)
+			}
+			stmt, err := decodeNode(pair.val.list)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			stmts = append(stmts, stmt)
+		case "edge":
+			if pair.val.list == nil {
+				return nil, errors.Errorf("invalid edge; expected list, got %v", pair.val)
+			}
+			stmt, err := decodeEdge(pair.val.list)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			stmts = append(stmts, stmt)
+		case "name":
+			if pair.val.isStr && len(pair.val.str) > 0 {
+				graph.ID = enc.Quote(pair.val.str)
+				continue
+			}
+			rest = append(rest, pair)
+		default:
+			rest = append(rest, pair)
+		}
+	}
+	if attrs := rest.attrs(nil); len(attrs) > 0 {
+		graph.Stmts = append(graph.Stmts, &ast.AttrStmt{Kind: ast.KindGraph, Attrs: attrs})
+	}
+	for _, stmt := range stmts {
+		if e, ok := stmt.(*ast.EdgeStmt); ok {
+			e.To.Directed = graph.Directed
+		}
+		graph.Stmts = append(graph.Stmts, stmt)
+	}
+	return graph, nil
+}
+
+// decodeNode decodes the given GML node list into a DOT node statement.
+func decodeNode(l list) (*ast.NodeStmt, error) {
+	stmt := &ast.NodeStmt{}
+	var rest list
+	for _, pair := range l {
+		if pair.key == "id" {
+			if pair.val.list != nil {
+				return nil, errors.New("invalid node ID; expected integer, got list")
+			}
+			stmt.Node = &ast.Node{ID: pair.val.id()}
+			continue
+		}
+		rest = append(rest, pair)
+	}
+	stmt.Attrs = rest.attrs(nil)
+	if stmt.Node == nil {
+		return nil, errors.New("invalid node; missing id")
+	}
+	return stmt, nil
+}
+
+// decodeEdge decodes the given GML edge list into a DOT edge statement.
+func decodeEdge(l list) (*ast.EdgeStmt, error) {
+	var from, to *ast.Node
+	var rest list
+	for _, pair := range l {
+		switch pair.key {
+		case "source", "target":
+			if pair.val.list != nil {
+				return nil, errors.Errorf("invalid edge %s; expected integer, got list", pair.key)
+			}
+			n := &ast.Node{ID: pair.val.id()}
+			if pair.key == "source" {
+				from = n
+			} else {
+				to = n
+			}
+		default:
+			rest = append(rest, pair)
+		}
+	}
+	if from == nil || to == nil {
+		return nil, errors.New("invalid edge; missing source or target")
+	}
+	return &ast.EdgeStmt{From: from, To: &ast.Edge{Vertex: to}, Attrs: rest.attrs(nil)}, nil
+}
+
+// === [ GML lists ] ===========================================================
+
+// A list is a GML list of key-value pairs.
+type list []*pair
+
+// A pair is a GML key-value pair.
+type pair struct {
+	// Key.
+	key string
+	// Value.
+	val *value
+}
+
+// A value is a GML value; either a number, a string or a list.
+type value struct {
+	// Number.
+	num string
+	// String; valid if isStr is set.
+	str string
+	// String value.
+	isStr bool
+	// List; or nil if not a list.
+	list list
+}
+
+// String returns the string representation of the value.
+func (v *value) String() string {
+	switch {
+	case v.list != nil:
+		return "[...]"
+	case v.isStr:
+		return strconv.Quote(v.str)
+	default:
+		return v.num
+	}
+}
+
+// id returns the value as a DOT identifier.
+func (v *value) id() string {
+	if v.isStr {
+		return enc.Quote(v.str)
+	}
+	return enc.Quote(v.num)
+}
+
+// attrs returns the DOT attributes of the key-value pairs of the list. The keys
+// of nested lists are prefixed by the given keys, separated by dots. Nested
+// lists sharing the same key are distinguished by an index in brackets.
+func (l list) attrs(prefix []string) []*ast.Attr {
+	count := make(map[string]int)
+	for _, p := range l {
+		if p.val.list != nil {
+			count[p.key]++
+		}
+	}
+	seen := make(map[string]int)
+	var attrs []*ast.Attr
+	for _, p := range l {
+		path := append(prefix[:len(prefix):len(prefix)], p.key)
+		if p.val.list == nil {
+			attrs = append(attrs, &ast.Attr{Key: enc.Quote(strings.Join(path, ".")), Val: p.val.id()})
+			continue
+		}
+		if count[p.key] > 1 {
+			path[len(path)-1] = fmt.Sprintf("%s[%d]", p.key, seen[p.key])
+			seen[p.key]++
+		}
+		attrs = append(attrs, p.val.list.attrs(path)...)
+	}
+	return attrs
+}
+
+// A parser parses GML input.
+type parser struct {
+	// Input.
+	s string
+	// Current offset.
+	pos int
+	// Current line number.
+	line int
+}
+
+// parseList parses a list of key-value pairs; terminated by ']' if nested, or
+// by the end of input otherwise.
+func (p *parser) parseList(nested bool) (list, error) {
+	var l list
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			if nested {
+				return nil, errors.Errorf("line %d: unterminated list", p.line)
+			}
+			return l, nil
+		}
+		if p.s[p.pos] == ']' {
+			if !nested {
+				return nil, errors.Errorf("line %d: unexpected ']'", p.line)
+			}
+			p.pos++
+			return l, nil
+		}
+		key := p.scan(func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' })
+		if len(key) == 0 {
+			return nil, errors.Errorf("line %d: invalid key; unexpected %q", p.line, p.s[p.pos])
+		}
+		val, err := p.parseValue()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		l = append(l, &pair{key: key, val: val})
+	}
+}
+
+// parseValue parses a number, string or list value.
+func (p *parser) parseValue() (*value, error) {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return nil, errors.Errorf("line %d: missing value", p.line)
+	}
+	switch c := p.s[p.pos]; {
+	case c == '[':
+		p.pos++
+		l, err := p.parseList(true)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if l == nil {
+			l = list{}
+		}
+		return &value{list: l}, nil
+	case c == '"':
+		end := strings.IndexByte(p.s[p.pos+1:], '"')
+		if end == -1 {
+			return nil, errors.Errorf("line %d: unterminated string", p.line)
+		}
+		s := p.s[p.pos+1 : p.pos+1+end]
+		p.line += strings.Count(s, "\n")
+		p.pos += end + 2
+		str := html.UnescapeString(s)
+		if !enc.IsText(str) {
+			return nil, errors.Errorf("line %d: invalid string %q; contains NUL, U+FFFD or invalid UTF-8", p.line, str)
+		}
+		return &value{str: str, isStr: true}, nil
+	default:
+		num := p.scan(func(r rune) bool { return !unicode.IsSpace(r) && r != '[' && r != ']' })
+		if !isNumber(num) {
+			return nil, errors.Errorf("line %d: invalid value %q", p.line, num)
+		}
+		return &value{num: num}, nil
+	}
+}
+
+// skipSpace skips whitespace and comments; i.e. lines beginning with '#'.
+func (p *parser) skipSpace() {
+	for p.pos < len(p.s) {
+		switch c := p.s[p.pos]; {
+		case c == '\n':
+			p.line++
+			p.pos++
+		case c == '#' && (p.pos == 0 || p.s[p.pos-1] == '\n'):
+			end := strings.IndexByte(p.s[p.pos:], '\n')
+			if end == -1 {
+				p.pos = len(p.s)
+			} else {
+				p.pos += end
+			}
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// scan scans a sequence of runes satisfying f.
+func (p *parser) scan(f func(r rune) bool) string {
+	start := p.pos
+	for p.pos < len(p.s) {
+		r, size := utf8.DecodeRuneInString(p.s[p.pos:])
+		if !f(r) {
+			break
+		}
+		p.pos += size
+	}
+	return p.s[start:p.pos]
+}
+
+// isNumber reports whether s is a GML integer or real number.
+func isNumber(s string) bool {
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return true
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil && !strings.ContainsAny(s, "xXpPnN_")
+}
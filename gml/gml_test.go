@@ -0,0 +1,109 @@
+package gml_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/gml"
+)
+
+func TestMarshal(t *testing.T) {
+	golden := []struct {
+		in  string
+		out string
+	}{
+		{
+			in:  "../internal/testdata/attr_stmt.dot",
+			out: "testdata/attr_stmt.gml",
+		},
+		{
+			in:  "testdata/graph.golden",
+			out: "testdata/graph.golden.gml",
+		},
+	}
+	for _, g := range golden {
+		file, err := dot.ParseFile(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", g.in, err)
+			continue
+		}
+		buf, err := gml.Marshal(file.Graphs[0])
+		if err != nil {
+			t.Errorf("%q: unable to encode graph; %v", g.in, err)
+			continue
+		}
+		want, err := ioutil.ReadFile(g.out)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", g.in, err)
+			continue
+		}
+		if !bytes.Equal(buf, want) {
+			t.Errorf("%q: GML mismatch; expected `%s`, got `%s`", g.in, want, buf)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	golden := []struct {
+		in  string
+		out string
+	}{
+		{
+			in:  "testdata/graph.gml",
+			out: "testdata/graph.golden",
+		},
+		{
+			in:  "testdata/graph.golden.gml",
+			out: "testdata/graph.golden",
+		},
+	}
+	for _, g := range golden {
+		buf, err := ioutil.ReadFile(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", g.in, err)
+			continue
+		}
+		graph, err := gml.Unmarshal(buf)
+		if err != nil {
+			t.Errorf("%q: unable to decode graph; %v", g.in, err)
+			continue
+		}
+		want, err := ioutil.ReadFile(g.out)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", g.in, err)
+			continue
+		}
+		got := graph.String()
+		if got != string(bytes.TrimSpace(want)) {
+			t.Errorf("%q: graph mismatch; expected `%s`, got `%s`", g.in, want, got)
+		}
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		{in: "graph [ node [ id 1 ]", want: "line 1: unterminated list"},
+		{in: "graph [ node [ label \"x\" ] ]", want: "invalid node; missing id"},
+		{in: "graph [\n\tlabel \"x ]", want: "line 2: unterminated string"},
+		{in: "graph [ edge [ source 1 ] ]", want: "invalid edge; missing source or target"},
+		{in: "graph [ x y ]", want: `line 1: invalid value "y"`},
+		// Invalid UTF-8.
+		{in: "0\x80", want: `line 1: invalid value "\x80"`},
+		{in: "graph [ label \"\x82\" ]", want: `line 1: invalid string "\x82"; contains NUL, U+FFFD or invalid UTF-8`},
+	}
+	for _, g := range golden {
+		_, err := gml.Unmarshal([]byte(g.in))
+		if err == nil {
+			t.Errorf("%q: expected error, got nil", g.in)
+			continue
+		}
+		if got := err.Error(); got != g.want {
+			t.Errorf("%q: error mismatch; expected `%v`, got `%v`", g.in, g.want, got)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+graph [
+	directed 1
+	bgcolor "transparent"
+	node [
+		id 0
+		label "A"
+		style "filled"
+		fillcolor "white"
+	]
+	node [
+		id 1
+		label "B"
+		style "filled"
+		fillcolor "white"
+	]
+	edge [
+		source 0
+		target 1
+		minlen 2
+	]
+]
@@ -0,0 +1,26 @@
+Creator "yFiles"
+Version 2.2
+# A comment line.
+graph [
+	directed 1
+	label "Hello, &quot;world&quot;"
+	node [
+		id 1
+		label "x"
+		graphics [ x 10.0 y -2.5e1 type "rectangle" ]
+	]
+	node [ id 2 label "y" ]
+	node [ id 3 ]
+	edge [
+		source 1
+		target 2
+		label "e"
+		graphics [
+			Line [
+				point [ x 1.0 y 2.0 ]
+				point [ x 3.0 y 4.0 ]
+			]
+		]
+	]
+	edge [ source 2 target 3 weight 3 ]
+]
@@ -0,0 +1,606 @@
+package dot
+
+import (
+	"context"
+	"strings"
+
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/internal/enc"
+	"github.com/graphism/dot/record"
+	"github.com/pkg/errors"
+)
+
+// === [ Graphs ] ==============================================================
+
+// A Graph represents a resolved Graphviz DOT graph. Node statements and edge
+// endpoints are resolved into unique nodes, edge statements are expanded into
+// individual edges between pairs of nodes, and default attributes are applied
+// to the nodes and edges created in their scope.
+type Graph struct {
+	// Strict graph; multi-edges forbidden.
+	Strict bool
+	// Directed graph.
+	Directed bool
+	// Graph ID; or empty if anonymous.
+	ID string
+	// Graph attributes.
+	Attrs Attrs
+	// Nodes of the graph, in order of creation.
+	Nodes []*Node
+	// Edges of the graph, in order of creation.
+	Edges []*Edge
+	// Subgraphs of the graph.
+	Subgraphs []*Subgraph
+
+	// nodes maps from unquoted node ID to node.
+	nodes map[string]*Node
+	// edges maps from source and destination node to edge; only used in strict
+	// graphs.
+	edges map[[2]*Node]*Edge
+	// srcs maps from attributes to the attributes of the AST from which they
+	// were set; or nil if not tracked.
+	srcs map[*ast.Attr]*ast.Attr
+	// Context of resolution, polled for cancellation; or nil once resolved.
+	ctx context.Context
+	// Resource limits of resolution.
+	limits resolveLimits
+	// Number of nodes and edges resolved, counting the nodes and edges of each
+	// subgraph.
+	numNodes, numEdges int
+}
+
+// resolveLimits specifies the resource limits of resolving a graph; 0 for no
+// limit.
+type resolveLimits struct {
+	// Maximum number of nodes, counting the nodes of each subgraph.
+	maxNodes int
+	// Maximum number of edges, counting the edges of each subgraph.
+	maxEdges int
+}
+
+// Resolve resolves the given graph.
+func Resolve(graph *ast.Graph) (*Graph, error) {
+	return resolve(context.Background(), graph, false, resolveLimits{})
+}
+
+// resolve resolves the given graph, tracking the attributes of the AST from
+// which the attributes of the graph are set if trackSrcs is set. Resolution
+// stops with the error of the context when the context is cancelled, and with
+// a LimitError when exceeding the given limits.
+func resolve(ctx context.Context, graph *ast.Graph, trackSrcs bool, limits resolveLimits) (*Graph, error) {
+	g := &Graph{
+		Strict:   graph.Strict,
+		Directed: graph.Directed,
+		ID:       graph.ID,
+		nodes:    make(map[string]*Node),
+		edges:    make(map[[2]*Node]*Edge),
+		ctx:      ctx,
+		limits:   limits,
+	}
+	if trackSrcs {
+		g.srcs = make(map[*ast.Attr]*ast.Attr)
+	}
+	s := &scope{}
+	for _, stmt := range graph.Stmts {
+		if err := g.resolveStmt(s, stmt); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	g.ctx = nil
+	return g, nil
+}
+
+// count counts a node or edge resolved into the graph or a subgraph, as tracked
+// by n, and returns an error if the given limit is exceeded or the context is
+// cancelled. The context is polled at intervals of resolved nodes and edges.
+func (g *Graph) count(n *int, max int, limit string) error {
+	*n++
+	if max > 0 && *n > max {
+		return errors.WithStack(&LimitError{Limit: limit, Max: int64(max)})
+	}
+	if (g.numNodes+g.numEdges)%pollInterval == 0 {
+		if err := g.ctx.Err(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// Node returns the node with the given node ID, and a boolean value indicating
+// if such a node exists. Quoted and unquoted node IDs are considered equal.
+func (g *Graph) Node(id string) (*Node, bool) {
+	n, ok := g.nodes[enc.Unquote(id)]
+	return n, ok
+}
+
+// AST returns the abstract syntax tree of the resolved graph. Each node is
+// declared with its attributes before any subgraph, and each edge is placed in
+// the innermost subgraph containing it. Default attributes are not used, as
+// attributes are explicitly specified on every node and edge.
+func (g *Graph) AST() *ast.Graph {
+	graph := &ast.Graph{
+		Strict:   g.Strict,
+		Directed: g.Directed,
+		ID:       g.ID,
+	}
+	if len(g.Attrs) > 0 {
+		graph.Stmts = append(graph.Stmts, &ast.AttrStmt{Kind: ast.KindGraph, Attrs: g.Attrs.clone()})
+	}
+	for _, n := range g.Nodes {
+		graph.Stmts = append(graph.Stmts, &ast.NodeStmt{Node: &ast.Node{ID: n.ID}, Attrs: n.Attrs.clone()})
+	}
+	// Edges already placed in a subgraph.
+	done := make(map[*Edge]bool)
+	for _, sub := range g.Subgraphs {
+		graph.Stmts = append(graph.Stmts, g.subgraphAST(sub, done))
+	}
+	for _, e := range g.Edges {
+		if !done[e] {
+			graph.Stmts = append(graph.Stmts, g.edgeAST(e))
+		}
+	}
+	return graph
+}
+
+// subgraphAST returns the abstract syntax tree of the given subgraph. The done
+// map keeps track of edges already placed in a subgraph.
+func (g *Graph) subgraphAST(sub *Subgraph, done map[*Edge]bool) *ast.Subgraph {
+	s := &ast.Subgraph{ID: sub.ID}
+	if len(sub.Attrs) > 0 {
+		s.Stmts = append(s.Stmts, &ast.AttrStmt{Kind: ast.KindGraph, Attrs: sub.Attrs.clone()})
+	}
+	// Nodes of nested subgraphs are declared in the nested subgraphs.
+	nested := make(map[*Node]bool)
+	for _, child := range sub.Subgraphs {
+		for n := range child.nodes {
+			nested[n] = true
+		}
+	}
+	for _, n := range sub.Nodes {
+		if !nested[n] {
+			s.Stmts = append(s.Stmts, &ast.NodeStmt{Node: &ast.Node{ID: n.ID}})
+		}
+	}
+	for _, child := range sub.Subgraphs {
+		s.Stmts = append(s.Stmts, g.subgraphAST(child, done))
+	}
+	for _, e := range sub.Edges {
+		if !done[e] {
+			s.Stmts = append(s.Stmts, g.edgeAST(e))
+			done[e] = true
+		}
+	}
+	return s
+}
+
+// edgeAST returns the abstract syntax tree of the given edge.
+func (g *Graph) edgeAST(e *Edge) *ast.EdgeStmt {
+	return &ast.EdgeStmt{
+		From: &ast.Node{ID: e.From.ID, Port: e.FromPort},
+		To: &ast.Edge{
+			Directed: g.Directed,
+			Vertex:   &ast.Node{ID: e.To.ID, Port: e.ToPort},
+		},
+		Attrs: e.Attrs.clone(),
+	}
+}
+
+// --- [ Subgraphs ] -----------------------------------------------------------
+
+// A Subgraph represents a resolved subgraph.
+type Subgraph struct {
+	// Subgraph ID; or empty if anonymous.
+	ID string
+	// Subgraph attributes.
+	Attrs Attrs
+	// Nodes of the subgraph, including the nodes of nested subgraphs.
+	Nodes []*Node
+	// Edges of the subgraph, including the edges of nested subgraphs.
+	Edges []*Edge
+	// Nested subgraphs.
+	Subgraphs []*Subgraph
+
+	// nodes tracks the nodes of the subgraph.
+	nodes map[*Node]bool
+	// edges tracks the edges of the subgraph.
+	edges map[*Edge]bool
+	// scope of the subgraph, as used when reopened.
+	scope *scope
+}
+
+// addNode adds the node to the subgraph, unless already present.
+func (sub *Subgraph) addNode(n *Node) {
+	if sub.nodes[n] {
+		return
+	}
+	sub.nodes[n] = true
+	sub.Nodes = append(sub.Nodes, n)
+}
+
+// addEdge adds the edge to the subgraph, unless already present.
+func (sub *Subgraph) addEdge(e *Edge) {
+	if sub.edges[e] {
+		return
+	}
+	sub.edges[e] = true
+	sub.Edges = append(sub.Edges, e)
+}
+
+// === [ Nodes ] ===============================================================
+
+// A Node represents a resolved node.
+type Node struct {
+	// Node ID, as specified at the first occurrence of the node.
+	ID string
+	// Node attributes, including default attributes in scope at the creation of
+	// the node.
+	Attrs Attrs
+}
+
+// Record returns the record label of the node; or nil if the node is not of
+// record shape or has an HTML-like label. The label defaults to \N.
+func (n *Node) Record() (*record.Label, error) {
+	shape, _ := n.Attrs.Get("shape")
+	switch strings.ToLower(enc.Unquote(shape)) {
+	case "record", "mrecord":
+	default:
+		return nil, nil
+	}
+	label, ok := n.Attrs.Get("label")
+	if !ok {
+		label = `\N`
+	}
+	if enc.IsHTML(label) {
+		return nil, nil
+	}
+	l, err := record.Parse(label)
+	if err != nil {
+		return nil, errors.Errorf("invalid record label %s of node %q; %v", label, n.ID, err)
+	}
+	return l, nil
+}
+
+// === [ Edges ] ===============================================================
+
+// An Edge represents a resolved edge between two nodes.
+type Edge struct {
+	// Source node.
+	From *Node
+	// Source node port; or nil if none.
+	FromPort *ast.Port
+	// Destination node.
+	To *Node
+	// Destination node port; or nil if none.
+	ToPort *ast.Port
+	// Edge attributes, including default attributes in scope at the creation of
+	// the edge.
+	Attrs Attrs
+}
+
+// === [ Attributes ] ==========================================================
+
+// Attrs is an ordered list of attributes. Quoted and unquoted attribute keys
+// are considered equal.
+type Attrs []*ast.Attr
+
+// Get returns the value of the attribute with the given key, and a boolean
+// value indicating if such an attribute exists.
+func (as Attrs) Get(key string) (string, bool) {
+	key = enc.Unquote(key)
+	for _, a := range as {
+		if enc.Unquote(a.Key) == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// Set sets the value of the attribute with the given key, replacing the
+// existing value if present.
+func (as *Attrs) Set(key, val string) {
+	as.set(key, val)
+}
+
+// set sets the value of the attribute with the given key, replacing the
+// existing value if present, and returns the attribute set.
+func (as *Attrs) set(key, val string) *ast.Attr {
+	k := enc.Unquote(key)
+	for i, a := range *as {
+		if enc.Unquote(a.Key) == k {
+			(*as)[i] = &ast.Attr{Key: a.Key, Val: val}
+			return (*as)[i]
+		}
+	}
+	attr := &ast.Attr{Key: key, Val: val}
+	*as = append(*as, attr)
+	return attr
+}
+
+// Del deletes the attribute with the given key, if present.
+func (as *Attrs) Del(key string) {
+	k := enc.Unquote(key)
+	for i, a := range *as {
+		if enc.Unquote(a.Key) == k {
+			*as = append((*as)[:i:i], (*as)[i+1:]...)
+			return
+		}
+	}
+}
+
+// clone returns a deep copy of the attribute list.
+func (as Attrs) clone() Attrs {
+	if len(as) == 0 {
+		return nil
+	}
+	c := make(Attrs, len(as))
+	for i, a := range as {
+		c[i] = &ast.Attr{Key: a.Key, Val: a.Val}
+	}
+	return c
+}
+
+// === [ Resolution ] ==========================================================
+
+// setAttr sets the given attribute of the AST in the attribute list.
+func (g *Graph) setAttr(as *Attrs, attr *ast.Attr) {
+	a := as.set(attr.Key, attr.Val)
+	if g.srcs != nil {
+		g.srcs[a] = attr
+	}
+}
+
+// cloneAttrs returns a deep copy of the attribute list, as inherited from
+// default attributes.
+func (g *Graph) cloneAttrs(as Attrs) Attrs {
+	c := as.clone()
+	if g.srcs != nil {
+		for i, a := range as {
+			if src, ok := g.srcs[a]; ok {
+				g.srcs[c[i]] = src
+			}
+		}
+	}
+	return c
+}
+
+// src returns the attribute of the AST from which the given attribute of the
+// graph was set; or the attribute itself if not tracked.
+func (g *Graph) src(attr *ast.Attr) *ast.Attr {
+	if src, ok := g.srcs[attr]; ok {
+		return src
+	}
+	return attr
+}
+
+// A scope tracks the default attributes of a graph or subgraph.
+type scope struct {
+	// Parent scope; or nil if root graph.
+	parent *scope
+	// Subgraph of the scope; or nil if root graph.
+	sub *Subgraph
+	// Default node attributes.
+	nodeAttrs Attrs
+	// Default edge attributes.
+	edgeAttrs Attrs
+}
+
+// resolveStmt resolves the given statement in scope s.
+func (g *Graph) resolveStmt(s *scope, stmt ast.Stmt) error {
+	switch stmt := stmt.(type) {
+	case *ast.NodeStmt:
+		n, err := g.node(s, stmt.Node.ID)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		for _, attr := range stmt.Attrs {
+			g.setAttr(&n.Attrs, attr)
+		}
+		return nil
+	case *ast.EdgeStmt:
+		return g.resolveEdgeStmt(s, stmt)
+	case *ast.AttrStmt:
+		for _, attr := range stmt.Attrs {
+			switch stmt.Kind {
+			case ast.KindGraph:
+				g.setAttr(g.graphAttrs(s), attr)
+			case ast.KindNode:
+				g.setAttr(&s.nodeAttrs, attr)
+			case ast.KindEdge:
+				g.setAttr(&s.edgeAttrs, attr)
+			default:
+				return errors.Errorf("support for graph component kind %d not yet implemented", uint(stmt.Kind))
+			}
+		}
+		return nil
+	case *ast.Attr:
+		g.setAttr(g.graphAttrs(s), stmt)
+		return nil
+	case *ast.Subgraph:
+		_, err := g.resolveSubgraph(s, stmt)
+		return err
+	default:
+		return errors.Errorf("support for statement of type %T not yet implemented", stmt)
+	}
+}
+
+// resolveEdgeStmt resolves the given edge statement in scope s.
+func (g *Graph) resolveEdgeStmt(s *scope, stmt *ast.EdgeStmt) error {
+	from, err := g.resolveVertex(s, stmt.From)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for to := stmt.To; to != nil; to = to.To {
+		dsts, err := g.resolveVertex(s, to.Vertex)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		for _, src := range from {
+			for _, dst := range dsts {
+				if err := g.edge(s, src, dst, stmt.Attrs); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+		}
+		from = dsts
+	}
+	return nil
+}
+
+// An endpoint is a node and optional port of an edge.
+type endpoint struct {
+	// Node.
+	node *Node
+	// Node port; or nil if none.
+	port *ast.Port
+}
+
+// resolveVertex resolves the given vertex in scope s, and returns the
+// endpoints it represents.
+func (g *Graph) resolveVertex(s *scope, vertex ast.Vertex) ([]endpoint, error) {
+	switch vertex := vertex.(type) {
+	case *ast.Node:
+		n, err := g.node(s, vertex.ID)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return []endpoint{{node: n, port: vertex.Port}}, nil
+	case *ast.Subgraph:
+		sub, err := g.resolveSubgraph(s, vertex)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		var ends []endpoint
+		for _, n := range sub.Nodes {
+			ends = append(ends, endpoint{node: n})
+		}
+		return ends, nil
+	default:
+		return nil, errors.Errorf("support for vertex of type %T not yet implemented", vertex)
+	}
+}
+
+// resolveSubgraph resolves the given subgraph in scope s.
+func (g *Graph) resolveSubgraph(s *scope, subgraph *ast.Subgraph) (*Subgraph, error) {
+	child := g.subgraph(s, subgraph.ID)
+	for _, stmt := range subgraph.Stmts {
+		if err := g.resolveStmt(child, stmt); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return child.sub, nil
+}
+
+// subgraph returns the scope of the subgraph with the given ID, creating the
+// subgraph in scope s if not yet present. As in Graphviz, a named subgraph of
+// scope s is reopened and extended by later subgraphs with the same ID in the
+// same scope, while anonymous subgraphs are always created.
+func (g *Graph) subgraph(s *scope, id string) *scope {
+	subs := &g.Subgraphs
+	if s.sub != nil {
+		subs = &s.sub.Subgraphs
+	}
+	if id != "" {
+		for _, sub := range *subs {
+			if enc.Unquote(sub.ID) == enc.Unquote(id) {
+				return sub.scope
+			}
+		}
+	}
+	sub := &Subgraph{
+		ID:    id,
+		nodes: make(map[*Node]bool),
+		edges: make(map[*Edge]bool),
+	}
+	*subs = append(*subs, sub)
+	// Default attributes are inherited from the enclosing scope.
+	sub.scope = &scope{
+		parent:    s,
+		sub:       sub,
+		nodeAttrs: g.cloneAttrs(s.nodeAttrs),
+		edgeAttrs: g.cloneAttrs(s.edgeAttrs),
+	}
+	return sub.scope
+}
+
+// graphAttrs returns the graph attributes of scope s.
+func (g *Graph) graphAttrs(s *scope) *Attrs {
+	if s.sub != nil {
+		return &s.sub.Attrs
+	}
+	return &g.Attrs
+}
+
+// node returns the node with the given ID, creating it in scope s if not yet
+// present. The node is added to the subgraphs of scope s and its ancestors.
+func (g *Graph) node(s *scope, id string) (*Node, error) {
+	n, ok := g.Node(id)
+	if !ok {
+		if err := g.count(&g.numNodes, g.limits.maxNodes, "MaxNodes"); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		n = &Node{ID: id, Attrs: g.cloneAttrs(s.nodeAttrs)}
+		g.nodes[enc.Unquote(id)] = n
+		g.Nodes = append(g.Nodes, n)
+	}
+	// The nodes of a subgraph are nodes of its ancestors; thus, the ancestors
+	// of a subgraph which contains the node are not revisited.
+	for ; s != nil && s.sub != nil && !s.sub.nodes[n]; s = s.parent {
+		if err := g.count(&g.numNodes, g.limits.maxNodes, "MaxNodes"); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		s.sub.addNode(n)
+	}
+	return n, nil
+}
+
+// edge creates a new edge in scope s between the given endpoints, with the
+// given attributes. In strict graphs, the attributes are merged into the
+// existing edge between the endpoints, if present. The edge is added to the
+// subgraphs of scope s and its ancestors.
+func (g *Graph) edge(s *scope, from, to endpoint, attrs []*ast.Attr) error {
+	// Edges merged into existing edges of strict graphs are counted, as they
+	// are resolved all the same.
+	if err := g.count(&g.numEdges, g.limits.maxEdges, "MaxEdges"); err != nil {
+		return errors.WithStack(err)
+	}
+	var e *Edge
+	if g.Strict {
+		e = g.findEdge(from.node, to.node)
+	}
+	if e == nil {
+		e = &Edge{
+			From:     from.node,
+			FromPort: from.port,
+			To:       to.node,
+			ToPort:   to.port,
+			Attrs:    g.cloneAttrs(s.edgeAttrs),
+		}
+		g.Edges = append(g.Edges, e)
+		if g.Strict {
+			g.edges[[2]*Node{from.node, to.node}] = e
+		}
+	}
+	for _, attr := range attrs {
+		g.setAttr(&e.Attrs, attr)
+	}
+	// The edges of a subgraph are edges of its ancestors.
+	for ; s != nil && s.sub != nil && !s.sub.edges[e]; s = s.parent {
+		if err := g.count(&g.numEdges, g.limits.maxEdges, "MaxEdges"); err != nil {
+			return errors.WithStack(err)
+		}
+		s.sub.addEdge(e)
+	}
+	return nil
+}
+
+// findEdge returns the edge between the given nodes of a strict graph; or nil
+// if not present. In undirected graphs, the direction of the edge is ignored.
+func (g *Graph) findEdge(from, to *Node) *Edge {
+	if e, ok := g.edges[[2]*Node{from, to}]; ok {
+		return e
+	}
+	if !g.Directed {
+		return g.edges[[2]*Node{to, from}]
+	}
+	return nil
+}
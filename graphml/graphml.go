@@ -0,0 +1,564 @@
+// Package graphml implements conversion between Graphviz DOT graphs and the
+// GraphML file format.
+//
+// DOT attributes are stored as GraphML data, with one key declared per
+// attribute name and graph component kind. Subgraphs are stored as nested
+// GraphML graphs of group nodes, and ports on edge endpoints are stored as
+// GraphML ports. As a node may only be contained within one GraphML graph, a
+// DOT node present in several sibling subgraphs is placed in the first of them.
+//
+// ref: http://graphml.graphdrawing.org/specification.html
+package graphml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/internal/astx"
+	"github.com/graphism/dot/internal/enc"
+	"github.com/pkg/errors"
+)
+
+// Namespace is the XML namespace of GraphML documents.
+const Namespace = "http://graphml.graphdrawing.org/xmlns"
+
+// === [ Encoding ] ============================================================
+
+// Marshal returns the GraphML encoding of the given graph.
+func Marshal(graph *ast.Graph) ([]byte, error) {
+	g, err := dot.Resolve(graph)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	e := newEncoder(g)
+	doc := &xmlGraphML{Xmlns: Namespace}
+	root := e.encodeGraph()
+	doc.Keys = e.keys
+	doc.Graphs = []*xmlGraph{root}
+	buf := new(bytes.Buffer)
+	buf.WriteString(xml.Header)
+	out, err := xml.MarshalIndent(doc, "", "\t")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	buf.Write(out)
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// An encoder keeps track of GraphML keys and element IDs.
+type encoder struct {
+	// Resolved graph.
+	g *dot.Graph
+	// GraphML keys, in order of declaration.
+	keys []*xmlKey
+	// keyIDs maps from graph component kind and attribute name to key ID.
+	keyIDs map[string]string
+	// nodeIDs maps from node to node ID.
+	nodeIDs map[*dot.Node]string
+	// ids tracks the node IDs in use.
+	ids map[string]bool
+	// ports maps from node to port names, in order of use.
+	ports map[*dot.Node][]string
+	// home maps from node to the subgraph in which the node is placed; or nil
+	// if placed in the root graph.
+	home map[*dot.Node]*dot.Subgraph
+}
+
+// newEncoder returns a new encoder for the given resolved graph.
+func newEncoder(g *dot.Graph) *encoder {
+	e := &encoder{
+		g:       g,
+		keyIDs:  make(map[string]string),
+		nodeIDs: make(map[*dot.Node]string),
+		ids:     make(map[string]bool),
+		ports:   make(map[*dot.Node][]string),
+		home:    make(map[*dot.Node]*dot.Subgraph),
+	}
+	for _, n := range g.Nodes {
+		id := enc.Unquote(n.ID)
+		e.nodeIDs[n] = id
+		e.ids[id] = true
+	}
+	// Place each node in the innermost subgraph containing it; visiting
+	// subgraphs in pre-order.
+	var walk func(subs []*dot.Subgraph)
+	walk = func(subs []*dot.Subgraph) {
+		for _, sub := range subs {
+			for _, n := range sub.Nodes {
+				if _, ok := e.home[n]; !ok && !nested(sub, n) {
+					e.home[n] = sub
+				}
+			}
+			walk(sub.Subgraphs)
+		}
+	}
+	walk(g.Subgraphs)
+	addPort := func(n *dot.Node, port *ast.Port) {
+		name := portName(port)
+		if len(name) == 0 {
+			return
+		}
+		for _, p := range e.ports[n] {
+			if p == name {
+				return
+			}
+		}
+		e.ports[n] = append(e.ports[n], name)
+	}
+	for _, edge := range g.Edges {
+		addPort(edge.From, edge.FromPort)
+		addPort(edge.To, edge.ToPort)
+	}
+	return e
+}
+
+// nested reports whether the node is contained within a nested subgraph of sub.
+func nested(sub *dot.Subgraph, n *dot.Node) bool {
+	for _, child := range sub.Subgraphs {
+		for _, m := range child.Nodes {
+			if m == n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// encodeGraph returns the GraphML graph of the root graph.
+func (e *encoder) encodeGraph() *xmlGraph {
+	graph := &xmlGraph{
+		ID:          enc.Unquote(e.g.ID),
+		EdgeDefault: "undirected",
+		Data:        e.encodeAttrs("graph", e.g.Attrs),
+	}
+	if e.g.Directed {
+		graph.EdgeDefault = "directed"
+	}
+	for _, n := range e.g.Nodes {
+		if e.home[n] == nil {
+			graph.Nodes = append(graph.Nodes, e.encodeNode(n))
+		}
+	}
+	for _, sub := range e.g.Subgraphs {
+		graph.Nodes = append(graph.Nodes, e.encodeSubgraph(sub, graph.EdgeDefault))
+	}
+	for i, edge := range e.g.Edges {
+		graph.Edges = append(graph.Edges, e.encodeEdge(i, edge))
+	}
+	return graph
+}
+
+// encodeSubgraph returns the GraphML group node of the given subgraph.
+func (e *encoder) encodeSubgraph(sub *dot.Subgraph, edgeDefault string) *xmlNode {
+	id := enc.Unquote(sub.ID)
+	if len(id) == 0 {
+		// Anonymous subgraphs are named "%N" by Graphviz.
+		id = "%0"
+	}
+	graph := &xmlGraph{
+		ID:          id,
+		EdgeDefault: edgeDefault,
+		Data:        e.encodeAttrs("graph", sub.Attrs),
+	}
+	for _, n := range sub.Nodes {
+		if e.home[n] == sub {
+			graph.Nodes = append(graph.Nodes, e.encodeNode(n))
+		}
+	}
+	for _, child := range sub.Subgraphs {
+		graph.Nodes = append(graph.Nodes, e.encodeSubgraph(child, edgeDefault))
+	}
+	return &xmlNode{ID: e.uniqueID(id), Graph: graph}
+}
+
+// encodeNode returns the GraphML node of the given node.
+func (e *encoder) encodeNode(n *dot.Node) *xmlNode {
+	node := &xmlNode{
+		ID:   e.nodeIDs[n],
+		Data: e.encodeAttrs("node", n.Attrs),
+	}
+	for _, name := range e.ports[n] {
+		node.Ports = append(node.Ports, &xmlPort{Name: name})
+	}
+	return node
+}
+
+// encodeEdge returns the GraphML edge of the given edge, with the given index.
+func (e *encoder) encodeEdge(index int, edge *dot.Edge) *xmlEdge {
+	x := &xmlEdge{
+		ID:     "e" + strconv.Itoa(index),
+		Source: e.nodeIDs[edge.From],
+		Target: e.nodeIDs[edge.To],
+		Data:   e.encodeAttrs("edge", edge.Attrs),
+	}
+	x.SourcePort = portName(edge.FromPort)
+	x.TargetPort = portName(edge.ToPort)
+	return x
+}
+
+// encodeAttrs returns the GraphML data of the given attributes, declaring keys
+// for the given graph component kind as needed.
+func (e *encoder) encodeAttrs(kind string, attrs []*ast.Attr) []*xmlData {
+	var data []*xmlData
+	for _, attr := range attrs {
+		name := enc.Unquote(attr.Key)
+		k := kind + "\x00" + name
+		id, ok := e.keyIDs[k]
+		if !ok {
+			id = "d" + strconv.Itoa(len(e.keys))
+			e.keyIDs[k] = id
+			e.keys = append(e.keys, &xmlKey{ID: id, For: kind, Name: name, Type: "string"})
+		}
+		data = append(data, &xmlData{Key: id, Value: enc.Unquote(attr.Val)})
+	}
+	return data
+}
+
+// uniqueID returns a node ID based on the given ID, which is not yet in use.
+func (e *encoder) uniqueID(id string) string {
+	unique := id
+	for i := 1; e.ids[unique]; i++ {
+		unique = fmt.Sprintf("%s::%d", id, i)
+	}
+	e.ids[unique] = true
+	return unique
+}
+
+// portName returns the GraphML port name of the given port; or an empty string
+// if port is nil or the default port.
+func portName(port *ast.Port) string {
+	if port == nil {
+		return ""
+	}
+	s := enc.Unquote(port.ID)
+	if port.CompassPoint != ast.CompassPointDefault {
+		if len(s) > 0 {
+			s += ":"
+		}
+		s += port.CompassPoint.String()
+	}
+	return s
+}
+
+// === [ Decoding ] ============================================================
+
+// Unmarshal parses the GraphML-encoded data into a graph. The GraphML document
+// must contain exactly one top-level graph.
+func Unmarshal(data []byte) (*ast.Graph, error) {
+	doc := &xmlGraphML{}
+	if err := xml.Unmarshal(data, doc); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(doc.Graphs) != 1 {
+		return nil, errors.Errorf("invalid number of GraphML graphs; expected 1, got %d", len(doc.Graphs))
+	}
+	d := &decoder{keys: make(map[string]*xmlKey)}
+	for _, key := range doc.Keys {
+		d.keys[key.ID] = key
+	}
+	root := doc.Graphs[0]
+	id, err := quoteID(root.ID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	graph := &ast.Graph{
+		Directed: root.EdgeDefault != "undirected",
+		ID:       id,
+	}
+	// Default values of keys are stored as default attributes.
+	for _, kind := range []ast.Kind{ast.KindGraph, ast.KindNode, ast.KindEdge} {
+		var attrs []*ast.Attr
+		for _, key := range doc.Keys {
+			if key.Default != nil && (key.For == kind.String() || key.For == "all") {
+				attr, err := newAttr(key.name(), *key.Default)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				attrs = append(attrs, attr)
+			}
+		}
+		if len(attrs) > 0 {
+			graph.Stmts = append(graph.Stmts, &ast.AttrStmt{Kind: kind, Attrs: attrs})
+		}
+	}
+	// Locate the group nodes used as edge endpoints, as these are declared as
+	// both nodes and subgraphs.
+	d.endpoints = make(map[string]bool)
+	d.findEndpoints(root)
+	stmts, err := d.decodeGraph(root)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	graph.Stmts = append(graph.Stmts, stmts...)
+	// Edges are placed in the root graph, after all nodes have been declared, to
+	// retain the subgraph membership of nodes.
+	var edges []ast.Stmt
+	if err := d.decodeEdges(root, graph.Directed, &edges); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	graph.Stmts = append(graph.Stmts, edges...)
+	return graph, nil
+}
+
+// A decoder keeps track of GraphML keys.
+type decoder struct {
+	// keys maps from key ID to key.
+	keys map[string]*xmlKey
+	// endpoints tracks the IDs of nodes used as edge endpoints.
+	endpoints map[string]bool
+}
+
+// findEndpoints records the edge endpoints of the given graph and its nested
+// graphs.
+func (d *decoder) findEndpoints(graph *xmlGraph) {
+	for _, edge := range graph.Edges {
+		d.endpoints[edge.Source] = true
+		d.endpoints[edge.Target] = true
+	}
+	for _, node := range graph.Nodes {
+		if node.Graph != nil {
+			d.findEndpoints(node.Graph)
+		}
+	}
+}
+
+// decodeGraph returns the attribute, node and subgraph statements of the given
+// GraphML graph.
+func (d *decoder) decodeGraph(graph *xmlGraph) ([]ast.Stmt, error) {
+	var stmts []ast.Stmt
+	attrs, err := d.decodeData(graph.Data)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(attrs) > 0 {
+		stmts = append(stmts, &ast.AttrStmt{Kind: ast.KindGraph, Attrs: attrs})
+	}
+	for _, node := range graph.Nodes {
+		attrs, err := d.decodeData(node.Data)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if node.Graph == nil || d.endpoints[node.ID] {
+			id, err := quoteID(node.ID)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			stmts = append(stmts, &ast.NodeStmt{Node: &ast.Node{ID: id}, Attrs: attrs})
+			attrs = nil
+		}
+		if node.Graph == nil {
+			continue
+		}
+		// Group node.
+		id := node.Graph.ID
+		if len(id) == 0 {
+			id = node.ID
+		}
+		if strings.HasPrefix(id, "%") {
+			// Anonymous subgraph.
+			id = ""
+		}
+		subID, err := quoteID(id)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		sub := &ast.Subgraph{ID: subID}
+		if len(attrs) > 0 {
+			sub.Stmts = append(sub.Stmts, &ast.AttrStmt{Kind: ast.KindGraph, Attrs: attrs})
+		}
+		subStmts, err := d.decodeGraph(node.Graph)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		sub.Stmts = append(sub.Stmts, subStmts...)
+		stmts = append(stmts, sub)
+	}
+	return stmts, nil
+}
+
+// decodeEdges appends the edge statements of the given GraphML graph and its
+// nested graphs to stmts.
+func (d *decoder) decodeEdges(graph *xmlGraph, directed bool, stmts *[]ast.Stmt) error {
+	for _, edge := range graph.Edges {
+		attrs, err := d.decodeData(edge.Data)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		// Edges of a direction other than the graph direction are drawn using
+		// the dir attribute.
+		if len(edge.Directed) > 0 {
+			dir, err := strconv.ParseBool(edge.Directed)
+			if err != nil {
+				return errors.Errorf("invalid directed attribute of edge %q; %v", edge.ID, err)
+			}
+			switch {
+			case directed && !dir:
+				attrs = append(attrs, &ast.Attr{Key: "dir", Val: "none"})
+			case !directed && dir:
+				attrs = append(attrs, &ast.Attr{Key: "dir", Val: "forward"})
+			}
+		}
+		source, err := quoteID(edge.Source)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		from := &ast.Node{ID: source}
+		if len(edge.SourcePort) > 0 {
+			if from.Port, err = parsePort(edge.SourcePort); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		target, err := quoteID(edge.Target)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		to := &ast.Node{ID: target}
+		if len(edge.TargetPort) > 0 {
+			if to.Port, err = parsePort(edge.TargetPort); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		e := &ast.EdgeStmt{
+			From:  from,
+			To:    &ast.Edge{Directed: directed, Vertex: to},
+			Attrs: attrs,
+		}
+		*stmts = append(*stmts, e)
+	}
+	for _, node := range graph.Nodes {
+		if node.Graph != nil {
+			if err := d.decodeEdges(node.Graph, directed, stmts); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	return nil
+}
+
+// decodeData returns the attributes of the given GraphML data.
+func (d *decoder) decodeData(data []*xmlData) ([]*ast.Attr, error) {
+	var attrs []*ast.Attr
+	for _, x := range data {
+		key, ok := d.keys[x.Key]
+		if !ok {
+			return nil, errors.Errorf("unable to locate GraphML key %q", x.Key)
+		}
+		val := x.Value
+		if key.Type != "" && key.Type != "string" {
+			val = strings.TrimSpace(val)
+		}
+		attr, err := newAttr(key.name(), val)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs, nil
+}
+
+// newAttr returns a DOT attribute of the given GraphML attribute name and
+// value.
+func newAttr(key, val string) (*ast.Attr, error) {
+	if !enc.IsText(key) {
+		return nil, errors.Errorf("invalid attribute %q; contains NUL, U+FFFD or invalid UTF-8", key)
+	}
+	if !enc.IsText(val) {
+		return nil, errors.Errorf("invalid %q value %q; contains NUL, U+FFFD or invalid UTF-8", key, val)
+	}
+	return &ast.Attr{Key: enc.Quote(key), Val: enc.Quote(val)}, nil
+}
+
+// parsePort parses the given GraphML port name into a DOT port.
+func parsePort(name string) (*ast.Port, error) {
+	if !enc.IsText(name) {
+		return nil, errors.Errorf("invalid port %q; contains NUL, U+FFFD or invalid UTF-8", name)
+	}
+	if pos := strings.LastIndex(name, ":"); pos != -1 {
+		return astx.NewPort(enc.Quote(name[:pos]), name[pos+1:])
+	}
+	return astx.NewPort(enc.Quote(name), nil)
+}
+
+// quoteID returns the given ID as a DOT identifier; or an empty string if id is
+// empty.
+func quoteID(id string) (string, error) {
+	if len(id) == 0 {
+		return "", nil
+	}
+	if !enc.IsText(id) {
+		return "", errors.Errorf("invalid ID %q; contains NUL, U+FFFD or invalid UTF-8", id)
+	}
+	return enc.Quote(id), nil
+}
+
+// === [ GraphML elements ] ====================================================
+
+// xmlGraphML is the root element of a GraphML document.
+type xmlGraphML struct {
+	XMLName xml.Name    `xml:"graphml"`
+	Xmlns   string      `xml:"xmlns,attr,omitempty"`
+	Keys    []*xmlKey   `xml:"key"`
+	Graphs  []*xmlGraph `xml:"graph"`
+}
+
+// xmlKey declares a GraphML attribute.
+type xmlKey struct {
+	ID      string  `xml:"id,attr"`
+	For     string  `xml:"for,attr,omitempty"`
+	Name    string  `xml:"attr.name,attr,omitempty"`
+	Type    string  `xml:"attr.type,attr,omitempty"`
+	Default *string `xml:"default"`
+}
+
+// name returns the attribute name of the key; or the key ID if unnamed.
+func (key *xmlKey) name() string {
+	if len(key.Name) > 0 {
+		return key.Name
+	}
+	return key.ID
+}
+
+// xmlGraph is a GraphML graph.
+type xmlGraph struct {
+	ID          string     `xml:"id,attr,omitempty"`
+	EdgeDefault string     `xml:"edgedefault,attr"`
+	Data        []*xmlData `xml:"data"`
+	Nodes       []*xmlNode `xml:"node"`
+	Edges       []*xmlEdge `xml:"edge"`
+}
+
+// xmlNode is a GraphML node; a group node if it contains a nested graph.
+type xmlNode struct {
+	ID    string     `xml:"id,attr"`
+	Data  []*xmlData `xml:"data"`
+	Ports []*xmlPort `xml:"port"`
+	Graph *xmlGraph  `xml:"graph"`
+}
+
+// xmlPort is a GraphML port.
+type xmlPort struct {
+	Name string `xml:"name,attr"`
+}
+
+// xmlEdge is a GraphML edge.
+type xmlEdge struct {
+	ID         string     `xml:"id,attr,omitempty"`
+	Directed   string     `xml:"directed,attr,omitempty"`
+	Source     string     `xml:"source,attr"`
+	Target     string     `xml:"target,attr"`
+	SourcePort string     `xml:"sourceport,attr,omitempty"`
+	TargetPort string     `xml:"targetport,attr,omitempty"`
+	Data       []*xmlData `xml:"data"`
+}
+
+// xmlData is the value of a GraphML attribute.
+type xmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
@@ -0,0 +1,144 @@
+package graphml_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/graphml"
+)
+
+func TestMarshal(t *testing.T) {
+	golden := []struct {
+		in  string
+		out string
+	}{
+		{
+			in:  "testdata/cluster.dot",
+			out: "testdata/cluster.graphml",
+		},
+	}
+	for _, g := range golden {
+		file, err := dot.ParseFile(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", g.in, err)
+			continue
+		}
+		buf, err := graphml.Marshal(file.Graphs[0])
+		if err != nil {
+			t.Errorf("%q: unable to encode graph; %v", g.in, err)
+			continue
+		}
+		want, err := ioutil.ReadFile(g.out)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", g.in, err)
+			continue
+		}
+		if !bytes.Equal(buf, want) {
+			t.Errorf("%q: GraphML mismatch; expected `%s`, got `%s`", g.in, want, buf)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	golden := []struct {
+		in  string
+		out string
+	}{
+		{
+			in:  "testdata/cluster.graphml",
+			out: "testdata/cluster.golden",
+		},
+		{
+			in:  "testdata/yed.graphml",
+			out: "testdata/yed.golden",
+		},
+	}
+	for _, g := range golden {
+		buf, err := ioutil.ReadFile(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", g.in, err)
+			continue
+		}
+		graph, err := graphml.Unmarshal(buf)
+		if err != nil {
+			t.Errorf("%q: unable to decode graph; %v", g.in, err)
+			continue
+		}
+		want, err := ioutil.ReadFile(g.out)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", g.in, err)
+			continue
+		}
+		got := graph.String()
+		if got != string(bytes.TrimSpace(want)) {
+			t.Errorf("%q: graph mismatch; expected `%s`, got `%s`", g.in, want, got)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	paths := []string{
+		"../internal/testdata/attr.dot",
+		"../internal/testdata/attr_stmt.dot",
+		"../internal/testdata/edge_stmt.dot",
+		"../internal/testdata/port.dot",
+		"../internal/testdata/quoted_id.dot",
+		"../internal/testdata/subgraph.dot",
+		"../internal/testdata/subgraph_vertex.dot",
+		"testdata/cluster.dot",
+	}
+	for _, path := range paths {
+		file, err := dot.ParseFile(path)
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", path, err)
+			continue
+		}
+		want, err := graphml.Marshal(file.Graphs[0])
+		if err != nil {
+			t.Errorf("%q: unable to encode graph; %v", path, err)
+			continue
+		}
+		// DOT -> GraphML -> DOT -> GraphML.
+		graph, err := graphml.Unmarshal(want)
+		if err != nil {
+			t.Errorf("%q: unable to decode graph; %v", path, err)
+			continue
+		}
+		f, err := dot.ParseString(graph.String())
+		if err != nil {
+			t.Errorf("%q: unable to parse decoded graph; %v", path, err)
+			continue
+		}
+		got, err := graphml.Marshal(f.Graphs[0])
+		if err != nil {
+			t.Errorf("%q: unable to encode decoded graph; %v", path, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%q: GraphML mismatch after round trip; expected `%s`, got `%s`", path, want, got)
+		}
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		{in: `<graphml><graph/><graph/></graphml>`, want: "invalid number of GraphML graphs; expected 1, got 2"},
+		// U+FFFD is invalid in DOT IDs.
+		{in: `<graphml><graph><node id="&#xFFFD;"/></graph></graphml>`, want: `invalid ID "�"; contains NUL, U+FFFD or invalid UTF-8`},
+	}
+	for _, g := range golden {
+		_, err := graphml.Unmarshal([]byte(g.in))
+		if err == nil {
+			t.Errorf("%q: expected error, got nil", g.in)
+			continue
+		}
+		if got := err.Error(); got != g.want {
+			t.Errorf("%q: error mismatch; expected `%v`, got `%v`", g.in, g.want, got)
+		}
+	}
+}
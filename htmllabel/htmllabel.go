@@ -0,0 +1,768 @@
+// Package htmllabel implements parsing and printing of Graphviz HTML-like
+// labels.
+//
+// HTML-like labels are either formatted text or a table, as specified by the
+// following grammar.
+//
+//    label     : text | fonttable
+//    text      : textitem | text textitem
+//    textitem  : string | <BR/> | <FONT> text </FONT> | <I> text </I>
+//              | <B> text </B> | <U> text </U> | <O> text </O>
+//              | <SUB> text </SUB> | <SUP> text </SUP> | <S> text </S>
+//    fonttable : table | <FONT> table </FONT> | <I> table </I>
+//              | <B> table </B> | <U> table </U> | <O> table </O>
+//    table     : <TABLE> rows </TABLE>
+//    rows      : row | rows row | rows <HR/> row
+//    row       : <TR> cells </TR>
+//    cells     : cell | cells cell | cells <VR/> cell
+//    cell      : <TD> label </TD> | <TD> <IMG/> </TD>
+//
+// Element and attribute names are case-insensitive.
+//
+// ref: https://graphviz.org/doc/info/shapes.html#html
+package htmllabel
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/graphism/dot/internal/enc"
+	"github.com/pkg/errors"
+)
+
+// === [ Labels ] ==============================================================
+
+// A Label is an HTML-like label.
+type Label struct {
+	// Label contents; either text items or a single (possibly formatted)
+	// table.
+	Nodes []Node
+}
+
+// String returns the string representation of the label, as an HTML string
+// enclosed in angle brackets. Tables are pretty-printed, with one row and cell
+// per line.
+func (l *Label) String() string {
+	buf := &bytes.Buffer{}
+	buf.WriteString("<")
+	if isBlock(l.Nodes) {
+		buf.WriteString("\n")
+		printNodes(buf, l.Nodes, 1)
+	} else {
+		for _, n := range l.Nodes {
+			buf.WriteString(n.String())
+		}
+	}
+	buf.WriteString(">")
+	return buf.String()
+}
+
+// Ports returns the port names defined by the PORT attributes of the tables and
+// cells of the label, in order of appearance.
+func (l *Label) Ports() []string {
+	var ports []string
+	var walk func(nodes []Node)
+	walk = func(nodes []Node) {
+		for _, n := range nodes {
+			elem, ok := n.(*Element)
+			if !ok {
+				continue
+			}
+			if port, ok := elem.Attr("PORT"); ok {
+				ports = append(ports, port)
+			}
+			walk(elem.Children)
+		}
+	}
+	walk(l.Nodes)
+	return ports
+}
+
+// === [ Nodes ] ===============================================================
+
+// A Node is a node of an HTML-like label; an element or text.
+//
+// Node may have one of the following underlying types.
+//
+//    *Element
+//    *Text
+type Node interface {
+	// String returns the string representation of the node.
+	String() string
+	// isNode ensures that only HTML-like label nodes can be assigned to the
+	// Node interface.
+	isNode()
+}
+
+// An Element is an element of an HTML-like label.
+type Element struct {
+	// Element kind.
+	Kind Kind
+	// Element attributes.
+	Attrs []*Attr
+	// Child nodes.
+	Children []Node
+}
+
+// Attr returns the value of the attribute with the given name, and a boolean
+// value indicating if such an attribute exists. Attribute names are
+// case-insensitive.
+func (e *Element) Attr(name string) (string, bool) {
+	for _, a := range e.Attrs {
+		if strings.EqualFold(a.Name, name) {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// String returns the string representation of the element.
+func (e *Element) String() string {
+	buf := &bytes.Buffer{}
+	writeStartTag(buf, e)
+	if isEmpty(e.Kind) {
+		return buf.String()
+	}
+	for _, child := range e.Children {
+		buf.WriteString(child.String())
+	}
+	fmt.Fprintf(buf, "</%s>", e.Kind)
+	return buf.String()
+}
+
+// An Attr is an attribute of an element.
+type Attr struct {
+	// Attribute name, in upper case.
+	Name string
+	// Attribute value, with character entities decoded.
+	Val string
+}
+
+// String returns the string representation of the attribute.
+func (a *Attr) String() string {
+	return fmt.Sprintf(`%s="%s"`, a.Name, attrEscaper.Replace(a.Val))
+}
+
+// Text is the text of an HTML-like label.
+type Text struct {
+	// Text, with character entities decoded.
+	Text string
+}
+
+// String returns the string representation of the text.
+func (t *Text) String() string {
+	return textEscaper.Replace(t.Text)
+}
+
+// isNode ensures that only HTML-like label nodes can be assigned to the Node
+// interface.
+func (*Element) isNode() {}
+func (*Text) isNode()    {}
+
+// Kind specifies the kind of an element.
+type Kind uint8
+
+// Element kinds.
+const (
+	KindNone Kind = iota
+	KindTable
+	KindTR
+	KindTD
+	KindFont
+	KindB
+	KindI
+	KindU
+	KindO
+	KindSub
+	KindSup
+	KindS
+	KindBR
+	KindHR
+	KindVR
+	KindImg
+)
+
+// kindNames maps from element kind to element name.
+var kindNames = [...]string{
+	KindTable: "TABLE",
+	KindTR:    "TR",
+	KindTD:    "TD",
+	KindFont:  "FONT",
+	KindB:     "B",
+	KindI:     "I",
+	KindU:     "U",
+	KindO:     "O",
+	KindSub:   "SUB",
+	KindSup:   "SUP",
+	KindS:     "S",
+	KindBR:    "BR",
+	KindHR:    "HR",
+	KindVR:    "VR",
+	KindImg:   "IMG",
+}
+
+// String returns the element name of the kind.
+func (kind Kind) String() string {
+	if kind == KindNone || int(kind) >= len(kindNames) {
+		return fmt.Sprintf("Kind(%d)", uint(kind))
+	}
+	return kindNames[kind]
+}
+
+// kindOf returns the element kind of the given element name; or KindNone if
+// unknown.
+func kindOf(name string) Kind {
+	name = strings.ToUpper(name)
+	for kind, s := range kindNames {
+		if s != "" && s == name {
+			return Kind(kind)
+		}
+	}
+	return KindNone
+}
+
+// isEmpty reports whether elements of the given kind have no content.
+func isEmpty(kind Kind) bool {
+	switch kind {
+	case KindBR, KindHR, KindVR, KindImg:
+		return true
+	}
+	return false
+}
+
+// === [ Printing ] ============================================================
+
+// isBlock reports whether the given nodes contain a table, and are thus
+// printed with one row and cell per line.
+func isBlock(nodes []Node) bool {
+	for _, n := range nodes {
+		if elem, ok := n.(*Element); ok {
+			if elem.Kind == KindTable || elem.Kind == KindTR || elem.Kind == KindTD || isBlock(elem.Children) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// printNodes pretty-prints the given nodes at the given indentation depth.
+// Whitespace-only text between block elements is omitted.
+func printNodes(buf *bytes.Buffer, nodes []Node, depth int) {
+	indent := strings.Repeat("\t", depth)
+	for _, n := range nodes {
+		if t, ok := n.(*Text); ok && strings.TrimSpace(t.Text) == "" {
+			continue
+		}
+		elem, ok := n.(*Element)
+		if !ok || !isBlock(elem.Children) {
+			buf.WriteString(indent)
+			buf.WriteString(n.String())
+			buf.WriteString("\n")
+			continue
+		}
+		buf.WriteString(indent)
+		writeStartTag(buf, elem)
+		buf.WriteString("\n")
+		printNodes(buf, elem.Children, depth+1)
+		fmt.Fprintf(buf, "%s</%s>\n", indent, elem.Kind)
+	}
+}
+
+// writeStartTag writes the start tag of the given element. The tags of empty
+// elements are self-closing.
+func writeStartTag(buf *bytes.Buffer, e *Element) {
+	fmt.Fprintf(buf, "<%s", e.Kind)
+	for _, a := range e.Attrs {
+		fmt.Fprintf(buf, " %s", a)
+	}
+	if isEmpty(e.Kind) {
+		buf.WriteString("/")
+	}
+	buf.WriteString(">")
+}
+
+// textEscaper escapes special characters of text.
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// attrEscaper escapes special characters of attribute values.
+var attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// === [ Parsing ] =============================================================
+
+// Parse parses the given HTML-like label. The label may be enclosed in angle
+// brackets, as in the value of an attribute.
+func Parse(s string) (*Label, error) {
+	if enc.IsHTML(s) {
+		s = s[1 : len(s)-1]
+	}
+	p := &parser{s: s}
+	nodes, err := p.nodes(nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	l := &Label{Nodes: nodes}
+	if err := checkLabel(l.Nodes); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return l, nil
+}
+
+// A parser tracks the position within an HTML-like label.
+type parser struct {
+	// HTML-like label, without enclosing angle brackets.
+	s string
+	// Current position.
+	pos int
+}
+
+// nodes parses nodes until the end tag of the given parent element; or until
+// the end of input if parent is nil.
+func (p *parser) nodes(parent *Element) ([]Node, error) {
+	var nodes []Node
+	for p.pos < len(p.s) {
+		switch {
+		case strings.HasPrefix(p.s[p.pos:], "<!--"):
+			end := strings.Index(p.s[p.pos:], "-->")
+			if end == -1 {
+				return nil, errors.Errorf("offset %d: unterminated comment", p.pos)
+			}
+			p.pos += end + len("-->")
+		case strings.HasPrefix(p.s[p.pos:], "</"):
+			start := p.pos
+			end := strings.IndexByte(p.s[p.pos:], '>')
+			if end == -1 {
+				return nil, errors.Errorf("offset %d: unterminated end tag", start)
+			}
+			name := strings.TrimSpace(p.s[p.pos+2 : p.pos+end])
+			p.pos += end + 1
+			if parent == nil {
+				return nil, errors.Errorf("offset %d: unexpected end tag </%s>", start, name)
+			}
+			if kindOf(name) != parent.Kind {
+				return nil, errors.Errorf("offset %d: mismatched end tag </%s>; expected </%s>", start, name, parent.Kind)
+			}
+			return nodes, nil
+		case p.s[p.pos] == '<':
+			elem, err := p.element()
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			nodes = append(nodes, elem)
+		default:
+			end := strings.IndexByte(p.s[p.pos:], '<')
+			if end == -1 {
+				end = len(p.s) - p.pos
+			}
+			text := html.UnescapeString(p.s[p.pos : p.pos+end])
+			p.pos += end
+			// Merge adjacent text, as split by comments.
+			if n := len(nodes); n > 0 {
+				if t, ok := nodes[n-1].(*Text); ok {
+					t.Text += text
+					continue
+				}
+			}
+			nodes = append(nodes, &Text{Text: text})
+		}
+	}
+	if parent != nil {
+		return nil, errors.Errorf("offset %d: missing end tag </%s>", p.pos, parent.Kind)
+	}
+	return nodes, nil
+}
+
+// element parses an element, starting at its start tag.
+func (p *parser) element() (*Element, error) {
+	start := p.pos
+	p.pos++
+	name := p.name()
+	kind := kindOf(name)
+	if kind == KindNone {
+		return nil, errors.Errorf("offset %d: unknown element <%s>", start, name)
+	}
+	elem := &Element{Kind: kind}
+	for {
+		p.skipSpace()
+		if p.pos == len(p.s) {
+			return nil, errors.Errorf("offset %d: unterminated start tag <%s>", start, kind)
+		}
+		if strings.HasPrefix(p.s[p.pos:], "/>") {
+			p.pos += 2
+			break
+		}
+		if p.s[p.pos] == '>' {
+			p.pos++
+			children, err := p.nodes(elem)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			elem.Children = children
+			if isEmpty(kind) && len(children) > 0 {
+				return nil, errors.Errorf("offset %d: unexpected content in empty element <%s/>", start, kind)
+			}
+			break
+		}
+		attr, err := p.attr(elem)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		elem.Attrs = append(elem.Attrs, attr)
+	}
+	if err := checkAttrs(elem); err != nil {
+		return nil, errors.Errorf("offset %d: %v", start, err)
+	}
+	return elem, nil
+}
+
+// attr parses an attribute of the given element.
+func (p *parser) attr(elem *Element) (*Attr, error) {
+	start := p.pos
+	name := strings.ToUpper(p.name())
+	if name == "" {
+		return nil, errors.Errorf("offset %d: invalid character %q in start tag <%s>", start, p.s[p.pos], elem.Kind)
+	}
+	p.skipSpace()
+	if p.pos == len(p.s) || p.s[p.pos] != '=' {
+		return nil, errors.Errorf("offset %d: missing value of attribute %s", start, name)
+	}
+	p.pos++
+	p.skipSpace()
+	if p.pos == len(p.s) || (p.s[p.pos] != '"' && p.s[p.pos] != '\'') {
+		return nil, errors.Errorf("offset %d: unquoted value of attribute %s", start, name)
+	}
+	quote := p.s[p.pos]
+	end := strings.IndexByte(p.s[p.pos+1:], quote)
+	if end == -1 {
+		return nil, errors.Errorf("offset %d: unterminated value of attribute %s", start, name)
+	}
+	val := html.UnescapeString(p.s[p.pos+1 : p.pos+1+end])
+	p.pos += end + 2
+	if _, ok := elem.Attr(name); ok {
+		return nil, errors.Errorf("offset %d: duplicate attribute %s", start, name)
+	}
+	return &Attr{Name: name, Val: val}, nil
+}
+
+// name parses an element or attribute name.
+func (p *parser) name() string {
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_') {
+			break
+		}
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// skipSpace skips whitespace characters.
+func (p *parser) skipSpace() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) != -1 {
+		p.pos++
+	}
+}
+
+// === [ Validation ] ==========================================================
+
+// checkLabel validates the structure of the given label contents; either text
+// items or a single (possibly formatted) table. Whitespace-only text between
+// table elements is removed.
+func checkLabel(nodes []Node) error {
+	if hasTable(nodes) {
+		return checkFontTable(nodes)
+	}
+	return checkText(nodes)
+}
+
+// hasTable reports whether the given nodes contain a table, either directly or
+// within formatting elements.
+func hasTable(nodes []Node) bool {
+	for _, n := range nodes {
+		if elem, ok := n.(*Element); ok {
+			if elem.Kind == KindTable || hasTable(elem.Children) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkFontTable validates the structure of the given (possibly formatted)
+// table.
+func checkFontTable(nodes []Node) error {
+	var table *Element
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *Text:
+			if strings.TrimSpace(n.Text) != "" {
+				return errors.Errorf("unexpected text %q next to <TABLE>", n.Text)
+			}
+		case *Element:
+			if table != nil {
+				return errors.Errorf("unexpected <%s> next to <TABLE>", n.Kind)
+			}
+			table = n
+		}
+	}
+	switch table.Kind {
+	case KindTable:
+		return checkTable(table)
+	case KindFont, KindB, KindI, KindU, KindO:
+		return checkFontTable(table.Children)
+	}
+	return errors.Errorf("unexpected <%s> enclosing <TABLE>", table.Kind)
+}
+
+// checkText validates the structure of the given text items.
+func checkText(nodes []Node) error {
+	for _, n := range nodes {
+		elem, ok := n.(*Element)
+		if !ok {
+			continue
+		}
+		switch elem.Kind {
+		case KindBR:
+		case KindFont, KindB, KindI, KindU, KindO, KindSub, KindSup, KindS:
+			if err := checkText(elem.Children); err != nil {
+				return errors.WithStack(err)
+			}
+		default:
+			return errors.Errorf("unexpected <%s> in text", elem.Kind)
+		}
+	}
+	return nil
+}
+
+// checkTable validates the structure of the given table; rows optionally
+// separated by horizontal rules.
+func checkTable(table *Element) error {
+	children, err := checkChildren(table, KindTR, KindHR, checkRow)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	table.Children = children
+	return nil
+}
+
+// checkRow validates the structure of the given row; cells optionally
+// separated by vertical rules.
+func checkRow(row *Element) error {
+	children, err := checkChildren(row, KindTD, KindVR, checkCell)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	row.Children = children
+	return nil
+}
+
+// checkChildren validates that the children of the given table or row are
+// items of the given kind, optionally separated by rules of the given kind.
+// Each item is validated by the check function. The children are returned
+// without whitespace-only text.
+func checkChildren(parent *Element, item, rule Kind, check func(*Element) error) ([]Node, error) {
+	var children []Node
+	// Rules are only valid between items.
+	afterItem := false
+	for _, n := range parent.Children {
+		switch n := n.(type) {
+		case *Text:
+			if strings.TrimSpace(n.Text) != "" {
+				return nil, errors.Errorf("unexpected text %q in <%s>", n.Text, parent.Kind)
+			}
+			continue
+		case *Element:
+			switch n.Kind {
+			case item:
+				if err := check(n); err != nil {
+					return nil, errors.WithStack(err)
+				}
+				afterItem = true
+			case rule:
+				if !afterItem {
+					return nil, errors.Errorf("unexpected <%s/> in <%s>; expected <%s>", rule, parent.Kind, item)
+				}
+				afterItem = false
+			default:
+				return nil, errors.Errorf("unexpected <%s> in <%s>; expected <%s> or <%s/>", n.Kind, parent.Kind, item, rule)
+			}
+			children = append(children, n)
+		}
+	}
+	if len(children) == 0 {
+		return nil, errors.Errorf("missing <%s> in <%s>", item, parent.Kind)
+	}
+	if !afterItem {
+		return nil, errors.Errorf("unexpected <%s/> at end of <%s>", rule, parent.Kind)
+	}
+	return children, nil
+}
+
+// checkCell validates the structure of the given cell; either an image or a
+// label.
+func checkCell(cell *Element) error {
+	var img *Element
+	for _, n := range cell.Children {
+		if elem, ok := n.(*Element); ok && elem.Kind == KindImg {
+			img = elem
+		}
+	}
+	if img == nil {
+		return checkLabel(cell.Children)
+	}
+	for _, n := range cell.Children {
+		if t, ok := n.(*Text); ok && strings.TrimSpace(t.Text) == "" {
+			continue
+		}
+		if n != img {
+			return errors.New("unexpected content next to <IMG/> in <TD>")
+		}
+	}
+	cell.Children = []Node{img}
+	return nil
+}
+
+// checkAttrs validates the attributes of the given element.
+func checkAttrs(elem *Element) error {
+	valid := attrs[elem.Kind]
+	for _, a := range elem.Attrs {
+		check, ok := valid[a.Name]
+		if !ok {
+			return errors.Errorf("invalid attribute %s of <%s>", a.Name, elem.Kind)
+		}
+		if err := check(a.Val); err != nil {
+			return errors.Errorf("invalid value %q of attribute %s of <%s>; %v", a.Val, a.Name, elem.Kind, err)
+		}
+	}
+	if elem.Kind == KindImg {
+		if _, ok := elem.Attr("SRC"); !ok {
+			return errors.New("missing attribute SRC of <IMG>")
+		}
+	}
+	return nil
+}
+
+// attrs maps from element kind to valid attributes and their value checks.
+var attrs = map[Kind]map[string]func(string) error{
+	KindTable: {
+		"ALIGN":         enum("CENTER", "LEFT", "RIGHT"),
+		"BGCOLOR":       anyValue,
+		"BORDER":        intRange(0, 255),
+		"CELLBORDER":    intRange(0, 127),
+		"CELLPADDING":   intRange(0, 255),
+		"CELLSPACING":   intRange(0, 127),
+		"COLOR":         anyValue,
+		"COLUMNS":       enum("*"),
+		"FIXEDSIZE":     enum("FALSE", "TRUE"),
+		"GRADIENTANGLE": intRange(0, 360),
+		"HEIGHT":        intRange(0, 65535),
+		"HREF":          anyValue,
+		"ID":            anyValue,
+		"PORT":          anyValue,
+		"ROWS":          enum("*"),
+		"SIDES":         sides,
+		"STYLE":         styles("ROUNDED", "RADIAL", "SOLID", "INVISIBLE", "INVIS", "DOTTED", "DASHED"),
+		"TARGET":        anyValue,
+		"TITLE":         anyValue,
+		"TOOLTIP":       anyValue,
+		"VALIGN":        enum("MIDDLE", "BOTTOM", "TOP"),
+		"WIDTH":         intRange(0, 65535),
+	},
+	KindTD: {
+		"ALIGN":         enum("CENTER", "LEFT", "RIGHT", "TEXT"),
+		"BALIGN":        enum("CENTER", "LEFT", "RIGHT"),
+		"BGCOLOR":       anyValue,
+		"BORDER":        intRange(0, 127),
+		"CELLPADDING":   intRange(0, 255),
+		"CELLSPACING":   intRange(0, 127),
+		"COLOR":         anyValue,
+		"COLSPAN":       intRange(1, 65535),
+		"FIXEDSIZE":     enum("FALSE", "TRUE"),
+		"GRADIENTANGLE": intRange(0, 360),
+		"HEIGHT":        intRange(0, 65535),
+		"HREF":          anyValue,
+		"ID":            anyValue,
+		"PORT":          anyValue,
+		"ROWSPAN":       intRange(1, 65535),
+		"SIDES":         sides,
+		"STYLE":         styles("RADIAL", "SOLID", "INVISIBLE", "INVIS", "DOTTED", "DASHED"),
+		"TARGET":        anyValue,
+		"TITLE":         anyValue,
+		"TOOLTIP":       anyValue,
+		"VALIGN":        enum("MIDDLE", "BOTTOM", "TOP"),
+		"WIDTH":         intRange(0, 65535),
+	},
+	KindFont: {
+		"COLOR":      anyValue,
+		"FACE":       anyValue,
+		"POINT-SIZE": positive,
+	},
+	KindBR: {
+		"ALIGN": enum("CENTER", "LEFT", "RIGHT"),
+	},
+	KindImg: {
+		"SCALE": enum("FALSE", "TRUE", "WIDTH", "HEIGHT", "BOTH"),
+		"SRC":   anyValue,
+	},
+}
+
+// anyValue accepts any attribute value.
+func anyValue(string) error {
+	return nil
+}
+
+// enum returns a check which accepts the given case-insensitive values.
+func enum(vals ...string) func(string) error {
+	return func(s string) error {
+		for _, val := range vals {
+			if strings.EqualFold(s, val) {
+				return nil
+			}
+		}
+		return errors.Errorf("expected one of %s", strings.Join(vals, ", "))
+	}
+}
+
+// intRange returns a check which accepts integers in the range [min, max].
+func intRange(min, max int) func(string) error {
+	return func(s string) error {
+		var x int
+		if _, err := fmt.Sscanf(s, "%d", &x); err != nil || fmt.Sprint(x) != strings.TrimPrefix(s, "+") || x < min || x > max {
+			return errors.Errorf("expected integer in range [%d, %d]", min, max)
+		}
+		return nil
+	}
+}
+
+// positive accepts positive numbers.
+func positive(s string) error {
+	var x float64
+	if _, err := fmt.Sscanf(s, "%g", &x); err != nil || x <= 0 {
+		return errors.New("expected positive number")
+	}
+	return nil
+}
+
+// sides accepts any combination of the letters L, T, R and B.
+func sides(s string) error {
+	if s == "" || strings.Trim(strings.ToUpper(s), "LTRB") != "" {
+		return errors.New("expected combination of L, T, R and B")
+	}
+	return nil
+}
+
+// styles returns a check which accepts comma- or space-separated lists of the
+// given case-insensitive styles.
+func styles(vals ...string) func(string) error {
+	check := enum(vals...)
+	return func(s string) error {
+		for _, style := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+			if err := check(style); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		return nil
+	}
+}
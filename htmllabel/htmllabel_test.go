@@ -0,0 +1,175 @@
+package htmllabel_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/graphism/dot/htmllabel"
+)
+
+func TestParse(t *testing.T) {
+	golden := []struct {
+		path  string
+		want  string
+		ports []string
+	}{
+		{
+			path:  "testdata/table.html",
+			want:  "testdata/table.golden",
+			ports: []string{"a", "b", "c"},
+		},
+		{
+			path: "testdata/text.html",
+			want: "testdata/text.golden",
+		},
+	}
+	for _, g := range golden {
+		buf, err := ioutil.ReadFile(g.path)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", g.path, err)
+			continue
+		}
+		label, err := htmllabel.Parse(string(bytes.TrimSpace(buf)))
+		if err != nil {
+			t.Errorf("%q: unable to parse label; %v", g.path, err)
+			continue
+		}
+		want, err := ioutil.ReadFile(g.want)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", g.want, err)
+			continue
+		}
+		got := label.String()
+		if got != string(bytes.TrimSpace(want)) {
+			t.Errorf("%q: label mismatch; expected `%s`, got `%s`", g.path, want, got)
+		}
+		if ports := label.Ports(); !reflect.DeepEqual(ports, g.ports) {
+			t.Errorf("%q: ports mismatch; expected %q, got %q", g.path, g.ports, ports)
+		}
+		// Pretty-printed labels parse to the same label.
+		label, err = htmllabel.Parse(got)
+		if err != nil {
+			t.Errorf("%q: unable to parse pretty-printed label; %v", g.path, err)
+			continue
+		}
+		if s := label.String(); s != got {
+			t.Errorf("%q: round trip mismatch; expected `%s`, got `%s`", g.path, got, s)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		{
+			in:   `<<B>x</I>>`,
+			want: "offset 4: mismatched end tag </I>; expected </B>",
+		},
+		{
+			in:   `<<B>x>`,
+			want: "offset 4: missing end tag </B>",
+		},
+		{
+			in:   `<<BLINK>x</BLINK>>`,
+			want: "offset 0: unknown element <BLINK>",
+		},
+		{
+			in:   `<<FONT COLOR=red>x</FONT>>`,
+			want: "offset 6: unquoted value of attribute COLOR",
+		},
+		{
+			in:   `<<FONT COLOR="red" COLOR="blue">x</FONT>>`,
+			want: "offset 18: duplicate attribute COLOR",
+		},
+		{
+			in:   `<<FONT SIZE="2">x</FONT>>`,
+			want: "offset 0: invalid attribute SIZE of <FONT>",
+		},
+		{
+			in:   `<<FONT POINT-SIZE="-2">x</FONT>>`,
+			want: `offset 0: invalid value "-2" of attribute POINT-SIZE of <FONT>; expected positive number`,
+		},
+		{
+			in:   `<<BR ALIGN="TEXT"/>>`,
+			want: `offset 0: invalid value "TEXT" of attribute ALIGN of <BR>; expected one of CENTER, LEFT, RIGHT`,
+		},
+		{
+			in:   `<<BR>x</BR>>`,
+			want: "offset 0: unexpected content in empty element <BR/>",
+		},
+		{
+			in:   `<<TABLE BORDER="256"><TR><TD>x</TD></TR></TABLE>>`,
+			want: `offset 0: invalid value "256" of attribute BORDER of <TABLE>; expected integer in range [0, 255]`,
+		},
+		{
+			in:   `<<TABLE><TR><TD STYLE="ROUNDED">x</TD></TR></TABLE>>`,
+			want: `offset 11: invalid value "ROUNDED" of attribute STYLE of <TD>; expected one of RADIAL, SOLID, INVISIBLE, INVIS, DOTTED, DASHED`,
+		},
+		{
+			in:   `<<TABLE SIDES="LX"><TR><TD>x</TD></TR></TABLE>>`,
+			want: `offset 0: invalid value "LX" of attribute SIDES of <TABLE>; expected combination of L, T, R and B`,
+		},
+		{
+			in:   `<<TABLE><TR><TD COLSPAN="0">x</TD></TR></TABLE>>`,
+			want: `offset 11: invalid value "0" of attribute COLSPAN of <TD>; expected integer in range [1, 65535]`,
+		},
+		{
+			in:   `<<TABLE></TABLE>>`,
+			want: "missing <TR> in <TABLE>",
+		},
+		{
+			in:   `<<TABLE><HR/><TR><TD>x</TD></TR></TABLE>>`,
+			want: "unexpected <HR/> in <TABLE>; expected <TR>",
+		},
+		{
+			in:   `<<TABLE><TR><TD>x</TD></TR><HR/></TABLE>>`,
+			want: "unexpected <HR/> at end of <TABLE>",
+		},
+		{
+			in:   `<<TABLE><TR><TD>x</TD><VR/><VR/><TD>y</TD></TR></TABLE>>`,
+			want: "unexpected <VR/> in <TR>; expected <TD>",
+		},
+		{
+			in:   `<<TABLE><TD>x</TD></TABLE>>`,
+			want: "unexpected <TD> in <TABLE>; expected <TR> or <HR/>",
+		},
+		{
+			in:   `<<TABLE>x<TR><TD>x</TD></TR></TABLE>>`,
+			want: `unexpected text "x" in <TABLE>`,
+		},
+		{
+			in:   `<x<TABLE><TR><TD>x</TD></TR></TABLE>>`,
+			want: `unexpected text "x" next to <TABLE>`,
+		},
+		{
+			in:   `<<SUB><TABLE><TR><TD>x</TD></TR></TABLE></SUB>>`,
+			want: "unexpected <SUB> enclosing <TABLE>",
+		},
+		{
+			in:   `<<TR><TD>x</TD></TR>>`,
+			want: "unexpected <TR> in text",
+		},
+		{
+			in:   `<<TABLE><TR><TD>x<IMG SRC="a.png"/></TD></TR></TABLE>>`,
+			want: "unexpected content next to <IMG/> in <TD>",
+		},
+		{
+			in:   `<<TABLE><TR><TD><IMG/></TD></TR></TABLE>>`,
+			want: "offset 15: missing attribute SRC of <IMG>",
+		},
+	}
+	for _, g := range golden {
+		_, err := htmllabel.Parse(g.in)
+		if err == nil {
+			t.Errorf("%q: expected error, got nil", g.in)
+			continue
+		}
+		if got := err.Error(); got != g.want {
+			t.Errorf("%q: error mismatch; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/internal/enc"
 	"github.com/graphism/dot/internal/token"
 	"github.com/pkg/errors"
 )
@@ -262,14 +263,22 @@ func NewPort(id, optCompassPoint interface{}) (*ast.Port, error) {
 	if optCompassPoint != nil && !ok {
 		return nil, errors.Errorf("invalid compass point type; expected string or nil, got %T", optCompassPoint)
 	}
-	compassPoint, _ := getCompassPoint(c)
+	if optCompassPoint == nil {
+		return &ast.Port{ID: i}, nil
+	}
+	compassPoint, ok := getCompassPoint(c)
+	if !ok {
+		// Invalid compass points are ignored by Graphviz, and reported by the
+		// semantic checker.
+		return &ast.Port{ID: i, InvalidCompassPoint: c}, nil
+	}
 	return &ast.Port{ID: i, CompassPoint: compassPoint}, nil
 }
 
 // getCompassPoint returns the corresponding compass point to the given string,
 // and a boolean value indicating if such a compass point exists.
 func getCompassPoint(s string) (ast.CompassPoint, bool) {
-	switch s {
+	switch enc.Unquote(s) {
 	case "_":
 		return ast.CompassPointDefault, true
 	case "n":
@@ -308,11 +317,35 @@ func NewID(id interface{}) (string, error) {
 	// multiple physical lines using the standard C convention of a backslash
 	// immediately preceding a newline character.
 	if strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
-		// Strip "\\\n" sequences.
-		s = strings.Replace(s, "\\\n", "", -1)
+		s = stripLineContinuations(s)
 	}
 
 	// TODO: Add support for concatenated using a '+' operator.
 
 	return s, nil
 }
+
+// stripLineContinuations strips the "\\\n" sequences of the given double-quoted
+// string. Escaped backslashes and double quotes are kept as is; thus a newline
+// preceded by an escaped backslash is not a line continuation.
+func stripLineContinuations(s string) string {
+	if !strings.Contains(s, "\\\n") {
+		return s
+	}
+	buf := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch s[i+1] {
+			case '\n':
+				i++
+				continue
+			case '\\', '"':
+				buf = append(buf, s[i], s[i+1])
+				i++
+				continue
+			}
+		}
+		buf = append(buf, s[i])
+	}
+	return string(buf)
+}
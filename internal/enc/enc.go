@@ -0,0 +1,246 @@
+// Package enc implements encoding and decoding of Graphviz DOT identifiers.
+package enc
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Quote returns s as a valid DOT identifier. The identifier is double-quoted
+// unless s is already a valid identifier; i.e. an alphanumeric string, a
+// numeral, a double-quoted string or an HTML string. Keywords are always
+// double-quoted.
+//
+// The dyad \" is the only escape sequence recognized within double-quoted
+// strings, as such any double quote character of s is escaped with a
+// backslash; all other characters are left unchanged. As backslashes escape
+// the character following them, a backslash preceding a double quote or the
+// end of s is doubled, and the dyad \\ is kept as is. Thus the quoted string is
+// unquoted to s if IsQuotable(s); otherwise, the quoted string is a valid DOT
+// identifier which is unquoted to a different string.
+func Quote(s string) string {
+	if IsID(s) && !IsKeyword(s) {
+		return s
+	}
+	return quote(s)
+}
+
+// QuoteText returns s as a valid DOT identifier, which is unquoted to s if
+// IsQuotable(s). Unlike
+// Quote, double-quoted strings and HTML strings are double-quoted, as s is text
+// rather than an identifier; only alphanumeric strings and numerals are left
+// unquoted.
+func QuoteText(s string) string {
+	if len(s) > 0 && (isAlnum(s) || isNumeral(s)) && !IsKeyword(s) {
+		return s
+	}
+	return quote(s)
+}
+
+// quote returns s as a double-quoted string, escaping double quotes as
+// described by Quote.
+func quote(s string) string {
+	buf := make([]byte, 0, len(s)+2)
+	buf = append(buf, '"')
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			buf = append(buf, '\\', '"')
+		case '\\':
+			buf = append(buf, '\\')
+			switch {
+			case i+1 < len(s) && s[i+1] == '\\':
+				// Keep escaped backslash.
+				buf = append(buf, '\\')
+				i++
+			case i+1 == len(s) || s[i+1] == '"':
+				// The backslash would otherwise escape the closing or escaped
+				// double quote.
+				buf = append(buf, '\\')
+			}
+		default:
+			buf = append(buf, s[i])
+		}
+	}
+	buf = append(buf, '"')
+	return string(buf)
+}
+
+// Unquote returns the unquoted version of s if s is a double-quoted string;
+// otherwise s is returned unchanged.
+//
+// Note, in quoted strings the only escaped character is double-quote ("). That
+// is, in quoted strings, the dyad \" is converted to "; all other characters
+// are left unchanged. In particular, \\ remains \\.
+func Unquote(s string) string {
+	if !IsQuoted(s) {
+		return s
+	}
+	s = s[1 : len(s)-1]
+	if !strings.Contains(s, `\"`) {
+		return s
+	}
+	return strings.Replace(s, `\"`, `"`, -1)
+}
+
+// IsQuotable reports whether s is unquoted to s once double-quoted by Quote or
+// QuoteText. As backslashes escape the character following them, this is not
+// the case if s contains an odd number of consecutive backslashes preceding a
+// double quote, a newline (line continuation) or the end of s.
+func IsQuotable(s string) bool {
+	// Number of consecutive backslashes.
+	n := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			n++
+			continue
+		case '"', '\n':
+			if n%2 == 1 {
+				return false
+			}
+		}
+		n = 0
+	}
+	return n%2 == 0
+}
+
+// IsQuoted reports whether s is a double-quoted string.
+func IsQuoted(s string) bool {
+	return len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`)
+}
+
+// IsHTML reports whether s is an HTML string.
+func IsHTML(s string) bool {
+	return len(s) >= 2 && strings.HasPrefix(s, "<") && strings.HasSuffix(s, ">")
+}
+
+// IsID reports whether s is a valid DOT identifier. An identifier is one of the
+// following:
+//
+//    1) Any string of alphabetic ([a-zA-Z\200-\377]) characters, underscores
+//       ('_') or digits ([0-9]), not beginning with a digit;
+//
+//    2) a numeral [-]?(.[0-9]+ | [0-9]+(.[0-9]*)? );
+//
+//    3) any double-quoted string ("...") possibly containing escaped quotes
+//       (\");
+//
+//    4) an HTML string (<...>).
+func IsID(s string) bool {
+	switch {
+	case len(s) == 0:
+		return false
+	case isAlnum(s), isNumeral(s):
+		return true
+	case IsQuoted(s):
+		return isQuotedString(s)
+	case IsHTML(s):
+		return isHTMLString(s)
+	}
+	return false
+}
+
+// IsText reports whether s is representable in DOT files; i.e. whether s is
+// valid UTF-8 without NUL characters and U+FFFD, which are invalid in IDs as by
+// the DOT grammar.
+func IsText(s string) bool {
+	for _, r := range s {
+		if r == 0 || r == utf8.RuneError {
+			return false
+		}
+	}
+	return true
+}
+
+// IsKeyword reports whether s is a DOT keyword. Keywords are case-insensitive.
+func IsKeyword(s string) bool {
+	switch strings.ToLower(s) {
+	case "node", "edge", "graph", "digraph", "subgraph", "strict":
+		return true
+	}
+	return false
+}
+
+// isAlnum reports whether s is a string of alphabetic characters, underscores
+// or digits, not beginning with a digit.
+func isAlnum(s string) bool {
+	for i, r := range s {
+		switch {
+		case r == utf8.RuneError:
+			return false
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', r == '_', r >= 0x80:
+			// valid letter.
+		case '0' <= r && r <= '9':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isNumeral reports whether s is a numeral [-]?(.[0-9]+ | [0-9]+(.[0-9]*)? ).
+func isNumeral(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	if strings.HasPrefix(s, ".") {
+		return isDecimals(s[1:])
+	}
+	pos := strings.IndexByte(s, '.')
+	if pos == -1 {
+		return isDecimals(s)
+	}
+	return isDecimals(s[:pos]) && (pos+1 == len(s) || isDecimals(s[pos+1:]))
+}
+
+// isDecimals reports whether s is a non-empty string of decimal digits.
+func isDecimals(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isQuotedString reports whether the double-quoted string s only contains
+// escaped double quotes.
+func isQuotedString(s string) bool {
+	s = s[1 : len(s)-1]
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			// Skip escaped character.
+			i++
+			if i == len(s) {
+				return false
+			}
+		case '"':
+			return false
+		}
+	}
+	return true
+}
+
+// isHTMLString reports whether s is an HTML string with balanced angle
+// brackets.
+func isHTMLString(s string) bool {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '<':
+			depth++
+		case '>':
+			depth--
+			if depth == 0 && i != len(s)-1 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
@@ -0,0 +1,90 @@
+package enc_test
+
+import (
+	"testing"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/internal/enc"
+)
+
+func TestQuoteText(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+		// Reports whether the quoted string is unquoted to in.
+		quotable bool
+	}{
+		{in: "a", want: "a", quotable: true},
+		{in: "a b", want: `"a b"`, quotable: true},
+		{in: `a"b`, want: `"a\"b"`, quotable: true},
+		{in: `\N`, want: `"\N"`, quotable: true},
+		{in: `a\\`, want: `"a\\"`, quotable: true},
+		{in: `a\\"b`, want: `"a\\\"b"`, quotable: true},
+		{in: `\\\\`, want: `"\\\\"`, quotable: true},
+		{in: "a\nb", want: "\"a\nb\"", quotable: true},
+		{in: "a\\\\\nb", want: "\"a\\\\\nb\"", quotable: true},
+		// Backslashes escaping the closing double quote, a double quote or a
+		// newline.
+		{in: `a\`, want: `"a\\"`},
+		{in: `a\\\`, want: `"a\\\\"`},
+		{in: `a\"b`, want: `"a\\\"b"`},
+		{in: `x\"`, want: `"x\\\""`},
+		{in: "a\\\nb", want: "\"a\\\nb\""},
+	}
+	for _, g := range golden {
+		got := enc.QuoteText(g.in)
+		if got != g.want {
+			t.Errorf("%q: quoted string mismatch; expected %q, got %q", g.in, g.want, got)
+			continue
+		}
+		if quotable := enc.IsQuotable(g.in); quotable != g.quotable {
+			t.Errorf("%q: quotable mismatch; expected %v, got %v", g.in, g.quotable, quotable)
+		}
+		// Verify that the quoted string re-parses as a single ID, which is
+		// unquoted to in if quotable.
+		file, err := dot.ParseString("graph { a=" + got + " }")
+		if err != nil {
+			t.Errorf("%q: unable to parse quoted string; %v", g.in, err)
+			continue
+		}
+		attr := file.Graphs[0].Stmts[0].(*ast.Attr)
+		if val := enc.Unquote(attr.Val); g.quotable && val != g.in {
+			t.Errorf("%q: round-trip mismatch; got %q", g.in, val)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		// Valid IDs are kept as is.
+		{in: "a", want: "a"},
+		{in: `"a\\"`, want: `"a\\"`},
+		{in: `"a\"b"`, want: `"a\"b"`},
+		{in: "<a\\>", want: "<a\\>"},
+		// Keywords are double-quoted.
+		{in: "node", want: `"node"`},
+		// Invalid IDs are double-quoted.
+		{in: `"a\"`, want: `"\"a\\\""`},
+		{in: `C:\`, want: `"C:\\"`},
+	}
+	for _, g := range golden {
+		got := enc.Quote(g.in)
+		if got != g.want {
+			t.Errorf("%q: quoted string mismatch; expected %q, got %q", g.in, g.want, got)
+			continue
+		}
+		file, err := dot.ParseString("graph { a=" + got + " }")
+		if err != nil {
+			t.Errorf("%q: unable to parse quoted string; %v", g.in, err)
+			continue
+		}
+		// Verify that the printed ID re-parses to the same ID.
+		if val := file.Graphs[0].Stmts[0].(*ast.Attr).Val; val != got {
+			t.Errorf("%q: round-trip mismatch; expected %q, got %q", g.in, got, val)
+		}
+	}
+}
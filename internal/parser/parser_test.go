@@ -86,7 +86,7 @@ func TestParseError(t *testing.T) {
 	}{
 		{
 			path: "../testdata/error.dot",
-			want: `Error in S30: INVALID(0,~), Pos(offset=13, line=2, column=7), expected one of: { } graphx ; -- -> node edge [ = subgraph : id `,
+			want: `syntax error at line 2, column 7: unexpected invalid token "~"; expected statement or "}"`,
 		},
 	}
 	for _, g := range golden {
@@ -0,0 +1,538 @@
+// Package scanner implements a hand-written lexer of Graphviz DOT files.
+//
+// The scanner produces the same tokens as the gocc-generated lexer of the
+// internal/lexer package, including the literals and positions of invalid
+// tokens, while avoiding per-rune transition function calls and per-token
+// allocations. Token literals are slices of the source.
+//
+// Scanners of readers hold only the source of the current token in memory,
+// along with a read buffer.
+package scanner
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"unicode/utf8"
+
+	"github.com/graphism/dot/internal/token"
+)
+
+// Token types.
+var (
+	typLBrace   = token.TokMap.Type("{")
+	typRBrace   = token.TokMap.Type("}")
+	typStrict   = token.TokMap.Type("strict")
+	typGraph    = token.TokMap.Type("graphx")
+	typDigraph  = token.TokMap.Type("digraph")
+	typSemi     = token.TokMap.Type(";")
+	typUndirect = token.TokMap.Type("--")
+	typDirect   = token.TokMap.Type("->")
+	typNode     = token.TokMap.Type("node")
+	typEdge     = token.TokMap.Type("edge")
+	typLBrack   = token.TokMap.Type("[")
+	typRBrack   = token.TokMap.Type("]")
+	typComma    = token.TokMap.Type(",")
+	typEqual    = token.TokMap.Type("=")
+	typSubgraph = token.TokMap.Type("subgraph")
+	typColon    = token.TokMap.Type(":")
+	typID       = token.TokMap.Type("id")
+)
+
+// Character classes of ASCII characters.
+const (
+	// Letter or underscore; first character of alphanumeric IDs.
+	classLetter = 1 << iota
+	// Decimal digit.
+	classDigit
+	// Single-character token.
+	classPunct
+)
+
+// classes maps from ASCII character to character class.
+var classes [utf8.RuneSelf]uint8
+
+// punct maps from single-character token to token type.
+var punct [utf8.RuneSelf]token.Type
+
+func init() {
+	for c := 'a'; c <= 'z'; c++ {
+		classes[c] |= classLetter
+		classes[c-'a'+'A'] |= classLetter
+	}
+	classes['_'] |= classLetter
+	for c := '0'; c <= '9'; c++ {
+		classes[c] |= classDigit
+	}
+	for c, typ := range map[byte]token.Type{'{': typLBrace, '}': typRBrace, ';': typSemi, '[': typLBrack, ']': typRBrack, ',': typComma, '=': typEqual, ':': typColon} {
+		classes[c] |= classPunct
+		punct[c] = typ
+	}
+}
+
+// A Scanner tokenizes Graphviz DOT files.
+type Scanner struct {
+	// Source of the DOT file; or the buffered source of readers.
+	src []byte
+	// Current offset in src.
+	pos int
+	// Offset of src in the DOT file.
+	base int
+	// Current line and column; as tracked by the gocc lexer, which advances
+	// columns by 4 at tabs and counts runes.
+	line, column int
+	// Preallocated tokens.
+	toks []token.Token
+	// onComment is called with the source of each skipped comment; or nil.
+	onComment func(lit []byte)
+
+	// Reader of the DOT file; or nil if src contains the entire DOT file.
+	r io.Reader
+	// Reports whether the end of the reader has been reached.
+	eof bool
+	// Read error.
+	err error
+}
+
+// New returns a new scanner of the given DOT file.
+func New(src []byte) *Scanner {
+	return &Scanner{src: src, line: 1, column: 1}
+}
+
+// Comments returns the terminated comments of the given DOT file, in order of
+// occurrence. Comment literals are slices of the source, including the comment
+// delimiters.
+func Comments(src []byte) [][]byte {
+	var comments [][]byte
+	s := New(src)
+	s.onComment = func(lit []byte) {
+		comments = append(comments, lit)
+	}
+	for s.Scan().Type != token.EOF {
+	}
+	return comments
+}
+
+// NewFile returns a new scanner of the given DOT file, reading from path.
+func NewFile(path string) (*Scanner, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(src), nil
+}
+
+// NewReader returns a new scanner of the given DOT file, reading from r.
+func NewReader(r io.Reader) *Scanner {
+	return &Scanner{r: r, line: 1, column: 1}
+}
+
+// Err returns the first non-EOF read error of the scanner. Scanners report
+// the end of the file at read errors.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// tokenChunk specifies the number of tokens allocated at once.
+const tokenChunk = 256
+
+// Scan returns the next token of the DOT file. Whitespace and comments are
+// skipped, and an EOF token is returned at the end of the file.
+func (s *Scanner) Scan() *token.Token {
+	if len(s.toks) == 0 {
+		s.toks = make([]token.Token, tokenChunk)
+	}
+	tok := &s.toks[0]
+	s.toks = s.toks[1:]
+	if s.r == nil {
+		s.scan(tok)
+		return tok
+	}
+	for {
+		pos, line, column := s.pos, s.line, s.column
+		s.scan(tok)
+		// Scanning decisions depend on at most one rune past the end of the
+		// token. Tokens are rescanned after reading more of the source, unless
+		// the rune is known to be buffered.
+		if s.eof || s.pos+utf8.UTFMax <= len(s.src) {
+			return tok
+		}
+		s.pos, s.line, s.column = pos, line, column
+		s.fill()
+	}
+}
+
+// readChunk specifies the minimum size of the read buffer.
+const readChunk = 64 * 1024
+
+// fill discards the source preceding the current offset, and reads more of
+// the source; at least as much as remains buffered, to rescan long tokens a
+// bounded number of times.
+func (s *Scanner) fill() {
+	rest := s.src[s.pos:]
+	n := 2 * len(rest)
+	if n < readChunk {
+		n = readChunk
+	}
+	// Token literals of preceding tokens remain valid, as the buffer is not
+	// reused.
+	buf := make([]byte, len(rest), n)
+	copy(buf, rest)
+	s.base += s.pos
+	s.pos = 0
+	want := 2 * len(rest)
+	if want == 0 {
+		want = 1
+	}
+	for len(buf) < want {
+		m, err := s.r.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+m]
+		if err != nil {
+			if err != io.EOF {
+				s.err = err
+			}
+			s.eof = true
+			break
+		}
+	}
+	s.src = buf
+}
+
+// scan scans the next token of the buffered source into tok.
+func (s *Scanner) scan(tok *token.Token) {
+	s.skip()
+	start := s.pos
+	tok.Pos = token.Pos{Offset: s.base + start, Line: s.line, Column: s.column}
+	if start >= len(s.src) {
+		tok.Type = token.EOF
+		tok.Lit = s.src[start:]
+		return
+	}
+	typ, end, size := s.token()
+	tok.Type = typ
+	if typ == token.INVALID {
+		// Invalid tokens include the rune at which scanning failed, which is not
+		// accounted for in the line and column.
+		s.advance(end)
+		s.pos = end + size
+		tok.Lit = s.src[start:s.pos]
+		return
+	}
+	if typ == typID {
+		s.advance(end)
+	} else {
+		// Keywords and operators.
+		s.column += end - start
+		s.pos = end
+	}
+	tok.Lit = s.src[start:end]
+}
+
+// skip skips whitespace and terminated comments.
+func (s *Scanner) skip() {
+	for s.pos < len(s.src) {
+		switch s.src[s.pos] {
+		case ' ':
+			s.column++
+			s.pos++
+		case '\t':
+			s.column += 4
+			s.pos++
+		case '\r':
+			s.column = 1
+			s.pos++
+		case '\n':
+			s.line++
+			s.column = 1
+			s.pos++
+		case '/', '#':
+			end := s.comment()
+			if end == -1 {
+				return
+			}
+			if s.onComment != nil {
+				s.onComment(s.src[s.pos:end])
+			}
+			s.advance(end)
+		default:
+			return
+		}
+	}
+}
+
+// comment returns the end offset of the comment at the current offset; or -1
+// if not a terminated comment.
+func (s *Scanner) comment() int {
+	src, pos := s.src, s.pos
+	var rest []byte
+	var term string
+	switch {
+	case src[pos] == '#':
+		rest, term = src[pos+1:], "\n"
+	case pos+1 < len(src) && src[pos+1] == '/':
+		rest, term = src[pos+2:], "\n"
+	case pos+1 < len(src) && src[pos+1] == '*':
+		rest, term = src[pos+2:], "*/"
+	default:
+		return -1
+	}
+	i := bytes.Index(rest, []byte(term))
+	if i == -1 {
+		return -1
+	}
+	return len(src) - len(rest) + i + len(term)
+}
+
+// advance advances the current offset to end, tracking lines and columns.
+func (s *Scanner) advance(end int) {
+	for i := s.pos; i < end; {
+		switch c := s.src[i]; {
+		case c == '\n':
+			s.line++
+			s.column = 1
+			i++
+		case c == '\r':
+			s.column = 1
+			i++
+		case c == '\t':
+			s.column += 4
+			i++
+		case c < utf8.RuneSelf:
+			s.column++
+			i++
+		default:
+			_, size := utf8.DecodeRune(s.src[i:])
+			s.column++
+			i += size
+		}
+	}
+	s.pos = end
+}
+
+// token scans the token at the current offset, which is not whitespace, and
+// returns its type and end offset. For invalid tokens, the end offset is that
+// of the rune at which scanning failed, and size is the size of the rune; or 0
+// at the end of the file.
+func (s *Scanner) token() (typ token.Type, end, size int) {
+	src, start := s.src, s.pos
+	c := src[start]
+	if c >= utf8.RuneSelf {
+		if r, n := utf8.DecodeRune(src[start:]); isLetter(r) {
+			return s.id(start + n)
+		}
+		return s.fail(start)
+	}
+	switch {
+	case classes[c]&classPunct != 0:
+		return punct[c], start + 1, 0
+	case classes[c]&classLetter != 0:
+		return s.id(start + 1)
+	case classes[c]&classDigit != 0:
+		return s.number(start)
+	}
+	switch c {
+	case '-':
+		if start+1 < len(src) {
+			switch src[start+1] {
+			case '-':
+				return typUndirect, start + 2, 0
+			case '>':
+				return typDirect, start + 2, 0
+			}
+		}
+		return s.number(start + 1)
+	case '.':
+		return s.number(start)
+	case '"':
+		return s.quoted(start + 1)
+	case '<':
+		return s.html(start + 1)
+	case '/':
+		if start+1 < len(src) && (src[start+1] == '/' || src[start+1] == '*') {
+			// Unterminated comment.
+			return token.INVALID, len(src), 0
+		}
+		return s.fail(start + 1)
+	case '#':
+		// Unterminated comment.
+		return token.INVALID, len(src), 0
+	}
+	return s.fail(start)
+}
+
+// fail returns an invalid token, for which scanning failed at the given offset.
+func (s *Scanner) fail(pos int) (typ token.Type, end, size int) {
+	if pos >= len(s.src) {
+		return token.INVALID, len(s.src), 0
+	}
+	if s.src[pos] < utf8.RuneSelf {
+		return token.INVALID, pos, 1
+	}
+	_, size = utf8.DecodeRune(s.src[pos:])
+	return token.INVALID, pos, size
+}
+
+// isLetter reports whether the given non-ASCII rune is a letter of
+// alphanumeric IDs; any rune except U+FFFD, which is also the rune of invalid
+// UTF-8 encodings.
+func isLetter(r rune) bool {
+	return r != utf8.RuneError
+}
+
+// id scans the remainder of an alphanumeric ID or keyword, starting at pos.
+//
+//    ID : letter { letter | digit }
+func (s *Scanner) id(pos int) (typ token.Type, end, size int) {
+	src := s.src
+	for pos < len(src) {
+		c := src[pos]
+		if c < utf8.RuneSelf {
+			if classes[c]&(classLetter|classDigit) == 0 {
+				break
+			}
+			pos++
+			continue
+		}
+		r, n := utf8.DecodeRune(src[pos:])
+		if !isLetter(r) {
+			break
+		}
+		pos += n
+	}
+	return keyword(src[s.pos:pos]), pos, 0
+}
+
+// keyword returns the token type of the given alphanumeric ID; either the type
+// of the keyword or the ID type. Keywords are recognized in lower case, upper
+// case and the capitalized forms of the DOT grammar.
+func keyword(lit []byte) token.Type {
+	switch string(lit) {
+	case "node", "Node", "NODE":
+		return typNode
+	case "edge", "Edge", "EDGE":
+		return typEdge
+	case "graph", "Graph", "GRAPH":
+		return typGraph
+	case "digraph", "Digraph", "diGraph", "DiGraph", "DIGRAPH":
+		return typDigraph
+	case "subgraph", "Subgraph", "subGraph", "SubGraph", "SUBGRAPH":
+		return typSubgraph
+	case "strict", "Strict", "STRICT":
+		return typStrict
+	}
+	return typID
+}
+
+// number scans a numeral, the optional sign of which precedes pos.
+//
+//    numeral : [ '-' ] ( '.' digits | digits [ '.' { digit } ] )
+func (s *Scanner) number(pos int) (typ token.Type, end, size int) {
+	src := s.src
+	if pos < len(src) && src[pos] == '.' {
+		pos++
+		if pos >= len(src) || !isDigit(src[pos]) {
+			return s.fail(pos)
+		}
+		return typID, digits(src, pos), 0
+	}
+	if pos >= len(src) || !isDigit(src[pos]) {
+		return s.fail(pos)
+	}
+	pos = digits(src, pos)
+	if pos < len(src) && src[pos] == '.' {
+		pos = digits(src, pos+1)
+	}
+	return typID, pos, 0
+}
+
+// isDigit reports whether c is a decimal digit.
+func isDigit(c byte) bool {
+	return c < utf8.RuneSelf && classes[c]&classDigit != 0
+}
+
+// digits returns the end offset of the decimal digits starting at pos.
+func digits(src []byte, pos int) int {
+	for pos < len(src) && isDigit(src[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// quoted scans the remainder of a double-quoted string, starting at pos. The
+// dyad \" is an escaped double quote, and backslashes escape any character.
+// NUL characters and U+FFFD are invalid, as by the DOT grammar.
+func (s *Scanner) quoted(pos int) (typ token.Type, end, size int) {
+	src := s.src
+	for pos < len(src) {
+		switch src[pos] {
+		case '"':
+			return typID, pos + 1, 0
+		case '\\':
+			pos++
+			if pos < len(src) && (src[pos] == '"' || src[pos] == '\\') {
+				pos++
+				continue
+			}
+		}
+		// Plain or escaped character.
+		if pos >= len(src) {
+			break
+		}
+		if c := src[pos]; c < utf8.RuneSelf {
+			if c == 0 {
+				return s.fail(pos)
+			}
+			pos++
+			continue
+		}
+		r, n := utf8.DecodeRune(src[pos:])
+		if r == utf8.RuneError {
+			return s.fail(pos)
+		}
+		pos += n
+	}
+	return s.fail(pos)
+}
+
+// html scans the remainder of an HTML string, starting at pos. HTML strings
+// contain text and non-empty tags, and runes up to U+00FF except NUL, as by
+// the DOT grammar; as recognized by the gocc lexer.
+//
+//    html : '<' { text | '<' char text '>' } '>'
+func (s *Scanner) html(pos int) (typ token.Type, end, size int) {
+	src := s.src
+	// Offset of the current tag; or -1 if outside of tag.
+	tag := -1
+	for pos < len(src) {
+		c := src[pos]
+		switch {
+		case c == '>':
+			if tag == -1 {
+				return typID, pos + 1, 0
+			}
+			if tag == pos-1 {
+				// Empty tag.
+				return s.fail(pos)
+			}
+			tag = -1
+			pos++
+		case c == '<':
+			if tag != -1 {
+				return s.fail(pos)
+			}
+			tag = pos
+			pos++
+		case c == 0:
+			return s.fail(pos)
+		case c < utf8.RuneSelf:
+			pos++
+		default:
+			r, n := utf8.DecodeRune(src[pos:])
+			if r > 0xFF {
+				return s.fail(pos)
+			}
+			pos += n
+		}
+	}
+	return s.fail(pos)
+}
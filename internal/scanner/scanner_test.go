@@ -0,0 +1,213 @@
+package scanner_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/graphism/dot/internal/lexer"
+	"github.com/graphism/dot/internal/scanner"
+	"github.com/graphism/dot/internal/token"
+)
+
+func TestScan(t *testing.T) {
+	golden := []string{
+		"",
+		"  \t\r\n",
+		"graph G { a -- b }",
+		"Node NODE nOde node1 Digraph diGraph DIGRAPH diGRAPH SubGraph subGRAPH Strict sTrict",
+		"a -> b; c:nw -- d:se [x=1, y=2]",
+		"-20 .10 3.14 1. -.5 - -. -x .x 1.2.3 --- -->",
+		`"foo" "a\"b" "a\\" "line\` + "\n" + `break" "unterminated`,
+		"\"nul\x00\" \"bad\xff\" \"esc\\\x00\"",
+		"<<b>x</b>> <a<b>c> <<a<b>>> <unterminated",
+		"<é> <€> <\xff>",
+		"# preprocessor\n/* block */ // line\na",
+		"/* unterminated",
+		"// unterminated",
+		"# unterminated",
+		"/x / @ $ \x00 é€ \xff \xef\xbf\xbd",
+		"a/**/b /* ** */ c /*/ d */ e",
+		"\ta\r\tb\n\t\tc",
+	}
+	for _, in := range golden {
+		compare(t, in, []byte(in))
+	}
+}
+
+func TestScanCorpus(t *testing.T) {
+	// Tokenize all files of testdata directories.
+	for _, path := range testdata(t) {
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", path, err)
+			continue
+		}
+		compare(t, path, buf)
+	}
+}
+
+func TestScanReader(t *testing.T) {
+	// Tokenize all files of testdata directories, reading one byte at a time.
+	for _, path := range testdata(t) {
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", path, err)
+			continue
+		}
+		compareReader(t, path, buf)
+	}
+}
+
+func TestScanReaderError(t *testing.T) {
+	r := iotest.TimeoutReader(strings.NewReader("graph G { a -- b }"))
+	s := scanner.NewReader(iotest.OneByteReader(r))
+	for s.Scan().Type != token.EOF {
+	}
+	if s.Err() != iotest.ErrTimeout {
+		t.Errorf("read error mismatch; expected %v, got %v", iotest.ErrTimeout, s.Err())
+	}
+}
+
+func TestComments(t *testing.T) {
+	golden := []struct {
+		in   string
+		want []string
+	}{
+		{in: "# a\n/* b */ c // d\n", want: []string{"# a\n", "/* b */", "// d\n"}},
+		// Comment delimiters of IDs are not comments.
+		{in: `a [label="// b" URL="http://c/*d*/"] <e//f>`, want: nil},
+		// Unterminated comments are invalid tokens.
+		{in: "a // b", want: nil},
+	}
+	for _, g := range golden {
+		var got []string
+		for _, comment := range scanner.Comments([]byte(g.in)) {
+			got = append(got, string(comment))
+		}
+		if strings.Join(got, "|") != strings.Join(g.want, "|") || len(got) != len(g.want) {
+			t.Errorf("%q: comments mismatch; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+}
+
+// FuzzScan verifies that the scanner produces the tokens of the gocc lexer,
+// also when reading one byte at a time.
+//
+// The corpus is seeded from the files of testdata directories.
+func FuzzScan(f *testing.F) {
+	for _, path := range testdata(f) {
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf)
+	}
+	f.Fuzz(func(t *testing.T, src []byte) {
+		compare(t, string(src), src)
+		compareReader(t, string(src), src)
+	})
+}
+
+// testdata returns the paths of all files of testdata directories.
+func testdata(t testing.TB) []string {
+	var paths []string
+	walk := func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && filepath.Base(filepath.Dir(path)) == "testdata" {
+			paths = append(paths, path)
+		}
+		return nil
+	}
+	if err := filepath.Walk("../..", walk); err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("empty testdata corpus")
+	}
+	return paths
+}
+
+// compare reports mismatches between the tokens of the scanner and the gocc
+// lexer for the given input.
+func compare(t *testing.T, name string, src []byte) {
+	s := scanner.New(src)
+	l := lexer.NewLexer(src)
+	for i := 0; ; i++ {
+		got, want := s.Scan(), l.Scan()
+		if got.Type != want.Type || string(got.Lit) != string(want.Lit) || got.Pos != want.Pos {
+			t.Errorf("%q: token %d mismatch; expected %s at %v, got %s at %v", name, i, token.TokMap.TokenString(want), want.Pos, token.TokMap.TokenString(got), got.Pos)
+			return
+		}
+		if want.Type == token.EOF {
+			return
+		}
+	}
+}
+
+// compareReader reports mismatches between the tokens of the scanner of the
+// given input, and of a scanner reading the input one byte at a time.
+func compareReader(t *testing.T, name string, src []byte) {
+	want := scanner.New(src)
+	got := scanner.NewReader(iotest.OneByteReader(bytes.NewReader(src)))
+	for i := 0; ; i++ {
+		g, w := got.Scan(), want.Scan()
+		if g.Type != w.Type || string(g.Lit) != string(w.Lit) || g.Pos != w.Pos {
+			t.Errorf("%q: token %d mismatch; expected %s at %v, got %s at %v", name, i, token.TokMap.TokenString(w), w.Pos, token.TokMap.TokenString(g), g.Pos)
+			return
+		}
+		if w.Type == token.EOF {
+			return
+		}
+	}
+}
+
+func BenchmarkScanner(b *testing.B) {
+	src := corpus(b)
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s := scanner.New(src)
+		for s.Scan().Type != token.EOF {
+		}
+	}
+}
+
+func BenchmarkLexer(b *testing.B) {
+	src := corpus(b)
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l := lexer.NewLexer(src)
+		for l.Scan().Type != token.EOF {
+		}
+	}
+}
+
+// corpus returns the DOT files of the internal testdata directory, repeatedly
+// concatenated up to 1 MB.
+func corpus(b *testing.B) []byte {
+	paths, err := filepath.Glob("../testdata/*.dot")
+	if err != nil {
+		b.Fatal(err)
+	}
+	var src []byte
+	for _, path := range paths {
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		src = append(src, buf...)
+		src = append(src, '\n')
+	}
+	for n := len(src); len(src) < 1<<20; {
+		src = append(src, src[:n]...)
+	}
+	return src
+}
@@ -1,53 +0,0 @@
-// generated by gocc; DO NOT EDIT.
-
-//Copyright 2013 Vastech SA (PTY) LTD
-//
-//   Licensed under the Apache License, Version 2.0 (the "License");
-//   you may not use this file except in compliance with the License.
-//   You may obtain a copy of the License at
-//
-//       http://www.apache.org/licenses/LICENSE-2.0
-//
-//   Unless required by applicable law or agreed to in writing, software
-//   distributed under the License is distributed on an "AS IS" BASIS,
-//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
-//   See the License for the specific language governing permissions and
-//   limitations under the License.
-
-package util
-
-import (
-	"fmt"
-)
-
-func RuneToString(r rune) string {
-	if r >= 0x20 && r < 0x7f {
-		return fmt.Sprintf("'%c'", r)
-	}
-	switch r {
-	case 0x07:
-		return "'\\a'"
-	case 0x08:
-		return "'\\b'"
-	case 0x0C:
-		return "'\\f'"
-	case 0x0A:
-		return "'\\n'"
-	case 0x0D:
-		return "'\\r'"
-	case 0x09:
-		return "'\\t'"
-	case 0x0b:
-		return "'\\v'"
-	case 0x5c:
-		return "'\\\\\\'"
-	case 0x27:
-		return "'\\''"
-	case 0x22:
-		return "'\\\"'"
-	}
-	if r < 0x10000 {
-		return fmt.Sprintf("\\u%04x", r)
-	}
-	return fmt.Sprintf("\\U%08x", r)
-}
@@ -0,0 +1,765 @@
+// Package json implements encoding and decoding of Graphviz DOT graphs in the
+// JSON format output by Graphviz for -Tjson0.
+//
+// Each graph is encoded as a JSON object, in which subgraphs and nodes are
+// stored in the "objects" array and edges in the "edges" array. Subgraphs
+// precede nodes in the "objects" array, and the index of an object or edge is
+// recorded in its "_gvid" member. Subgraphs refer to their nested subgraphs,
+// nodes and edges by index, and edges refer to their "tail" and "head" nodes by
+// index. Attributes are stored as string members of the graph, subgraph, node
+// and edge objects.
+//
+// ref: https://graphviz.org/docs/outputs/json/
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/internal/astx"
+	"github.com/graphism/dot/internal/enc"
+	"github.com/pkg/errors"
+)
+
+// === [ Encoding ] ============================================================
+
+// Marshal returns the JSON encoding of the graphs of the given DOT file. Each
+// graph is encoded as a separate JSON object, terminated by a newline.
+func Marshal(file *ast.File) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	for _, graph := range file.Graphs {
+		g, err := dot.Resolve(graph)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		b, err := MarshalGraph(g)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		buf.Write(b)
+		buf.WriteString("\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// MarshalGraph returns the JSON encoding of the given resolved graph.
+func MarshalGraph(g *dot.Graph) ([]byte, error) {
+	e := newEncoder(g)
+	buf, err := json.MarshalIndent(e.encodeGraph(), "", "  ")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return buf, nil
+}
+
+// An encoder tracks the object indices of subgraphs and nodes, and the edge
+// indices of edges.
+type encoder struct {
+	// Resolved graph.
+	g *dot.Graph
+	// Subgraphs in pre-order.
+	subs []*dot.Subgraph
+	// subIndex maps from subgraph to object index.
+	subIndex map[*dot.Subgraph]int
+	// nodeIndex maps from node to object index.
+	nodeIndex map[*dot.Node]int
+	// edgeIndex maps from edge to edge index.
+	edgeIndex map[*dot.Edge]int
+}
+
+// newEncoder returns a new encoder for the given resolved graph.
+func newEncoder(g *dot.Graph) *encoder {
+	e := &encoder{
+		g:         g,
+		subIndex:  make(map[*dot.Subgraph]int),
+		nodeIndex: make(map[*dot.Node]int),
+		edgeIndex: make(map[*dot.Edge]int),
+	}
+	var walk func(subs []*dot.Subgraph)
+	walk = func(subs []*dot.Subgraph) {
+		for _, sub := range subs {
+			e.subIndex[sub] = len(e.subs)
+			e.subs = append(e.subs, sub)
+			walk(sub.Subgraphs)
+		}
+	}
+	walk(g.Subgraphs)
+	for i, n := range g.Nodes {
+		e.nodeIndex[n] = len(e.subs) + i
+	}
+	for i, edge := range g.Edges {
+		e.edgeIndex[edge] = i
+	}
+	return e
+}
+
+// encodeGraph returns the JSON object of the graph.
+func (e *encoder) encodeGraph() object {
+	obj := object{
+		{key: "name", val: enc.Unquote(e.g.ID)},
+		{key: "directed", val: e.g.Directed},
+		{key: "strict", val: e.g.Strict},
+	}
+	obj = appendAttrs(obj, e.g.Attrs)
+	obj = append(obj, member{key: "_subgraph_cnt", val: len(e.subs)})
+	if len(e.g.Subgraphs) > 0 {
+		obj = append(obj, member{key: "subgraphs", val: e.subIndices(e.g.Subgraphs)})
+	}
+	var objects []object
+	for i, sub := range e.subs {
+		objects = append(objects, e.encodeSubgraph(i, sub))
+	}
+	for _, n := range e.g.Nodes {
+		objects = append(objects, e.encodeNode(n))
+	}
+	if len(objects) > 0 {
+		obj = append(obj, member{key: "objects", val: objects})
+	}
+	var edges []object
+	for _, edge := range e.g.Edges {
+		edges = append(edges, e.encodeEdge(edge))
+	}
+	if len(edges) > 0 {
+		obj = append(obj, member{key: "edges", val: edges})
+	}
+	return obj
+}
+
+// encodeSubgraph returns the JSON object of the given subgraph with the given
+// object index.
+func (e *encoder) encodeSubgraph(index int, sub *dot.Subgraph) object {
+	name := enc.Unquote(sub.ID)
+	if len(name) == 0 {
+		// Anonymous subgraphs are named "%N" by Graphviz.
+		name = "%" + strconv.Itoa(index)
+	}
+	obj := object{
+		{key: "_gvid", val: index},
+		{key: "name", val: name},
+	}
+	obj = appendAttrs(obj, sub.Attrs)
+	if len(sub.Subgraphs) > 0 {
+		obj = append(obj, member{key: "subgraphs", val: e.subIndices(sub.Subgraphs)})
+	}
+	if len(sub.Nodes) > 0 {
+		nodes := make([]int, len(sub.Nodes))
+		for i, n := range sub.Nodes {
+			nodes[i] = e.nodeIndex[n]
+		}
+		obj = append(obj, member{key: "nodes", val: nodes})
+	}
+	if len(sub.Edges) > 0 {
+		edges := make([]int, len(sub.Edges))
+		for i, edge := range sub.Edges {
+			edges[i] = e.edgeIndex[edge]
+		}
+		obj = append(obj, member{key: "edges", val: edges})
+	}
+	return obj
+}
+
+// encodeNode returns the JSON object of the given node.
+func (e *encoder) encodeNode(n *dot.Node) object {
+	obj := object{
+		{key: "_gvid", val: e.nodeIndex[n]},
+		{key: "name", val: enc.Unquote(n.ID)},
+	}
+	return appendAttrs(obj, n.Attrs)
+}
+
+// encodeEdge returns the JSON object of the given edge.
+func (e *encoder) encodeEdge(edge *dot.Edge) object {
+	obj := object{
+		{key: "_gvid", val: e.edgeIndex[edge]},
+		{key: "tail", val: e.nodeIndex[edge.From]},
+		{key: "head", val: e.nodeIndex[edge.To]},
+	}
+	attrs := edge.Attrs
+	// Ports are stored as tailport and headport attributes by Graphviz.
+	if edge.FromPort != nil {
+		attrs = append(attrs[:len(attrs):len(attrs)], &ast.Attr{Key: "tailport", Val: portString(edge.FromPort)})
+	}
+	if edge.ToPort != nil {
+		attrs = append(attrs[:len(attrs):len(attrs)], &ast.Attr{Key: "headport", Val: portString(edge.ToPort)})
+	}
+	return appendAttrs(obj, attrs)
+}
+
+// subIndices returns the object indices of the given subgraphs.
+func (e *encoder) subIndices(subs []*dot.Subgraph) []int {
+	indices := make([]int, len(subs))
+	for i, sub := range subs {
+		indices[i] = e.subIndex[sub]
+	}
+	return indices
+}
+
+// appendAttrs appends the given attributes to the JSON object, with unquoted
+// keys and values.
+func appendAttrs(obj object, attrs []*ast.Attr) object {
+	for _, attr := range attrs {
+		obj = append(obj, member{key: enc.Unquote(attr.Key), val: enc.Unquote(attr.Val)})
+	}
+	return obj
+}
+
+// portString returns the unquoted string representation of the given port,
+// without leading colon.
+func portString(port *ast.Port) string {
+	s := enc.Unquote(port.ID)
+	if port.CompassPoint != ast.CompassPointDefault {
+		if len(s) > 0 {
+			s += ":"
+		}
+		s += port.CompassPoint.String()
+	}
+	return s
+}
+
+// === [ Decoding ] ============================================================
+
+// Unmarshal parses the JSON-encoded data of one or more graphs into a DOT file.
+func Unmarshal(data []byte) (*ast.File, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	file := &ast.File{}
+	for {
+		v, err := readValue(dec)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		obj, ok := v.(object)
+		if !ok {
+			return nil, errors.Errorf("invalid graph type; expected JSON object, got %T", v)
+		}
+		graph, err := decodeGraph(obj)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		file.Graphs = append(file.Graphs, graph)
+	}
+	if len(file.Graphs) == 0 {
+		return nil, errors.New("invalid JSON input; no graphs found")
+	}
+	return file, nil
+}
+
+// A decoder tracks the subgraph, node and edge objects of a graph.
+type decoder struct {
+	// Subgraph objects.
+	subs []object
+	// Node IDs, indexed by object index.
+	nodes map[int]string
+	// Edge objects.
+	edges []object
+	// Edges already placed in a subgraph.
+	done map[int]bool
+}
+
+// decodeGraph decodes the JSON object of a graph into a DOT graph.
+func decodeGraph(obj object) (*ast.Graph, error) {
+	graph := &ast.Graph{}
+	var err error
+	if graph.ID, err = getName(obj); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if graph.Directed, err = getBool(obj, "directed"); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if graph.Strict, err = getBool(obj, "strict"); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	objects, err := getObjects(obj, "objects")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	subCount, err := getInt(obj, "_subgraph_cnt")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if subCount > len(objects) {
+		return nil, errors.Errorf("invalid subgraph count; %d exceeds the number of objects (%d)", subCount, len(objects))
+	}
+	d := &decoder{
+		subs:  objects[:subCount],
+		nodes: make(map[int]string),
+		done:  make(map[int]bool),
+	}
+	if d.edges, err = getObjects(obj, "edges"); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// Graph attributes.
+	attrs, err := getAttrs(obj)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(attrs) > 0 {
+		graph.Stmts = append(graph.Stmts, &ast.AttrStmt{Kind: ast.KindGraph, Attrs: attrs})
+	}
+	// Nodes.
+	for i, o := range objects[subCount:] {
+		id, err := getName(o)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if len(id) == 0 {
+			return nil, errors.Errorf("invalid node object %d; missing name", subCount+i)
+		}
+		d.nodes[subCount+i] = id
+		attrs, err := getAttrs(o)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		graph.Stmts = append(graph.Stmts, &ast.NodeStmt{Node: &ast.Node{ID: id}, Attrs: attrs})
+	}
+	// Subgraphs.
+	var roots []int
+	if _, ok := obj.get("subgraphs"); ok {
+		if roots, err = getInts(obj, "subgraphs"); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	} else {
+		// Locate top-level subgraphs, as not referenced by any other subgraph.
+		nested := make(map[int]bool)
+		for _, sub := range d.subs {
+			children, err := getInts(sub, "subgraphs")
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			for _, child := range children {
+				nested[child] = true
+			}
+		}
+		for i := range d.subs {
+			if !nested[i] {
+				roots = append(roots, i)
+			}
+		}
+	}
+	for _, index := range roots {
+		sub, err := d.decodeSubgraph(graph.Directed, index, 0)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		graph.Stmts = append(graph.Stmts, sub)
+	}
+	// Edges.
+	for i, o := range d.edges {
+		if d.done[i] {
+			continue
+		}
+		e, err := d.decodeEdge(graph.Directed, o)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		graph.Stmts = append(graph.Stmts, e)
+	}
+	return graph, nil
+}
+
+// decodeSubgraph decodes the subgraph object with the given index into a DOT
+// subgraph.
+func (d *decoder) decodeSubgraph(directed bool, index, depth int) (*ast.Subgraph, error) {
+	if index < 0 || index >= len(d.subs) {
+		return nil, errors.Errorf("invalid subgraph index %d; expected < %d", index, len(d.subs))
+	}
+	// Guard against cyclic subgraph references.
+	if depth > len(d.subs) {
+		return nil, errors.Errorf("invalid subgraph %d; cyclic subgraph reference", index)
+	}
+	obj := d.subs[index]
+	id, err := getName(obj)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if strings.HasPrefix(enc.Unquote(id), "%") {
+		// Anonymous subgraph.
+		id = ""
+	}
+	sub := &ast.Subgraph{ID: id}
+	attrs, err := getAttrs(obj)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(attrs) > 0 {
+		sub.Stmts = append(sub.Stmts, &ast.AttrStmt{Kind: ast.KindGraph, Attrs: attrs})
+	}
+	children, err := getInts(obj, "subgraphs")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// Nodes of nested subgraphs are declared in the nested subgraphs.
+	nested := make(map[int]bool)
+	for _, child := range children {
+		if child < 0 || child >= len(d.subs) {
+			return nil, errors.Errorf("invalid subgraph index %d; expected < %d", child, len(d.subs))
+		}
+		nodes, err := getInts(d.subs[child], "nodes")
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, n := range nodes {
+			nested[n] = true
+		}
+	}
+	nodes, err := getInts(obj, "nodes")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, n := range nodes {
+		if nested[n] {
+			continue
+		}
+		id, ok := d.nodes[n]
+		if !ok {
+			return nil, errors.Errorf("invalid node index %d of subgraph %d", n, index)
+		}
+		sub.Stmts = append(sub.Stmts, &ast.NodeStmt{Node: &ast.Node{ID: id}})
+	}
+	for _, child := range children {
+		s, err := d.decodeSubgraph(directed, child, depth+1)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		sub.Stmts = append(sub.Stmts, s)
+	}
+	edges, err := getInts(obj, "edges")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, i := range edges {
+		if i < 0 || i >= len(d.edges) {
+			return nil, errors.Errorf("invalid edge index %d of subgraph %d", i, index)
+		}
+		if d.done[i] {
+			continue
+		}
+		e, err := d.decodeEdge(directed, d.edges[i])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		sub.Stmts = append(sub.Stmts, e)
+		d.done[i] = true
+	}
+	return sub, nil
+}
+
+// decodeEdge decodes the given edge object into a DOT edge statement.
+func (d *decoder) decodeEdge(directed bool, obj object) (*ast.EdgeStmt, error) {
+	tail, err := d.getNode(obj, "tail")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	head, err := d.getNode(obj, "head")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	objAttrs, err := getAttrs(obj)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var attrs []*ast.Attr
+	for _, attr := range objAttrs {
+		switch enc.Unquote(attr.Key) {
+		case "tailport":
+			if tail.Port, err = parsePort(attr.Val); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		case "headport":
+			if head.Port, err = parsePort(attr.Val); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		default:
+			attrs = append(attrs, attr)
+		}
+	}
+	e := &ast.EdgeStmt{
+		From:  tail,
+		To:    &ast.Edge{Directed: directed, Vertex: head},
+		Attrs: attrs,
+	}
+	return e, nil
+}
+
+// getNode returns a DOT node of the node object referred to by the given key
+// of the edge object.
+func (d *decoder) getNode(obj object, key string) (*ast.Node, error) {
+	index, err := getInt(obj, key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	id, ok := d.nodes[index]
+	if !ok {
+		return nil, errors.Errorf("invalid %s node index %d", key, index)
+	}
+	return &ast.Node{ID: id}, nil
+}
+
+// parsePort parses the given tailport or headport attribute value into a DOT
+// port.
+func parsePort(val string) (*ast.Port, error) {
+	s := enc.Unquote(val)
+	if pos := strings.LastIndex(s, ":"); pos != -1 {
+		return astx.NewPort(enc.Quote(s[:pos]), s[pos+1:])
+	}
+	return astx.NewPort(enc.Quote(s), nil)
+}
+
+// reservedKeys specifies the members of JSON objects which are not attributes.
+var reservedKeys = map[string]bool{
+	"_gvid":         true,
+	"_subgraph_cnt": true,
+	"directed":      true,
+	"edges":         true,
+	"head":          true,
+	"name":          true,
+	"nodes":         true,
+	"objects":       true,
+	"strict":        true,
+	"subgraphs":     true,
+	"tail":          true,
+}
+
+// getAttrs returns the attributes of the given JSON object. Members with
+// non-string values, such as the xdot drawing operations output by Graphviz for
+// -Tjson, are ignored.
+func getAttrs(obj object) ([]*ast.Attr, error) {
+	var attrs []*ast.Attr
+	for _, m := range obj {
+		if reservedKeys[m.key] {
+			continue
+		}
+		var val string
+		switch v := m.val.(type) {
+		case string:
+			val = v
+		case json.Number:
+			val = v.String()
+		default:
+			continue
+		}
+		if !enc.IsText(m.key) {
+			return nil, errors.Errorf("invalid attribute %q; contains NUL, U+FFFD or invalid UTF-8", m.key)
+		}
+		if !enc.IsText(val) {
+			return nil, errors.Errorf("invalid %q value %q; contains NUL, U+FFFD or invalid UTF-8", m.key, val)
+		}
+		attrs = append(attrs, &ast.Attr{Key: enc.Quote(m.key), Val: enc.Quote(val)})
+	}
+	return attrs, nil
+}
+
+// getName returns the quoted name of the given JSON object; or an empty string
+// if not present.
+func getName(obj object) (string, error) {
+	v, ok := obj.get("name")
+	if !ok {
+		return "", nil
+	}
+	name, ok := v.(string)
+	if !ok {
+		return "", errors.Errorf(`invalid "name" type; expected string, got %T`, v)
+	}
+	if len(name) == 0 {
+		return "", nil
+	}
+	if !enc.IsText(name) {
+		return "", errors.Errorf("invalid name %q; contains NUL, U+FFFD or invalid UTF-8", name)
+	}
+	return enc.Quote(name), nil
+}
+
+// getBool returns the boolean value of the given key of the JSON object; or
+// false if not present.
+func getBool(obj object, key string) (bool, error) {
+	v, ok := obj.get(key)
+	if !ok {
+		return false, nil
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, errors.Errorf("invalid %q type; expected bool, got %T", key, v)
+	}
+	return b, nil
+}
+
+// getInt returns the integer value of the given key of the JSON object; or 0 if
+// not present.
+func getInt(obj object, key string) (int, error) {
+	v, ok := obj.get(key)
+	if !ok {
+		return 0, nil
+	}
+	return toInt(key, v)
+}
+
+// getInts returns the integer array value of the given key of the JSON object;
+// or nil if not present.
+func getInts(obj object, key string) ([]int, error) {
+	v, ok := obj.get(key)
+	if !ok {
+		return nil, nil
+	}
+	vs, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.Errorf("invalid %q type; expected array, got %T", key, v)
+	}
+	var xs []int
+	for _, v := range vs {
+		x, err := toInt(key, v)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		xs = append(xs, x)
+	}
+	return xs, nil
+}
+
+// getObjects returns the object array value of the given key of the JSON
+// object; or nil if not present.
+func getObjects(obj object, key string) ([]object, error) {
+	v, ok := obj.get(key)
+	if !ok {
+		return nil, nil
+	}
+	vs, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.Errorf("invalid %q type; expected array, got %T", key, v)
+	}
+	var objs []object
+	for _, v := range vs {
+		o, ok := v.(object)
+		if !ok {
+			return nil, errors.Errorf("invalid %q element type; expected object, got %T", key, v)
+		}
+		objs = append(objs, o)
+	}
+	return objs, nil
+}
+
+// toInt returns the integer value of v, as stored in the given key.
+func toInt(key string, v interface{}) (int, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, errors.Errorf("invalid %q type; expected number, got %T", key, v)
+	}
+	x, err := strconv.Atoi(n.String())
+	if err != nil {
+		return 0, errors.Errorf("invalid %q value; %v", key, err)
+	}
+	return x, nil
+}
+
+// === [ JSON objects ] ========================================================
+
+// An object is a JSON object which preserves the order of its members.
+type object []member
+
+// A member is a key-value pair of a JSON object.
+type member struct {
+	// Member key.
+	key string
+	// Member value.
+	val interface{}
+}
+
+// get returns the value of the member with the given key, and a boolean value
+// indicating if such a member exists.
+func (obj object) get(key string) (interface{}, bool) {
+	for _, m := range obj {
+		if m.key == key {
+			return m.val, true
+		}
+	}
+	return nil, false
+}
+
+// MarshalJSON returns the JSON encoding of the object, with members in order.
+func (obj object) MarshalJSON() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteString("{")
+	for i, m := range obj {
+		if i != 0 {
+			buf.WriteString(",")
+		}
+		key, err := json.Marshal(m.key)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		val, err := json.Marshal(m.val)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		buf.Write(key)
+		buf.WriteString(":")
+		buf.Write(val)
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+// readValue reads the next JSON value from dec, preserving the order of object
+// members. Objects are returned as object, and arrays as []interface{}.
+func readValue(dec *json.Decoder) (interface{}, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case json.Delim('{'):
+		var obj object
+		for dec.More() {
+			k, err := dec.Token()
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, errors.Errorf("invalid object key type; expected string, got %T", k)
+			}
+			val, err := readValue(dec)
+			if err != nil {
+				return nil, errors.WithStack(noEOF(err))
+			}
+			obj = append(obj, member{key: key, val: val})
+		}
+		// Consume '}'.
+		if _, err := dec.Token(); err != nil {
+			return nil, errors.WithStack(noEOF(err))
+		}
+		return obj, nil
+	case json.Delim('['):
+		vs := []interface{}{}
+		for dec.More() {
+			v, err := readValue(dec)
+			if err != nil {
+				return nil, errors.WithStack(noEOF(err))
+			}
+			vs = append(vs, v)
+		}
+		// Consume ']'.
+		if _, err := dec.Token(); err != nil {
+			return nil, errors.WithStack(noEOF(err))
+		}
+		return vs, nil
+	default:
+		return t, nil
+	}
+}
+
+// noEOF returns io.ErrUnexpectedEOF if err is io.EOF; and err otherwise.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
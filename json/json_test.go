@@ -0,0 +1,148 @@
+package json_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/json"
+)
+
+func TestMarshal(t *testing.T) {
+	golden := []struct {
+		in  string
+		out string
+	}{
+		{
+			in:  "testdata/cluster.dot",
+			out: "testdata/cluster.json",
+		},
+		{
+			in:  "testdata/strict.dot",
+			out: "testdata/strict.json",
+		},
+	}
+	for _, g := range golden {
+		file, err := dot.ParseFile(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", g.in, err)
+			continue
+		}
+		buf, err := json.Marshal(file)
+		if err != nil {
+			t.Errorf("%q: unable to encode file; %v", g.in, err)
+			continue
+		}
+		want, err := ioutil.ReadFile(g.out)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", g.in, err)
+			continue
+		}
+		got := string(bytes.TrimSpace(buf))
+		if got != string(bytes.TrimSpace(want)) {
+			t.Errorf("%q: JSON mismatch; expected `%s`, got `%s`", g.in, want, got)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	golden := []struct {
+		in  string
+		out string
+	}{
+		{
+			in:  "testdata/cluster.json",
+			out: "testdata/cluster.golden",
+		},
+	}
+	for _, g := range golden {
+		buf, err := ioutil.ReadFile(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", g.in, err)
+			continue
+		}
+		file, err := json.Unmarshal(buf)
+		if err != nil {
+			t.Errorf("%q: unable to decode file; %v", g.in, err)
+			continue
+		}
+		want, err := ioutil.ReadFile(g.out)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", g.in, err)
+			continue
+		}
+		got := file.String()
+		if got != string(bytes.TrimSpace(want)) {
+			t.Errorf("%q: graph mismatch; expected `%s`, got `%s`", g.in, want, got)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	paths := []string{
+		"../internal/testdata/attr.dot",
+		"../internal/testdata/attr_lists.dot",
+		"../internal/testdata/attr_stmt.dot",
+		"../internal/testdata/edge_stmt.dot",
+		"../internal/testdata/multi.dot",
+		"../internal/testdata/port.dot",
+		"../internal/testdata/quoted_id.dot",
+		"../internal/testdata/subgraph.dot",
+		"../internal/testdata/subgraph_vertex.dot",
+		"testdata/cluster.dot",
+		"testdata/strict.dot",
+	}
+	for _, path := range paths {
+		file, err := dot.ParseFile(path)
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", path, err)
+			continue
+		}
+		want, err := json.Marshal(file)
+		if err != nil {
+			t.Errorf("%q: unable to encode file; %v", path, err)
+			continue
+		}
+		// DOT -> JSON -> DOT -> JSON.
+		f, err := json.Unmarshal(want)
+		if err != nil {
+			t.Errorf("%q: unable to decode file; %v", path, err)
+			continue
+		}
+		f, err = dot.ParseString(f.String())
+		if err != nil {
+			t.Errorf("%q: unable to parse decoded file; %v", path, err)
+			continue
+		}
+		got, err := json.Marshal(f)
+		if err != nil {
+			t.Errorf("%q: unable to encode decoded file; %v", path, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%q: JSON mismatch after round trip; expected `%s`, got `%s`", path, want, got)
+		}
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		// Invalid UTF-8 is decoded as U+FFFD, which is invalid in DOT IDs.
+		{in: "{\"objects\":[{\"name\":\"0\",\"\xff\":0}]}", want: `invalid attribute "�"; contains NUL, U+FFFD or invalid UTF-8`},
+		{in: `{"objects":[{"name":"\u0000"}]}`, want: `invalid name "\x00"; contains NUL, U+FFFD or invalid UTF-8`},
+	}
+	for _, g := range golden {
+		_, err := json.Unmarshal([]byte(g.in))
+		if err == nil {
+			t.Errorf("%q: expected error, got nil", g.in)
+			continue
+		}
+		if got := err.Error(); got != g.want {
+			t.Errorf("%q: error mismatch; expected `%v`, got `%v`", g.in, g.want, got)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package dot
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/graphism/dot/htmllabel"
+	"github.com/graphism/dot/internal/enc"
+	"github.com/graphism/dot/record"
+)
+
+// === [ Labels ] ==============================================================
+
+// A Line is a line of expanded label text.
+type Line struct {
+	// Line text.
+	Text string
+	// Justification of the line.
+	Just Justification
+}
+
+// Justification specifies the justification of a line of label text.
+type Justification uint8
+
+// Justifications.
+const (
+	JustCenter Justification = iota // \n
+	JustLeft                        // \l
+	JustRight                       // \r
+)
+
+// String returns the string representation of the justification.
+func (just Justification) String() string {
+	switch just {
+	case JustCenter:
+		return "center"
+	case JustLeft:
+		return "left"
+	case JustRight:
+		return "right"
+	}
+	return "unknown"
+}
+
+// Label returns the lines of the expanded label of the graph; or nil if the
+// graph has no label. \G expands to the graph ID.
+func (g *Graph) Label() []Line {
+	label, ok := g.Attrs.Get("label")
+	if !ok {
+		return nil
+	}
+	return expandLabel(label, map[byte]string{'G': enc.Unquote(g.ID)})
+}
+
+// SubgraphLabel returns the lines of the expanded label of the given subgraph;
+// or nil if the subgraph has no label. \G expands to the subgraph ID.
+func (g *Graph) SubgraphLabel(sub *Subgraph) []Line {
+	label, ok := sub.Attrs.Get("label")
+	if !ok {
+		return nil
+	}
+	return expandLabel(label, map[byte]string{'G': enc.Unquote(sub.ID)})
+}
+
+// NodeLabel returns the lines of the expanded label of the given node. The
+// label defaults to \N, which expands to the node ID, and \G expands to the
+// graph ID. The text fields of record labels are expanded in pre-order, each
+// starting a new line.
+func (g *Graph) NodeLabel(n *Node) []Line {
+	label, ok := n.Attrs.Get("label")
+	if !ok {
+		label = `\N`
+	}
+	names := map[byte]string{
+		'G': enc.Unquote(g.ID),
+		'N': enc.Unquote(n.ID),
+	}
+	if r, err := n.Record(); err == nil && r != nil {
+		var lines []Line
+		for _, text := range recordTexts(r.Fields) {
+			lines = append(lines, expand(text, names)...)
+		}
+		return lines
+	}
+	return expandLabel(label, names)
+}
+
+// EdgeLabel returns the lines of the expanded label of the given edge; or nil
+// if the edge has no label. \T and \H expand to the IDs of the tail and head
+// nodes, \E to the tail and head IDs joined by the edge operator, and \G to
+// the graph ID.
+func (g *Graph) EdgeLabel(e *Edge) []Line {
+	label, ok := e.Attrs.Get("label")
+	if !ok {
+		return nil
+	}
+	op := "--"
+	if g.Directed {
+		op = "->"
+	}
+	tail, head := enc.Unquote(e.From.ID), enc.Unquote(e.To.ID)
+	names := map[byte]string{
+		'E': tail + op + head,
+		'G': enc.Unquote(g.ID),
+		'H': head,
+		'T': tail,
+	}
+	return expandLabel(label, names)
+}
+
+// expandLabel returns the lines of the given label, with escape sequences
+// expanded using the given names. The escape sequences of HTML-like labels are
+// not expanded; instead, their lines are separated by <BR/> elements and table
+// cells.
+func expandLabel(label string, names map[byte]string) []Line {
+	if enc.IsHTML(label) {
+		return htmlLines(label)
+	}
+	return expand(enc.Unquote(label), names)
+}
+
+// expand returns the lines of the given escape string, with escape sequences
+// expanded using the given names. Lines end at \n, \l and \r escape sequences,
+// which specify their justification, and at newline characters. Escape
+// sequences without name are expanded to the empty string, and \L is removed.
+// Any other character following a backslash is kept as is.
+func expand(s string, names map[byte]string) []Line {
+	var lines []Line
+	buf := &bytes.Buffer{}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\n' {
+			lines = append(lines, Line{Text: buf.String(), Just: JustCenter})
+			buf.Reset()
+			continue
+		}
+		if c != '\\' || i+1 == len(s) {
+			buf.WriteByte(c)
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'n':
+			lines = append(lines, Line{Text: buf.String(), Just: JustCenter})
+			buf.Reset()
+		case 'l':
+			lines = append(lines, Line{Text: buf.String(), Just: JustLeft})
+			buf.Reset()
+		case 'r':
+			lines = append(lines, Line{Text: buf.String(), Just: JustRight})
+			buf.Reset()
+		case 'E', 'G', 'H', 'N', 'T':
+			buf.WriteString(names[c])
+		case 'L':
+			// Object-dependent label; not applicable to labels themselves.
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	if buf.Len() > 0 {
+		lines = append(lines, Line{Text: buf.String(), Just: JustCenter})
+	}
+	return lines
+}
+
+// recordTexts returns the text of the given record fields, and their nested
+// fields, in pre-order.
+func recordTexts(fields []*record.Field) []string {
+	var texts []string
+	for _, f := range fields {
+		if f.Fields != nil {
+			texts = append(texts, recordTexts(f.Fields)...)
+			continue
+		}
+		texts = append(texts, f.Text)
+	}
+	return texts
+}
+
+// htmlLines returns the lines of the given HTML-like label. Lines end at <BR/>
+// elements, which specify their justification, and at the end of table cells.
+// Malformed labels are returned as is, on a single line.
+func htmlLines(label string) []Line {
+	l, err := htmllabel.Parse(label)
+	if err != nil {
+		return []Line{{Text: label[1 : len(label)-1], Just: JustCenter}}
+	}
+	var lines []Line
+	buf := &bytes.Buffer{}
+	flush := func(just Justification) {
+		lines = append(lines, Line{Text: buf.String(), Just: just})
+		buf.Reset()
+	}
+	var walk func(nodes []htmllabel.Node)
+	walk = func(nodes []htmllabel.Node) {
+		for _, n := range nodes {
+			switch n := n.(type) {
+			case *htmllabel.Text:
+				buf.WriteString(n.Text)
+			case *htmllabel.Element:
+				switch n.Kind {
+				case htmllabel.KindBR:
+					align, _ := n.Attr("ALIGN")
+					switch strings.ToUpper(align) {
+					case "LEFT":
+						flush(JustLeft)
+					case "RIGHT":
+						flush(JustRight)
+					default:
+						flush(JustCenter)
+					}
+				case htmllabel.KindTD:
+					walk(n.Children)
+					if buf.Len() > 0 {
+						flush(JustCenter)
+					}
+				default:
+					walk(n.Children)
+				}
+			}
+		}
+	}
+	walk(l.Nodes)
+	if buf.Len() > 0 {
+		flush(JustCenter)
+	}
+	return lines
+}
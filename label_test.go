@@ -0,0 +1,86 @@
+package dot_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/graphism/dot"
+)
+
+func TestLabel(t *testing.T) {
+	golden := []struct {
+		in string
+		// Graph component of the label; "graph", "subgraph", "node ID" or
+		// "edge INDEX".
+		of   string
+		want string
+	}{
+		// Graph labels; \N and \E have no name in graph labels.
+		{in: `digraph G { a }`, of: "graph", want: ""},
+		{in: `digraph G { label="graph \G" }`, of: "graph", want: "center:graph G"},
+		{in: `digraph "my graph" { label="\G\l\N\E" }`, of: "graph", want: "left:my graph"},
+		// Subgraph labels.
+		{in: `digraph G { subgraph s { a } }`, of: "subgraph", want: ""},
+		{in: `digraph G { subgraph "s 1" { label="\G in G"; a } }`, of: "subgraph", want: "center:s 1 in G"},
+		// Node labels; default \N.
+		{in: `digraph G { a }`, of: "node a", want: "center:a"},
+		{in: `digraph G { "x y" [label="\N of \G"] }`, of: "node x y", want: "center:x y of G"},
+		// Justification.
+		{in: `digraph G { a [label="left\lright\rcenter\nlast"] }`, of: "node a", want: "left:left|right:right|center:center|center:last"},
+		{in: "digraph G { a [label=\"one\ntwo\"] }", of: "node a", want: "center:one|center:two"},
+		// \L is removed, and other escaped characters are kept as is.
+		{in: `digraph G { a [label="a\Lb\xc\\d\"e"] }`, of: "node a", want: `center:abxc\d"e`},
+		// Trailing backslash.
+		{in: `digraph G { a [label="x\\"] }`, of: "node a", want: `center:x\`},
+		// Record labels.
+		{in: `digraph G { a [shape=record label="<f0> \N|{x\ly|<f1> \G}"] }`, of: "node a", want: "center:a|left:x|center:y|center:G"},
+		{in: `digraph G { node [shape=Mrecord]; b }`, of: "node b", want: "center:b"},
+		// HTML-like labels; escape sequences are not expanded.
+		{in: `digraph G { a [label=<x\N<BR ALIGN="LEFT"/>y<BR align="right"/>z<BR/>w>] }`, of: "node a", want: `left:x\N|right:y|center:z|center:w`},
+		{in: `digraph G { a [label=<<TABLE><TR><TD>one</TD><TD>two<BR/>three</TD><TD></TD></TR></TABLE>>] }`, of: "node a", want: "center:one|center:two|center:three"},
+		{in: `digraph G { a [label=<<B>bold</I>>] }`, of: "node a", want: "center:<B>bold</I>"},
+		// Edge labels.
+		{in: `digraph G { a -> b }`, of: "edge 0", want: ""},
+		{in: `digraph G { a -> b [label="\E: \T to \H in \G"] }`, of: "edge 0", want: "center:a->b: a to b in G"},
+		{in: `graph G { a -- "b c" [label="\E"] }`, of: "edge 0", want: "center:a--b c"},
+		{in: `graph G { edge [label="\N\E"]; a -- b -- c }`, of: "edge 1", want: "center:b--c"},
+	}
+	for _, g := range golden {
+		file, err := dot.ParseString(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", g.in, err)
+			continue
+		}
+		graph, err := dot.Resolve(file.Graphs[0])
+		if err != nil {
+			t.Errorf("%q: unable to resolve graph; %v", g.in, err)
+			continue
+		}
+		var lines []dot.Line
+		switch {
+		case g.of == "graph":
+			lines = graph.Label()
+		case g.of == "subgraph":
+			lines = graph.SubgraphLabel(graph.Subgraphs[0])
+		case strings.HasPrefix(g.of, "node "):
+			n, ok := graph.Node(strings.TrimPrefix(g.of, "node "))
+			if !ok {
+				t.Errorf("%q: unable to locate %s", g.in, g.of)
+				continue
+			}
+			lines = graph.NodeLabel(n)
+		case strings.HasPrefix(g.of, "edge "):
+			var i int
+			fmt.Sscanf(g.of, "edge %d", &i)
+			lines = graph.EdgeLabel(graph.Edges[i])
+		}
+		var got []string
+		for _, line := range lines {
+			got = append(got, fmt.Sprintf("%v:%s", line.Just, line.Text))
+		}
+		if s := strings.Join(got, "|"); s != g.want {
+			t.Errorf("%q: label of %s mismatch; expected `%s`, got `%s`", g.in, g.of, g.want, s)
+		}
+	}
+}
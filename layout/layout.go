@@ -0,0 +1,344 @@
+// Package layout implements a layered layout of Graphviz DOT graphs, in the
+// style of the Graphviz dot layout engine.
+//
+// The layout is computed in four phases, as described by Sugiyama et al.
+//
+//    1) Cycle removal; edges are reversed to make the graph acyclic.
+//    2) Rank assignment; nodes are assigned to ranks (layers), honouring the
+//       minlen edge attribute and the rank attribute of subgraphs.
+//    3) Crossing minimisation; nodes are ordered within each rank, using the
+//       barycenter heuristic.
+//    4) Coordinate assignment; nodes are positioned within each rank, and
+//       edges are routed as splines through the ranks they span.
+//
+// The result is recorded as attributes of the graph, in the same units and
+// format as the output of dot -Tdot; the pos, width and height attributes of
+// nodes, the pos and lp attributes of edges, and the bb and lp attributes of
+// the graph and its clusters.
+//
+// Clusters are not kept apart by the layout; the bounding box of a cluster is
+// the bounding box of its nodes.
+//
+// ref: Gansner et al. "A Technique for Drawing Directed Graphs", 1993.
+package layout
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/internal/enc"
+	"github.com/pkg/errors"
+)
+
+// Layout parameters, in points unless otherwise specified.
+const (
+	// Points per inch.
+	ppi = 72.0
+	// Default font size.
+	defaultFontSize = 14.0
+	// Default node width and height, in inches.
+	defaultWidth, defaultHeight = 0.75, 0.5
+	// Default minimum separation between nodes of the same rank, in inches.
+	defaultNodeSep = 0.25
+	// Default minimum separation between ranks, in inches.
+	defaultRankSep = 0.5
+	// Horizontal and vertical node label margins.
+	marginX, marginY = 0.11 * ppi, 0.055 * ppi
+	// Length of arrowheads.
+	arrowLength = 10.0
+	// Margin between clusters and their nodes.
+	clusterMargin = 8.0
+	// Number of crossing minimisation iterations.
+	orderIterations = 24
+	// Number of coordinate assignment iterations.
+	positionIterations = 8
+)
+
+// LayoutGraph returns a laid out copy of the given graph.
+func LayoutGraph(graph *ast.Graph) (*ast.Graph, error) {
+	g, err := dot.Resolve(graph)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := Layout(g); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return g.AST(), nil
+}
+
+// Layout computes a layered layout of the given graph, and records the
+// positions and sizes of its nodes, edges and clusters as attributes.
+func Layout(g *dot.Graph) error {
+	l, err := newLayout(g)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	l.rank()
+	l.buildLayers()
+	l.order()
+	l.position()
+	l.output()
+	return nil
+}
+
+// A layout tracks the state of a graph layout.
+type layout struct {
+	// Resolved graph.
+	g *dot.Graph
+	// Rank direction; TB, LR, BT or RL.
+	rankdir string
+	// Minimum separation between nodes of the same rank.
+	nodesep float64
+	// Minimum separation between ranks.
+	ranksep float64
+	// Nodes, in order of creation.
+	nodes []*node
+	// nodeOf maps from resolved node to layout node.
+	nodeOf map[*dot.Node]*node
+	// Edges, in order of creation.
+	edges []*edge
+	// Nodes of each rank, in order.
+	ranks [][]*node
+}
+
+// A node is a real or virtual node of the layout. Virtual nodes are inserted
+// for edges spanning multiple ranks.
+//
+// The layout is computed in a top-to-bottom frame, which is transformed
+// according to the rank direction of the graph when output.
+type node struct {
+	// Resolved node; or nil if virtual.
+	n *dot.Node
+	// Node shape.
+	shape string
+	// Size of the node within its rank and along the rank direction.
+	w, h float64
+	// Rank and order within rank.
+	rank, order int
+	// Position of the node.
+	x, y float64
+	// Neighbours in the preceding and succeeding ranks.
+	up, down []*node
+	// Weights of the edges to the neighbours.
+	upWeight, downWeight []float64
+}
+
+// An edge of the layout.
+type edge struct {
+	// Resolved edge.
+	e *dot.Edge
+	// Source and destination nodes.
+	from, to *node
+	// Virtual nodes of the edge, from source to destination.
+	chain []*node
+}
+
+// newLayout returns a new layout of the given graph.
+func newLayout(g *dot.Graph) (*layout, error) {
+	l := &layout{
+		g:       g,
+		rankdir: "TB",
+		nodesep: defaultNodeSep * ppi,
+		ranksep: defaultRankSep * ppi,
+		nodeOf:  make(map[*dot.Node]*node),
+	}
+	if v, ok := getAttr(g.Attrs, "rankdir"); ok {
+		switch dir := strings.ToUpper(v); dir {
+		case "TB", "LR", "BT", "RL":
+			l.rankdir = dir
+		default:
+			return nil, errors.Errorf("invalid rankdir %q; expected TB, LR, BT or RL", v)
+		}
+	}
+	if v, ok := getAttr(g.Attrs, "nodesep"); ok {
+		sep, err := parseFloat(v)
+		if err != nil {
+			return nil, errors.Errorf("invalid nodesep %q; %v", v, err)
+		}
+		l.nodesep = math.Max(sep, 0.02) * ppi
+	}
+	if v, ok := getAttr(g.Attrs, "ranksep"); ok {
+		// The ranksep attribute may be suffixed by "equally".
+		sep, err := parseFloat(strings.Fields(v + " ")[0])
+		if err != nil && !strings.HasPrefix(v, "equally") {
+			return nil, errors.Errorf("invalid ranksep %q; %v", v, err)
+		}
+		if err == nil {
+			l.ranksep = math.Max(sep, 0.02) * ppi
+		}
+	}
+	for _, n := range g.Nodes {
+		v := &node{n: n}
+		w, h, err := nodeSize(n)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		v.shape, _ = getAttr(n.Attrs, "shape")
+		v.shape = strings.ToLower(v.shape)
+		v.w, v.h = w, h
+		if l.flipped() {
+			v.w, v.h = h, w
+		}
+		l.nodes = append(l.nodes, v)
+		l.nodeOf[n] = v
+	}
+	for _, e := range g.Edges {
+		l.edges = append(l.edges, &edge{e: e, from: l.nodeOf[e.From], to: l.nodeOf[e.To]})
+	}
+	return l, nil
+}
+
+// flipped reports whether ranks are laid out horizontally.
+func (l *layout) flipped() bool {
+	return l.rankdir == "LR" || l.rankdir == "RL"
+}
+
+// nodeSize returns the width and height of the given node, in points.
+func nodeSize(n *dot.Node) (float64, float64, error) {
+	minW, minH := defaultWidth*ppi, defaultHeight*ppi
+	if v, ok := getAttr(n.Attrs, "width"); ok {
+		w, err := parseFloat(v)
+		if err != nil {
+			return 0, 0, errors.Errorf("invalid width %q of node %s; %v", v, n.ID, err)
+		}
+		minW = w * ppi
+	}
+	if v, ok := getAttr(n.Attrs, "height"); ok {
+		h, err := parseFloat(v)
+		if err != nil {
+			return 0, 0, errors.Errorf("invalid height %q of node %s; %v", v, n.ID, err)
+		}
+		minH = h * ppi
+	}
+	shape, _ := getAttr(n.Attrs, "shape")
+	shape = strings.ToLower(shape)
+	if shape == "point" {
+		w := math.Min(minW, minH)
+		if _, ok := getAttr(n.Attrs, "width"); !ok {
+			w = 0.05 * ppi
+		}
+		return w, w, nil
+	}
+	if v, _ := getAttr(n.Attrs, "fixedsize"); v == "true" || v == "shape" {
+		return minW, minH, nil
+	}
+	tw, th := labelSize(n.Attrs, enc.Unquote(n.ID))
+	w, h := tw+2*marginX, th+2*marginY
+	switch shape {
+	case "plain":
+		w, h = tw, th
+		minW, minH = 0, 0
+	case "", "ellipse", "oval", "circle", "doublecircle", "diamond", "mdiamond":
+		// Text is inscribed within the shape.
+		w, h = w*math.Sqrt2, h*math.Sqrt2
+	}
+	w, h = math.Max(w, minW), math.Max(h, minH)
+	switch shape {
+	case "circle", "doublecircle", "square", "mcircle", "msquare":
+		w = math.Max(w, h)
+		h = w
+	}
+	return w, h, nil
+}
+
+// labelSize returns the estimated width and height of the label text of the
+// given attributes, in points. The name is used for the \N escape sequence and
+// for nodes without label.
+func labelSize(attrs dot.Attrs, name string) (float64, float64) {
+	fontsize := defaultFontSize
+	if v, ok := getAttr(attrs, "fontsize"); ok {
+		if size, err := parseFloat(v); err == nil && size > 0 {
+			fontsize = size
+		}
+	}
+	label, ok := attrs.Get("label")
+	if !ok {
+		label = `\N`
+	}
+	var lines []string
+	if enc.IsHTML(label) {
+		// Approximate HTML-like labels by their text content.
+		lines = []string{stripTags(label)}
+	} else {
+		label = strings.Replace(enc.Unquote(label), `\N`, name, -1)
+		lines = splitLines(label)
+	}
+	width := 0
+	for _, line := range lines {
+		if n := len([]rune(line)); n > width {
+			width = n
+		}
+	}
+	// Average character width of proportional fonts, relative to font size.
+	const charWidth = 0.55
+	return float64(width) * fontsize * charWidth, float64(len(lines)) * fontsize * 1.2
+}
+
+// splitLines splits the given escape string into lines, at \n, \l and \r
+// escape sequences and newline characters.
+func splitLines(s string) []string {
+	var lines []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\n':
+			lines = append(lines, s[start:i])
+			start = i + 1
+		case s[i] == '\\' && i+1 < len(s):
+			switch s[i+1] {
+			case 'n', 'l', 'r':
+				lines = append(lines, s[start:i])
+				start = i + 2
+			}
+			i++
+		}
+	}
+	if start < len(s) || len(lines) == 0 {
+		lines = append(lines, s[start:])
+	}
+	return lines
+}
+
+// stripTags returns the text content of the given HTML-like label.
+func stripTags(s string) string {
+	buf := make([]rune, 0, len(s))
+	depth := 0
+	for _, r := range s[1 : len(s)-1] {
+		switch {
+		case r == '<':
+			depth++
+		case r == '>':
+			depth--
+		case depth == 0:
+			buf = append(buf, r)
+		}
+	}
+	return strings.TrimSpace(string(buf))
+}
+
+// getAttr returns the unquoted value of the attribute with the given key, and a
+// boolean value indicating if such an attribute exists.
+func getAttr(attrs dot.Attrs, key string) (string, bool) {
+	v, ok := attrs.Get(key)
+	return enc.Unquote(v), ok
+}
+
+// parseFloat parses the given floating-point value.
+func parseFloat(s string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(s), 64)
+}
+
+// formatFloat returns the string representation of x, rounded to the given
+// number of decimals.
+func formatFloat(x float64, prec int) string {
+	scale := math.Pow(10, float64(prec))
+	x = math.Round(x*scale) / scale
+	if x == 0 {
+		// Avoid negative zero.
+		x = 0
+	}
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
@@ -0,0 +1,106 @@
+package layout_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/internal/enc"
+	"github.com/graphism/dot/layout"
+)
+
+func TestLayoutGraph(t *testing.T) {
+	golden := []struct {
+		in  string
+		out string
+	}{
+		{
+			in:  "testdata/cluster.dot",
+			out: "testdata/cluster.golden",
+		},
+		{
+			in:  "testdata/cycle.dot",
+			out: "testdata/cycle.golden",
+		},
+		{
+			in:  "testdata/rank.dot",
+			out: "testdata/rank.golden",
+		},
+	}
+	for _, g := range golden {
+		file, err := dot.ParseFile(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", g.in, err)
+			continue
+		}
+		graph, err := layout.LayoutGraph(file.Graphs[0])
+		if err != nil {
+			t.Errorf("%q: unable to lay out graph; %v", g.in, err)
+			continue
+		}
+		want, err := ioutil.ReadFile(g.out)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", g.in, err)
+			continue
+		}
+		got := graph.String()
+		if got != string(bytes.TrimSpace(want)) {
+			t.Errorf("%q: graph mismatch; expected `%s`, got `%s`", g.in, want, got)
+		}
+	}
+}
+
+func TestLayoutRankdir(t *testing.T) {
+	// Each edge of the acyclic test graph points in the rank direction, and
+	// nodes of rank=same subgraphs share rank.
+	for _, rankdir := range []string{"TB", "LR", "BT", "RL"} {
+		file, err := dot.ParseFile("testdata/rank.dot")
+		if err != nil {
+			t.Fatalf("unable to parse file; %v", err)
+		}
+		graph := file.Graphs[0]
+		graph.Stmts = append(graph.Stmts, &ast.Attr{Key: "rankdir", Val: rankdir})
+		g, err := dot.Resolve(graph)
+		if err != nil {
+			t.Fatalf("unable to resolve graph; %v", err)
+		}
+		if err := layout.Layout(g); err != nil {
+			t.Errorf("rankdir=%s: unable to lay out graph; %v", rankdir, err)
+			continue
+		}
+		// rank returns the rank coordinate of the node, increasing in rank
+		// direction.
+		rank := func(id string) float64 {
+			n, _ := g.Node(id)
+			pos, _ := n.Attrs.Get("pos")
+			p := strings.Split(enc.Unquote(pos), ",")
+			x, _ := strconv.ParseFloat(p[0], 64)
+			y, _ := strconv.ParseFloat(p[1], 64)
+			switch rankdir {
+			case "LR":
+				return x
+			case "BT":
+				return y
+			case "RL":
+				return -x
+			default:
+				return -y
+			}
+		}
+		for _, e := range g.Edges {
+			if from, to := rank(enc.Unquote(e.From.ID)), rank(enc.Unquote(e.To.ID)); from >= to {
+				t.Errorf("rankdir=%s: edge %s -> %s against rank direction; %v >= %v", rankdir, e.From.ID, e.To.ID, from, to)
+			}
+		}
+		if c, f := rank("c"), rank("f"); c != f {
+			t.Errorf("rankdir=%s: rank mismatch of rank=same nodes c and f; %v != %v", rankdir, c, f)
+		}
+		if _, ok := g.Attrs.Get("bb"); !ok {
+			t.Errorf("rankdir=%s: missing bounding box", rankdir)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package layout
+
+import (
+	"sort"
+)
+
+// === [ Crossing minimisation ] ===============================================
+
+// order orders the nodes within each rank to reduce edge crossings.
+//
+// The initial order is given by a depth-first search from the nodes in order
+// of creation. The order is then improved by alternately sweeping down and up
+// the ranks, sorting the nodes of each rank by the barycenter of their
+// neighbours in the adjacent rank. The order with the fewest crossings is
+// kept.
+func (l *layout) order() {
+	placed := make(map[*node]bool)
+	var place func(v *node)
+	place = func(v *node) {
+		if placed[v] {
+			return
+		}
+		placed[v] = true
+		l.ranks[v.rank] = append(l.ranks[v.rank], v)
+		for _, w := range v.down {
+			place(w)
+		}
+	}
+	for _, v := range l.nodes {
+		place(v)
+	}
+	l.renumber()
+
+	best := l.saveOrder()
+	bestCrossings := l.crossings()
+	for i := 0; i < orderIterations && bestCrossings > 0; i++ {
+		if i%2 == 0 {
+			for r := 1; r < len(l.ranks); r++ {
+				sortRank(l.ranks[r], func(v *node) []*node { return v.up })
+			}
+		} else {
+			for r := len(l.ranks) - 2; r >= 0; r-- {
+				sortRank(l.ranks[r], func(v *node) []*node { return v.down })
+			}
+		}
+		l.renumber()
+		if c := l.crossings(); c < bestCrossings {
+			best, bestCrossings = l.saveOrder(), c
+		}
+	}
+	l.ranks = best
+	l.renumber()
+}
+
+// renumber updates the order of each node to its index within its rank.
+func (l *layout) renumber() {
+	for _, rank := range l.ranks {
+		for i, v := range rank {
+			v.order = i
+		}
+	}
+}
+
+// saveOrder returns a copy of the current order of each rank.
+func (l *layout) saveOrder() [][]*node {
+	ranks := make([][]*node, len(l.ranks))
+	for r, rank := range l.ranks {
+		ranks[r] = append([]*node(nil), rank...)
+	}
+	return ranks
+}
+
+// sortRank sorts the nodes of the given rank by the barycenter of their
+// neighbours. Nodes without neighbours keep their position.
+func sortRank(rank []*node, neighbours func(v *node) []*node) {
+	type item struct {
+		v    *node
+		bary float64
+	}
+	var items []item
+	var slots []int
+	for i, v := range rank {
+		ns := neighbours(v)
+		if len(ns) == 0 {
+			continue
+		}
+		sum := 0.0
+		for _, w := range ns {
+			sum += float64(w.order)
+		}
+		items = append(items, item{v: v, bary: sum / float64(len(ns))})
+		slots = append(slots, i)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].bary < items[j].bary
+	})
+	for i, slot := range slots {
+		rank[slot] = items[i].v
+	}
+}
+
+// crossings returns the number of edge crossings between adjacent ranks.
+func (l *layout) crossings() int {
+	total := 0
+	for r := 0; r+1 < len(l.ranks); r++ {
+		// Collect the lower endpoints of edges, ordered by upper endpoint; the
+		// number of crossings is the number of inversions.
+		var ends []int
+		for _, v := range l.ranks[r] {
+			start := len(ends)
+			for _, w := range v.down {
+				ends = append(ends, w.order)
+			}
+			sort.Ints(ends[start:])
+		}
+		total += inversions(ends, len(l.ranks[r+1]))
+	}
+	return total
+}
+
+// inversions returns the number of inversions of xs, where each element is in
+// the range [0, n).
+func inversions(xs []int, n int) int {
+	// Fenwick tree of the number of elements seen so far.
+	tree := make([]int, n+1)
+	count := 0
+	for i, x := range xs {
+		// Number of elements seen so far which are less than or equal to x.
+		le := 0
+		for j := x + 1; j > 0; j -= j & -j {
+			le += tree[j]
+		}
+		count += i - le
+		for j := x + 1; j <= n; j += j & -j {
+			tree[j]++
+		}
+	}
+	return count
+}
@@ -0,0 +1,375 @@
+package layout
+
+import (
+	"math"
+	"strings"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/internal/enc"
+)
+
+// === [ Output ] ==============================================================
+
+// A point in the output frame, in points with the y-axis pointing upwards.
+type point struct {
+	x, y float64
+}
+
+// add returns p+q.
+func (p point) add(q point) point {
+	return point{x: p.x + q.x, y: p.y + q.y}
+}
+
+// sub returns p-q.
+func (p point) sub(q point) point {
+	return point{x: p.x - q.x, y: p.y - q.y}
+}
+
+// scale returns p scaled by s.
+func (p point) scale(s float64) point {
+	return point{x: p.x * s, y: p.y * s}
+}
+
+// String returns the string representation of the point, as "x,y".
+func (p point) String() string {
+	return formatFloat(p.x, 2) + "," + formatFloat(p.y, 2)
+}
+
+// A box is an axis-aligned rectangle.
+type box struct {
+	min, max point
+}
+
+// emptyBox returns an empty box, which contains no points.
+func emptyBox() box {
+	inf := math.Inf(1)
+	return box{min: point{x: inf, y: inf}, max: point{x: -inf, y: -inf}}
+}
+
+// extend extends the box to contain p.
+func (b *box) extend(p point) {
+	b.min.x, b.min.y = math.Min(b.min.x, p.x), math.Min(b.min.y, p.y)
+	b.max.x, b.max.y = math.Max(b.max.x, p.x), math.Max(b.max.y, p.y)
+}
+
+// union extends the box to contain c.
+func (b *box) union(c box) {
+	if c.empty() {
+		return
+	}
+	b.extend(c.min)
+	b.extend(c.max)
+}
+
+// empty reports whether the box is empty.
+func (b box) empty() bool {
+	return b.min.x > b.max.x
+}
+
+// String returns the string representation of the box, as "llx,lly,urx,ury".
+func (b box) String() string {
+	return b.min.String() + "," + b.max.String()
+}
+
+// A spline is the route of an edge, as a sequence of cubic Bézier curves.
+type spline struct {
+	// Control points; 3n+1 points for n curves.
+	pts []point
+	// Arrowhead end points at source and destination; or nil if none.
+	start, end *point
+	// Label position; or nil if no label.
+	lp *point
+}
+
+// output transforms the layout to the output frame, routes the edges, and
+// records the layout as attributes of the graph.
+func (l *layout) output() {
+	bb := emptyBox()
+	// Nodes.
+	centers := make(map[*node]point)
+	for _, v := range l.nodes {
+		c := l.transform(v.x, v.y)
+		centers[v] = c
+		w, h := l.size(v)
+		bb.extend(point{x: c.x - w/2, y: c.y - h/2})
+		bb.extend(point{x: c.x + w/2, y: c.y + h/2})
+	}
+	// Edges.
+	splines := make([]*spline, len(l.edges))
+	for i, e := range l.edges {
+		s := l.route(e, centers)
+		splines[i] = s
+		for _, p := range s.pts {
+			bb.extend(p)
+		}
+		if s.lp != nil {
+			w, h := labelSize(e.e.Attrs, "")
+			bb.extend(point{x: s.lp.x - w/2, y: s.lp.y - h/2})
+			bb.extend(point{x: s.lp.x + w/2, y: s.lp.y + h/2})
+		}
+	}
+	// Clusters.
+	clusters := make(map[*dot.Subgraph]box)
+	labels := make(map[*dot.Subgraph]point)
+	var walk func(subs []*dot.Subgraph) box
+	walk = func(subs []*dot.Subgraph) box {
+		all := emptyBox()
+		for _, sub := range subs {
+			b := walk(sub.Subgraphs)
+			if !strings.HasPrefix(enc.Unquote(sub.ID), "cluster") || len(sub.Nodes) == 0 {
+				all.union(b)
+				continue
+			}
+			for _, n := range sub.Nodes {
+				v := l.nodeOf[n]
+				c := centers[v]
+				w, h := l.size(v)
+				b.extend(point{x: c.x - w/2, y: c.y - h/2})
+				b.extend(point{x: c.x + w/2, y: c.y + h/2})
+			}
+			b.min = b.min.sub(point{x: clusterMargin, y: clusterMargin})
+			b.max = b.max.add(point{x: clusterMargin, y: clusterMargin})
+			if _, ok := sub.Attrs.Get("label"); ok {
+				labels[sub] = addLabel(&b, sub.Attrs, "t")
+			}
+			clusters[sub] = b
+			all.union(b)
+		}
+		return all
+	}
+	bb.union(walk(l.g.Subgraphs))
+	if bb.empty() {
+		bb = box{}
+	}
+	// Graph label.
+	var lp *point
+	if _, ok := l.g.Attrs.Get("label"); ok {
+		p := addLabel(&bb, l.g.Attrs, "b")
+		lp = &p
+	}
+
+	// Translate the layout to the origin, and record the layout as attributes.
+	origin := bb.min
+	for _, v := range l.nodes {
+		w, h := l.size(v)
+		setAttr(&v.n.Attrs, "pos", centers[v].sub(origin).String())
+		setAttr(&v.n.Attrs, "width", formatFloat(w/ppi, 4))
+		setAttr(&v.n.Attrs, "height", formatFloat(h/ppi, 4))
+	}
+	for i, e := range l.edges {
+		s := splines[i]
+		var parts []string
+		if s.end != nil {
+			parts = append(parts, "e,"+s.end.sub(origin).String())
+		}
+		if s.start != nil {
+			parts = append(parts, "s,"+s.start.sub(origin).String())
+		}
+		for _, p := range s.pts {
+			parts = append(parts, p.sub(origin).String())
+		}
+		setAttr(&e.e.Attrs, "pos", strings.Join(parts, " "))
+		if s.lp != nil {
+			setAttr(&e.e.Attrs, "lp", s.lp.sub(origin).String())
+		}
+	}
+	var record func(subs []*dot.Subgraph)
+	record = func(subs []*dot.Subgraph) {
+		for _, sub := range subs {
+			if b, ok := clusters[sub]; ok {
+				setAttr(&sub.Attrs, "bb", box{min: b.min.sub(origin), max: b.max.sub(origin)}.String())
+				if p, ok := labels[sub]; ok {
+					setAttr(&sub.Attrs, "lp", p.sub(origin).String())
+				}
+			}
+			record(sub.Subgraphs)
+		}
+	}
+	record(l.g.Subgraphs)
+	setAttr(&l.g.Attrs, "bb", box{max: bb.max.sub(origin)}.String())
+	if lp != nil {
+		setAttr(&l.g.Attrs, "lp", lp.sub(origin).String())
+	}
+}
+
+// addLabel extends the given box with room for the label of the given
+// attributes, and returns the label position. The label is placed at the top
+// or bottom of the box, as specified by the labelloc attribute.
+func addLabel(b *box, attrs dot.Attrs, loc string) point {
+	if v, ok := getAttr(attrs, "labelloc"); ok {
+		loc = v
+	}
+	w, h := labelSize(attrs, "")
+	if b.empty() {
+		*b = box{}
+	}
+	mid := (b.min.x + b.max.x) / 2
+	if width := b.max.x - b.min.x; w > width {
+		b.min.x -= (w - width) / 2
+		b.max.x += (w - width) / 2
+	}
+	if loc == "t" {
+		b.max.y += h
+		return point{x: mid, y: b.max.y - h/2}
+	}
+	b.min.y -= h
+	return point{x: mid, y: b.min.y + h/2}
+}
+
+// transform transforms the given point from the top-to-bottom layout frame to
+// the output frame, according to the rank direction. The output frame is
+// translated to the origin when output.
+func (l *layout) transform(x, y float64) point {
+	switch l.rankdir {
+	case "BT":
+		return point{x: x, y: y}
+	case "LR":
+		return point{x: y, y: -x}
+	case "RL":
+		return point{x: -y, y: -x}
+	default:
+		return point{x: x, y: -y}
+	}
+}
+
+// size returns the width and height of the given node in the output frame.
+func (l *layout) size(v *node) (float64, float64) {
+	if l.flipped() {
+		return v.h, v.w
+	}
+	return v.w, v.h
+}
+
+// route returns the spline of the given edge.
+func (l *layout) route(e *edge, centers map[*node]point) *spline {
+	s := &spline{}
+	from, to := centers[e.from], centers[e.to]
+	if e.from == e.to {
+		// Self-loops are routed on the right side of the node.
+		w, h := l.size(e.from)
+		p0 := l.clip(e.from, from, from.add(point{x: w / 2, y: h / 4}))
+		p3 := l.clip(e.from, from, from.add(point{x: w / 2, y: -h / 4}))
+		c1 := point{x: from.x + w/2 + 18, y: p0.y + 12}
+		c2 := point{x: from.x + w/2 + 18, y: p3.y - 12}
+		s.pts = []point{p0, c1, c2, p3}
+	} else {
+		path := []point{from}
+		for _, v := range e.chain {
+			path = append(path, l.transform(v.x, v.y))
+		}
+		path = append(path, to)
+		path[0] = l.clip(e.from, from, path[1])
+		path[len(path)-1] = l.clip(e.to, to, path[len(path)-2])
+		s.pts = bezier(path)
+		if _, ok := e.e.Attrs.Get("label"); ok {
+			var mid point
+			if n := len(path); n%2 == 1 {
+				mid = path[n/2]
+			} else {
+				mid = path[n/2-1].add(path[n/2]).scale(0.5)
+			}
+			w, _ := labelSize(e.e.Attrs, "")
+			s.lp = &point{x: mid.x + w/2 + 4, y: mid.y}
+		}
+	}
+	// Arrowheads.
+	dir := "none"
+	if l.g.Directed {
+		dir = "forward"
+	}
+	if v, ok := getAttr(e.e.Attrs, "dir"); ok {
+		dir = v
+	}
+	head := dir == "forward" || dir == "both"
+	tail := dir == "back" || dir == "both"
+	if v, _ := getAttr(e.e.Attrs, "arrowhead"); v == "none" {
+		head = false
+	}
+	if v, _ := getAttr(e.e.Attrs, "arrowtail"); v == "none" {
+		tail = false
+	}
+	if head {
+		n := len(s.pts)
+		end := s.pts[n-1]
+		s.end = &end
+		s.pts[n-1] = shorten(end, s.pts[n-2])
+	}
+	if tail {
+		start := s.pts[0]
+		s.start = &start
+		s.pts[0] = shorten(start, s.pts[1])
+	}
+	return s
+}
+
+// clip returns the intersection of the boundary of the given node, centered at
+// c, with the line segment from c to p. The center is returned if p is within
+// the node.
+func (l *layout) clip(v *node, c, p point) point {
+	w, h := l.size(v)
+	a, b := w/2, h/2
+	d := p.sub(c)
+	if d.x == 0 && d.y == 0 {
+		return c
+	}
+	var t float64
+	switch v.shape {
+	case "", "ellipse", "oval", "circle", "doublecircle", "point", "mcircle":
+		t = 1 / math.Sqrt((d.x*d.x)/(a*a)+(d.y*d.y)/(b*b))
+	case "diamond", "mdiamond":
+		t = 1 / (math.Abs(d.x)/a + math.Abs(d.y)/b)
+	default:
+		t = math.Inf(1)
+		if d.x != 0 {
+			t = a / math.Abs(d.x)
+		}
+		if d.y != 0 {
+			t = math.Min(t, b/math.Abs(d.y))
+		}
+	}
+	if t > 1 {
+		return c
+	}
+	return c.add(d.scale(t))
+}
+
+// shorten returns the end point p moved towards q by the length of an
+// arrowhead, or half the distance to q if shorter.
+func shorten(p, q point) point {
+	d := q.sub(p)
+	dist := math.Hypot(d.x, d.y)
+	if dist == 0 {
+		return p
+	}
+	return p.add(d.scale(math.Min(arrowLength, dist/2) / dist))
+}
+
+// bezier returns the control points of a smooth sequence of cubic Bézier
+// curves passing through the given points, as a Catmull-Rom spline.
+func bezier(path []point) []point {
+	pts := []point{path[0]}
+	for i := 0; i+1 < len(path); i++ {
+		prev, next := path[i], path[i+1]
+		if i > 0 {
+			prev = path[i-1]
+		}
+		if i+2 < len(path) {
+			next = path[i+2]
+		}
+		c1 := path[i].add(path[i+1].sub(prev).scale(1.0 / 6))
+		c2 := path[i+1].sub(next.sub(path[i]).scale(1.0 / 6))
+		if i == 0 && len(path) == 2 {
+			// Straight line; place control points at a third.
+			d := path[1].sub(path[0])
+			c1, c2 = path[0].add(d.scale(1.0/3)), path[0].add(d.scale(2.0/3))
+		}
+		pts = append(pts, c1, c2, path[i+1])
+	}
+	return pts
+}
+
+// setAttr sets the attribute with the given key to the given value, quoting
+// the value as needed.
+func setAttr(attrs *dot.Attrs, key, val string) {
+	attrs.Set(key, enc.Quote(val))
+}
@@ -0,0 +1,131 @@
+package layout
+
+import (
+	"math"
+)
+
+// === [ Coordinate assignment ] ===============================================
+
+// position assigns coordinates to the nodes, in the top-to-bottom frame.
+//
+// Nodes are initially packed to the left of each rank. Each node is then
+// iteratively moved towards the weighted average position of its neighbours,
+// while preserving the order and minimum separation of the nodes of each rank.
+func (l *layout) position() {
+	for _, rank := range l.ranks {
+		x := 0.0
+		for i, v := range rank {
+			if i > 0 {
+				x += l.sep(rank[i-1], v)
+			}
+			v.x = x
+		}
+	}
+	up := func(v *node) ([]*node, []float64) { return v.up, v.upWeight }
+	down := func(v *node) ([]*node, []float64) { return v.down, v.downWeight }
+	both := func(v *node) ([]*node, []float64) {
+		ns := append(append([]*node(nil), v.up...), v.down...)
+		ws := append(append([]float64(nil), v.upWeight...), v.downWeight...)
+		return ns, ws
+	}
+	for i := 0; i < positionIterations; i++ {
+		for r := 1; r < len(l.ranks); r++ {
+			l.place(l.ranks[r], up)
+		}
+		for r := len(l.ranks) - 2; r >= 0; r-- {
+			l.place(l.ranks[r], down)
+		}
+	}
+	for i := 0; i < positionIterations; i++ {
+		for _, rank := range l.ranks {
+			l.place(rank, both)
+		}
+	}
+	// Translate the layout to the origin.
+	left := math.Inf(1)
+	for _, rank := range l.ranks {
+		if len(rank) > 0 {
+			left = math.Min(left, rank[0].x-rank[0].w/2)
+		}
+	}
+	// Assign ranks from the top.
+	y := 0.0
+	for r, rank := range l.ranks {
+		h := 0.0
+		for _, v := range rank {
+			v.x -= left
+			h = math.Max(h, v.h)
+		}
+		if r > 0 {
+			y += l.ranksep
+		}
+		for _, v := range rank {
+			v.y = y + h/2
+		}
+		y += h
+	}
+}
+
+// sep returns the minimum separation between the centers of the given adjacent
+// nodes.
+func (l *layout) sep(a, b *node) float64 {
+	sep := l.nodesep
+	if a.n == nil || b.n == nil {
+		sep /= 2
+	}
+	return (a.w+b.w)/2 + sep
+}
+
+// place moves the nodes of the given rank towards the weighted average position
+// of their neighbours, while preserving the order and minimum separation of
+// nodes.
+//
+// The placement minimises the weighted squared distance of the nodes to their
+// desired positions, subject to the separation constraints; which is solved
+// as an isotonic regression using the pool adjacent violators algorithm.
+func (l *layout) place(rank []*node, neighbours func(v *node) ([]*node, []float64)) {
+	if len(rank) == 0 {
+		return
+	}
+	// Offsets of the nodes, given minimum separation.
+	offsets := make([]float64, len(rank))
+	for i := 1; i < len(rank); i++ {
+		offsets[i] = offsets[i-1] + l.sep(rank[i-1], rank[i])
+	}
+	// A block of nodes placed at the same offset position.
+	type block struct {
+		sum, weight float64
+		n           int
+	}
+	var blocks []block
+	for i, v := range rank {
+		// Nodes without neighbours prefer to stay in place.
+		target, weight := v.x, 1e-3
+		ns, ws := neighbours(v)
+		if len(ns) > 0 {
+			sum, total := 0.0, 0.0
+			for j, w := range ns {
+				sum += ws[j] * w.x
+				total += ws[j]
+			}
+			target, weight = sum/total, total
+		}
+		blocks = append(blocks, block{sum: weight * (target - offsets[i]), weight: weight, n: 1})
+		for len(blocks) > 1 {
+			a, b := blocks[len(blocks)-2], blocks[len(blocks)-1]
+			if a.sum/a.weight <= b.sum/b.weight {
+				break
+			}
+			blocks = blocks[:len(blocks)-1]
+			blocks[len(blocks)-1] = block{sum: a.sum + b.sum, weight: a.weight + b.weight, n: a.n + b.n}
+		}
+	}
+	i := 0
+	for _, b := range blocks {
+		pos := b.sum / b.weight
+		for j := 0; j < b.n; j++ {
+			rank[i].x = pos + offsets[i]
+			i++
+		}
+	}
+}
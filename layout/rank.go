@@ -0,0 +1,242 @@
+package layout
+
+import (
+	"strconv"
+
+	"github.com/graphism/dot"
+)
+
+// === [ Rank assignment ] =====================================================
+
+// A rankEdge is an edge between two rank groups.
+type rankEdge struct {
+	// Source and destination groups.
+	from, to int
+	// Minimum rank difference.
+	minlen int
+}
+
+// rank assigns nodes to ranks.
+//
+// Nodes of subgraphs with rank=same are merged into groups, which are assigned
+// the same rank. Cycles are broken by reversing edges in depth-first order,
+// and each group is assigned its longest path from a source. Sources are then
+// pulled down towards their successors to shorten edges.
+func (l *layout) rank() {
+	index := make(map[*node]int)
+	for i, v := range l.nodes {
+		index[v] = i
+	}
+	// Merge nodes of rank=same subgraphs into groups.
+	parent := make([]int, len(l.nodes))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	// kinds maps from group leader to rank kind (min or max).
+	kinds := make(map[*node]string)
+	var walk func(subs []*dot.Subgraph)
+	walk = func(subs []*dot.Subgraph) {
+		for _, sub := range subs {
+			kind, _ := getAttr(sub.Attrs, "rank")
+			switch kind {
+			case "same", "min", "source", "max", "sink":
+				if len(sub.Nodes) == 0 {
+					break
+				}
+				root := find(index[l.nodeOf[sub.Nodes[0]]])
+				for _, n := range sub.Nodes[1:] {
+					if r := find(index[l.nodeOf[n]]); r != root {
+						parent[r] = root
+					}
+				}
+				switch kind {
+				case "min", "source":
+					kinds[l.nodes[root]] = "min"
+				case "max", "sink":
+					kinds[l.nodes[root]] = "max"
+				}
+			}
+			walk(sub.Subgraphs)
+		}
+	}
+	walk(l.g.Subgraphs)
+	groupKind := make(map[int]string)
+	for v, kind := range kinds {
+		groupKind[find(index[v])] = kind
+	}
+
+	// Collect edges between groups.
+	out := make([][]rankEdge, len(l.nodes))
+	for _, e := range l.edges {
+		if v, _ := getAttr(e.e.Attrs, "constraint"); v == "false" {
+			continue
+		}
+		from, to := find(index[e.from]), find(index[e.to])
+		if from == to {
+			continue
+		}
+		minlen := 1
+		if v, ok := getAttr(e.e.Attrs, "minlen"); ok {
+			if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+				minlen = n
+			}
+		}
+		out[from] = append(out[from], rankEdge{from: from, to: to, minlen: minlen})
+	}
+
+	// Break cycles by reversing back edges, found by depth-first search.
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := make([]int, len(l.nodes))
+	var dag []rankEdge
+	var order []int // reverse topological order
+	var visit func(u int)
+	visit = func(u int) {
+		state[u] = active
+		for _, e := range out[u] {
+			switch state[e.to] {
+			case unvisited:
+				dag = append(dag, e)
+				visit(e.to)
+			case active:
+				dag = append(dag, rankEdge{from: e.to, to: e.from, minlen: e.minlen})
+			default:
+				dag = append(dag, e)
+			}
+		}
+		state[u] = done
+		order = append(order, u)
+	}
+	for i := range l.nodes {
+		if find(i) == i && state[i] == unvisited {
+			visit(i)
+		}
+	}
+	in := make([][]rankEdge, len(l.nodes))
+	out = make([][]rankEdge, len(l.nodes))
+	for _, e := range dag {
+		in[e.to] = append(in[e.to], e)
+		out[e.from] = append(out[e.from], e)
+	}
+
+	// Assign the longest path from a source, in topological order.
+	ranks := make([]int, len(l.nodes))
+	for i := len(order) - 1; i >= 0; i-- {
+		v := order[i]
+		for _, e := range in[v] {
+			if r := ranks[e.from] + e.minlen; r > ranks[v] {
+				ranks[v] = r
+			}
+		}
+	}
+	// Pull sources down towards their successors, in reverse topological
+	// order.
+	for _, v := range order {
+		if len(in[v]) != 0 || len(out[v]) == 0 || groupKind[v] != "" {
+			continue
+		}
+		min := -1
+		for _, e := range out[v] {
+			if r := ranks[e.to] - e.minlen; min == -1 || r < min {
+				min = r
+			}
+		}
+		ranks[v] = min
+	}
+
+	// Place min and max groups on the minimum and maximum rank.
+	lo, hi := 0, 0
+	for i, v := range order {
+		if i == 0 || ranks[v] < lo {
+			lo = ranks[v]
+		}
+		if i == 0 || ranks[v] > hi {
+			hi = ranks[v]
+		}
+	}
+	for v, kind := range groupKind {
+		switch kind {
+		case "min":
+			ranks[v] = lo
+		case "max":
+			ranks[v] = hi
+		}
+	}
+	// Push the successors of max groups further down.
+	for i := len(order) - 1; i >= 0; i-- {
+		v := order[i]
+		if groupKind[v] != "" {
+			continue
+		}
+		for _, e := range in[v] {
+			if r := ranks[e.from] + e.minlen; r > ranks[v] {
+				ranks[v] = r
+			}
+		}
+	}
+	for i, v := range l.nodes {
+		v.rank = ranks[find(i)] - lo
+	}
+}
+
+// buildLayers places the nodes in ranks, inserting virtual nodes for edges
+// spanning multiple ranks.
+func (l *layout) buildLayers() {
+	max := 0
+	for _, v := range l.nodes {
+		if v.rank > max {
+			max = v.rank
+		}
+	}
+	l.ranks = make([][]*node, max+1)
+	for _, e := range l.edges {
+		upper, lower := e.from, e.to
+		if upper.rank > lower.rank {
+			upper, lower = lower, upper
+		}
+		if upper.rank == lower.rank {
+			// Self-loops and flat edges are routed separately.
+			continue
+		}
+		prev := upper
+		for r := upper.rank + 1; r < lower.rank; r++ {
+			v := &node{rank: r}
+			e.chain = append(e.chain, v)
+			link(prev, v)
+			prev = v
+		}
+		link(prev, lower)
+		if e.from != upper {
+			// Order virtual nodes from source to destination.
+			for i, j := 0, len(e.chain)-1; i < j; i, j = i+1, j-1 {
+				e.chain[i], e.chain[j] = e.chain[j], e.chain[i]
+			}
+		}
+	}
+}
+
+// link adds an edge between the given nodes of adjacent ranks. Edges between
+// virtual nodes are given higher weight, to keep long edges straight.
+func link(upper, lower *node) {
+	weight := 1.0
+	switch {
+	case upper.n == nil && lower.n == nil:
+		weight = 8
+	case upper.n == nil || lower.n == nil:
+		weight = 2
+	}
+	upper.down = append(upper.down, lower)
+	upper.downWeight = append(upper.downWeight, weight)
+	lower.up = append(lower.up, upper)
+	lower.upWeight = append(lower.upWeight, weight)
+}
@@ -0,0 +1,734 @@
+package dot
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/internal/enc"
+	"github.com/pkg/errors"
+)
+
+// === [ Marshalling ] =========================================================
+
+// A Marshaler is a value which marshals itself into a DOT ID or attribute
+// value.
+type Marshaler interface {
+	// MarshalDOT returns the unquoted DOT ID or attribute value of the value.
+	MarshalDOT() (string, error)
+}
+
+// An Unmarshaler is a value which unmarshals itself from a DOT ID or attribute
+// value.
+type Unmarshaler interface {
+	// UnmarshalDOT sets the value from the given unquoted DOT ID or attribute
+	// value.
+	UnmarshalDOT(s string) error
+}
+
+// Marshal returns the DOT graph of the given struct, as specified by the
+// struct tags of its fields. The following struct tags are recognized.
+//
+//    dot:"id"                    graph, node or subgraph ID
+//    dot:"strict"                strict graph (bool)
+//    dot:"directed"              directed graph (bool); digraph if not present
+//    dot:"attr=NAME[,omitempty]" attribute NAME of graph, node or edge
+//    dot:"node"                  slice of node structs
+//    dot:"edge[,from=F,to=T]"    slice of edge structs, with endpoint fields F
+//                                and T; From and To if not specified
+//    dot:"-"                     ignored field
+//
+// Fields without struct tag are ignored. Node and edge structs are described
+// by id and attribute fields. Edge endpoints are either node IDs or pointers to
+// node structs.
+//
+// IDs and attribute values are strings, booleans, integers, floating-point
+// numbers or implementations of Marshaler, and are quoted as needed. Strings
+// are double-quoted unless alphanumeric or numerals, and are thus never
+// interpreted as double-quoted strings or HTML strings; values of Marshaler
+// are kept as is if valid DOT IDs (e.g. HTML-like labels).
+func Marshal(v interface{}) (*ast.Graph, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, errors.Errorf("unable to marshal %T; expected struct or pointer to struct", v)
+	}
+	if !rv.CanAddr() {
+		// Make the value addressable, to call pointer receiver methods.
+		p := reflect.New(rv.Type())
+		p.Elem().Set(rv)
+		rv = p.Elem()
+	}
+	info, err := structInfoOf(rv.Type())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	graph := &ast.Graph{Directed: true}
+	if info.strict != nil {
+		graph.Strict = rv.Field(info.strict.index).Bool()
+	}
+	if info.directed != nil {
+		graph.Directed = rv.Field(info.directed.index).Bool()
+	}
+	if info.id != nil {
+		fv := rv.Field(info.id.index)
+		id, err := marshalValue(fv)
+		if err != nil {
+			return nil, errors.Errorf("unable to marshal ID of graph; %v", err)
+		}
+		if len(id) > 0 {
+			if graph.ID, err = quote(fv, id); err != nil {
+				return nil, errors.Errorf("unable to marshal ID of graph; %v", err)
+			}
+		}
+	}
+	attrs, err := marshalAttrs(rv, info)
+	if err != nil {
+		return nil, errors.Errorf("unable to marshal attributes of graph; %v", err)
+	}
+	if len(attrs) > 0 {
+		graph.Stmts = append(graph.Stmts, &ast.AttrStmt{Kind: ast.KindGraph, Attrs: attrs})
+	}
+	if info.nodes != nil {
+		nodes := rv.Field(info.nodes.index)
+		for i := 0; i < nodes.Len(); i++ {
+			stmt, err := marshalNode(nodes.Index(i))
+			if err != nil {
+				return nil, errors.Errorf("unable to marshal node %d of %s; %v", i, info.nodes.name, err)
+			}
+			if stmt != nil {
+				graph.Stmts = append(graph.Stmts, stmt)
+			}
+		}
+	}
+	if info.edges != nil {
+		edges := rv.Field(info.edges.index)
+		for i := 0; i < edges.Len(); i++ {
+			stmt, err := marshalEdge(edges.Index(i), info.edges, graph.Directed)
+			if err != nil {
+				return nil, errors.Errorf("unable to marshal edge %d of %s; %v", i, info.edges.name, err)
+			}
+			if stmt != nil {
+				graph.Stmts = append(graph.Stmts, stmt)
+			}
+		}
+	}
+	return graph, nil
+}
+
+// marshalNode returns the node statement of the given node struct; or nil if
+// v is a nil pointer.
+func marshalNode(v reflect.Value) (*ast.NodeStmt, error) {
+	v, ok := indirect(v)
+	if !ok {
+		return nil, nil
+	}
+	id, err := nodeID(v)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	info, _ := structInfoOf(v.Type())
+	attrs, err := marshalAttrs(v, info)
+	if err != nil {
+		return nil, errors.Errorf("unable to marshal attributes of node %q; %v", enc.Unquote(id), err)
+	}
+	return &ast.NodeStmt{Node: &ast.Node{ID: id}, Attrs: attrs}, nil
+}
+
+// marshalEdge returns the edge statement of the given edge struct, as
+// described by the edge field f; or nil if v is a nil pointer.
+func marshalEdge(v reflect.Value, f *fieldInfo, directed bool) (*ast.EdgeStmt, error) {
+	v, ok := indirect(v)
+	if !ok {
+		return nil, nil
+	}
+	from, err := endpointID(v.FieldByName(f.from))
+	if err != nil {
+		return nil, errors.Errorf("unable to marshal source node %s; %v", f.from, err)
+	}
+	to, err := endpointID(v.FieldByName(f.to))
+	if err != nil {
+		return nil, errors.Errorf("unable to marshal destination node %s; %v", f.to, err)
+	}
+	info, _ := structInfoOf(v.Type())
+	attrs, err := marshalAttrs(v, info)
+	if err != nil {
+		return nil, errors.Errorf("unable to marshal attributes of edge from %q to %q; %v", enc.Unquote(from), enc.Unquote(to), err)
+	}
+	stmt := &ast.EdgeStmt{
+		From: &ast.Node{ID: from},
+		To: &ast.Edge{
+			Directed: directed,
+			Vertex:   &ast.Node{ID: to},
+		},
+		Attrs: attrs,
+	}
+	return stmt, nil
+}
+
+// marshalAttrs returns the attributes of the given struct, as described by
+// info.
+func marshalAttrs(v reflect.Value, info *structInfo) ([]*ast.Attr, error) {
+	var attrs []*ast.Attr
+	for _, f := range info.attrs {
+		fv := v.Field(f.index)
+		if f.omitEmpty && isEmpty(fv) {
+			continue
+		}
+		val, err := marshalValue(fv)
+		if err != nil {
+			return nil, errors.Errorf("unable to marshal attribute %s; %v", f.attr, err)
+		}
+		qval, err := quote(fv, val)
+		if err != nil {
+			return nil, errors.Errorf("unable to marshal attribute %s; %v", f.attr, err)
+		}
+		attrs = append(attrs, &ast.Attr{Key: enc.Quote(f.attr), Val: qval})
+	}
+	return attrs, nil
+}
+
+// nodeID returns the node ID of the given node struct, quoted as needed.
+func nodeID(v reflect.Value) (string, error) {
+	info, err := structInfoOf(v.Type())
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if info.id == nil {
+		return "", errors.Errorf("missing id field in node struct %v", v.Type())
+	}
+	fv := v.Field(info.id.index)
+	id, err := marshalValue(fv)
+	if err != nil {
+		return "", errors.Errorf("unable to marshal ID of node; %v", err)
+	}
+	if len(id) == 0 {
+		return "", errors.Errorf("empty ID of node")
+	}
+	qid, err := quote(fv, id)
+	if err != nil {
+		return "", errors.Errorf("unable to marshal ID of node; %v", err)
+	}
+	return qid, nil
+}
+
+// endpointID returns the node ID of the given edge endpoint, which is either a
+// node ID or a pointer to a node struct, quoted as needed.
+func endpointID(v reflect.Value) (string, error) {
+	if isNodeRef(v.Type()) {
+		v, ok := indirect(v)
+		if !ok {
+			return "", errors.Errorf("nil node")
+		}
+		return nodeID(v)
+	}
+	id, err := marshalValue(v)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if len(id) == 0 {
+		return "", errors.Errorf("empty ID of node")
+	}
+	return quote(v, id)
+}
+
+// quote returns the given unquoted DOT ID or attribute value of v, quoted as
+// needed. Strings are double-quoted unless alphanumeric or numerals, as they
+// would otherwise be interpreted as double-quoted strings or HTML strings;
+// values of Marshaler are only quoted if not valid DOT IDs. An error is returned
+// if the quoted string would not be unquoted to s.
+func quote(v reflect.Value, s string) (string, error) {
+	str := isString(v)
+	if (str || !enc.IsID(s)) && !enc.IsQuotable(s) {
+		return "", errors.Errorf("unable to quote %q; odd number of backslashes preceding double quote, newline or end of string", s)
+	}
+	if str {
+		return enc.QuoteText(s), nil
+	}
+	return enc.Quote(s), nil
+}
+
+// isString reports whether v is a string, or a pointer to a string, which does
+// not implement Marshaler.
+func isString(v reflect.Value) bool {
+	for {
+		if _, ok := marshaler(v); ok {
+			return false
+		}
+		switch v.Kind() {
+		case reflect.String:
+			return true
+		case reflect.Ptr:
+			if v.IsNil() {
+				return false
+			}
+			v = v.Elem()
+		default:
+			return false
+		}
+	}
+}
+
+// marshalValue returns the unquoted DOT ID or attribute value of v.
+func marshalValue(v reflect.Value) (string, error) {
+	if m, ok := marshaler(v); ok {
+		return m.MarshalDOT()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			return "", nil
+		}
+		return marshalValue(v.Elem())
+	}
+	return "", errors.Errorf("unsupported type %v", v.Type())
+}
+
+// marshaler returns the Marshaler of v, and a boolean value indicating if v
+// implements Marshaler, either directly or through a pointer receiver.
+func marshaler(v reflect.Value) (Marshaler, bool) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, false
+	}
+	if m, ok := v.Interface().(Marshaler); ok {
+		return m, true
+	}
+	if v.CanAddr() {
+		if m, ok := v.Addr().Interface().(Marshaler); ok {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// isEmpty reports whether v is the zero value of its type, or an empty slice or
+// map.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// === [ Unmarshalling ] =======================================================
+
+// Unmarshal stores the given DOT graph in the struct pointed to by v, as
+// specified by the struct tags of its fields; see Marshal for the recognized
+// struct tags.
+//
+// The graph is resolved before being stored, thus default attributes apply to
+// nodes and edges, and edge statements are expanded into individual edges. Node
+// and edge slices are replaced by the nodes and edges of the graph, in order of
+// creation. Edge endpoints of pointer to node struct type refer to the
+// corresponding elements of the node slice. Attributes without a matching
+// struct field are ignored.
+func Unmarshal(graph *ast.Graph, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.Errorf("unable to unmarshal into %T; expected non-nil pointer to struct", v)
+	}
+	rv = rv.Elem()
+	info, err := structInfoOf(rv.Type())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	g, err := Resolve(graph)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if info.strict != nil {
+		rv.Field(info.strict.index).SetBool(g.Strict)
+	}
+	if info.directed != nil {
+		rv.Field(info.directed.index).SetBool(g.Directed)
+	}
+	if info.id != nil && len(g.ID) > 0 {
+		if err := unmarshalValue(rv.Field(info.id.index), enc.Unquote(g.ID)); err != nil {
+			return errors.Errorf("unable to unmarshal ID of graph; %v", err)
+		}
+	}
+	if err := unmarshalAttrs(rv, info, g.Attrs); err != nil {
+		return errors.Errorf("unable to unmarshal attributes of graph; %v", err)
+	}
+	u := &unmarshaler{nodes: make(map[nodeKey]reflect.Value)}
+	if info.nodes != nil {
+		field := rv.Field(info.nodes.index)
+		nodes := reflect.MakeSlice(field.Type(), len(g.Nodes), len(g.Nodes))
+		for i, n := range g.Nodes {
+			if err := u.unmarshalNode(nodes.Index(i), n); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		field.Set(nodes)
+	}
+	if info.edges != nil {
+		field := rv.Field(info.edges.index)
+		edges := reflect.MakeSlice(field.Type(), len(g.Edges), len(g.Edges))
+		for i, e := range g.Edges {
+			if err := u.unmarshalEdge(edges.Index(i), info.edges, e); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		field.Set(edges)
+	}
+	return nil
+}
+
+// An unmarshaler keeps track of the node structs of a graph being unmarshalled.
+type unmarshaler struct {
+	// nodes maps from node struct type and unquoted node ID to pointer to node
+	// struct.
+	nodes map[nodeKey]reflect.Value
+}
+
+// A nodeKey identifies a node struct.
+type nodeKey struct {
+	// Node struct type.
+	typ reflect.Type
+	// Unquoted node ID.
+	id string
+}
+
+// unmarshalNode stores the given node in v, which is a node struct or a pointer
+// to a node struct.
+func (u *unmarshaler) unmarshalNode(v reflect.Value, n *Node) error {
+	v = alloc(v)
+	info, _ := structInfoOf(v.Type())
+	id := enc.Unquote(n.ID)
+	if err := unmarshalValue(v.Field(info.id.index), id); err != nil {
+		return errors.Errorf("unable to unmarshal ID of node %q; %v", id, err)
+	}
+	if err := unmarshalAttrs(v, info, n.Attrs); err != nil {
+		return errors.Errorf("unable to unmarshal attributes of node %q; %v", id, err)
+	}
+	u.nodes[nodeKey{typ: v.Type(), id: id}] = v.Addr()
+	return nil
+}
+
+// unmarshalEdge stores the given edge in v, which is an edge struct or a
+// pointer to an edge struct described by the edge field f.
+func (u *unmarshaler) unmarshalEdge(v reflect.Value, f *fieldInfo, e *Edge) error {
+	v = alloc(v)
+	from, to := enc.Unquote(e.From.ID), enc.Unquote(e.To.ID)
+	if err := u.unmarshalEndpoint(v.FieldByName(f.from), from); err != nil {
+		return errors.Errorf("unable to unmarshal source node %s of edge from %q to %q; %v", f.from, from, to, err)
+	}
+	if err := u.unmarshalEndpoint(v.FieldByName(f.to), to); err != nil {
+		return errors.Errorf("unable to unmarshal destination node %s of edge from %q to %q; %v", f.to, from, to, err)
+	}
+	info, _ := structInfoOf(v.Type())
+	if err := unmarshalAttrs(v, info, e.Attrs); err != nil {
+		return errors.Errorf("unable to unmarshal attributes of edge from %q to %q; %v", from, to, err)
+	}
+	return nil
+}
+
+// unmarshalEndpoint stores the node ID of an edge endpoint in v. Pointers to
+// node structs refer to the node struct of the node ID, which is created if not
+// already present.
+func (u *unmarshaler) unmarshalEndpoint(v reflect.Value, id string) error {
+	if !isNodeRef(v.Type()) {
+		return unmarshalValue(v, id)
+	}
+	key := nodeKey{typ: v.Type().Elem(), id: id}
+	p, ok := u.nodes[key]
+	if !ok {
+		p = reflect.New(key.typ)
+		info, _ := structInfoOf(key.typ)
+		if err := unmarshalValue(p.Elem().Field(info.id.index), id); err != nil {
+			return errors.WithStack(err)
+		}
+		u.nodes[key] = p
+	}
+	v.Set(p)
+	return nil
+}
+
+// unmarshalAttrs stores the given attributes in the attribute fields of v, as
+// described by info.
+func unmarshalAttrs(v reflect.Value, info *structInfo, attrs Attrs) error {
+	for _, f := range info.attrs {
+		val, ok := attrs.Get(f.attr)
+		if !ok {
+			continue
+		}
+		if err := unmarshalValue(v.Field(f.index), enc.Unquote(val)); err != nil {
+			return errors.Errorf("unable to unmarshal attribute %s; %v", f.attr, err)
+		}
+	}
+	return nil
+}
+
+// unmarshalValue stores the given unquoted DOT ID or attribute value in v.
+func unmarshalValue(v reflect.Value, s string) error {
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(Unmarshaler); ok {
+			return u.UnmarshalDOT(s)
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+		return nil
+	case reflect.Bool:
+		b, ok := parseBool(s)
+		if !ok {
+			break
+		}
+		v.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			break
+		}
+		v.SetInt(x)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		x, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			break
+		}
+		v.SetUint(x)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		x, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			break
+		}
+		v.SetFloat(x)
+		return nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return unmarshalValue(v.Elem(), s)
+	default:
+		return errors.Errorf("unsupported type %v", v.Type())
+	}
+	return errors.Errorf("unable to parse %q as %v", s, v.Type())
+}
+
+// parseBool parses the given Graphviz boolean value, and reports whether s is
+// a valid boolean value. The strings "true" and "yes" are true, "false" and
+// "no" are false, and integers are true if non-zero.
+func parseBool(s string) (bool, bool) {
+	switch strings.ToLower(s) {
+	case "true", "yes":
+		return true, true
+	case "false", "no":
+		return false, true
+	}
+	x, err := strconv.Atoi(s)
+	if err != nil {
+		return false, false
+	}
+	return x != 0, true
+}
+
+// alloc returns the struct of v, allocating a new struct if v is a nil pointer
+// to struct.
+func alloc(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return v.Elem()
+	}
+	return v
+}
+
+// indirect returns the struct of v, and a boolean value indicating if v is a
+// struct or a non-nil pointer to struct.
+func indirect(v reflect.Value) (reflect.Value, bool) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return v, false
+		}
+		return v.Elem(), true
+	}
+	return v, true
+}
+
+// === [ Struct tags ] =========================================================
+
+// A structInfo describes the tagged fields of a struct type.
+type structInfo struct {
+	// ID field; or nil if not present.
+	id *fieldInfo
+	// Strict graph field; or nil if not present.
+	strict *fieldInfo
+	// Directed graph field; or nil if not present.
+	directed *fieldInfo
+	// Attribute fields.
+	attrs []*fieldInfo
+	// Node slice field; or nil if not present.
+	nodes *fieldInfo
+	// Edge slice field; or nil if not present.
+	edges *fieldInfo
+}
+
+// A fieldInfo describes a tagged struct field.
+type fieldInfo struct {
+	// Field index.
+	index int
+	// Field name.
+	name string
+	// Attribute name of attribute fields.
+	attr string
+	// Omit attribute if empty.
+	omitEmpty bool
+	// Source and destination endpoint field names of edge fields.
+	from, to string
+}
+
+// structInfoOf returns the description of the tagged fields of the given struct
+// type.
+func structInfoOf(t reflect.Type) (*structInfo, error) {
+	info := &structInfo{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("dot")
+		if !ok || tag == "-" {
+			continue
+		}
+		if len(field.PkgPath) > 0 {
+			return nil, errors.Errorf("invalid struct tag %q of unexported field %v.%s", tag, t, field.Name)
+		}
+		if err := info.addField(i, field, tag); err != nil {
+			return nil, errors.Errorf("invalid struct tag %q of field %v.%s; %v", tag, t, field.Name, err)
+		}
+	}
+	return info, nil
+}
+
+// addField adds the given tagged struct field to the struct description.
+func (info *structInfo) addField(index int, field reflect.StructField, tag string) error {
+	parts := strings.Split(tag, ",")
+	f := &fieldInfo{index: index, name: field.Name}
+	opts := parts[1:]
+	switch kind := parts[0]; {
+	case kind == "id":
+		if info.id != nil {
+			return errors.Errorf("duplicate id field %s", info.id.name)
+		}
+		info.id = f
+	case kind == "strict", kind == "directed":
+		if field.Type.Kind() != reflect.Bool {
+			return errors.Errorf("%s field of non-boolean type %v", kind, field.Type)
+		}
+		if kind == "strict" {
+			info.strict = f
+		} else {
+			info.directed = f
+		}
+	case strings.HasPrefix(kind, "attr="):
+		f.attr = kind[len("attr="):]
+		if len(f.attr) == 0 {
+			return errors.Errorf("missing attribute name")
+		}
+		for _, opt := range opts {
+			if opt != "omitempty" {
+				return errors.Errorf("unknown attribute option %q", opt)
+			}
+			f.omitEmpty = true
+		}
+		opts = nil
+		info.attrs = append(info.attrs, f)
+	case kind == "node":
+		elem, err := structElem(field.Type)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if elemInfo, err := structInfoOf(elem); err != nil {
+			return errors.WithStack(err)
+		} else if elemInfo.id == nil {
+			return errors.Errorf("missing id field in node struct %v", elem)
+		}
+		if info.nodes != nil {
+			return errors.Errorf("duplicate node field %s", info.nodes.name)
+		}
+		info.nodes = f
+	case kind == "edge":
+		f.from, f.to = "From", "To"
+		for _, opt := range opts {
+			switch {
+			case strings.HasPrefix(opt, "from="):
+				f.from = opt[len("from="):]
+			case strings.HasPrefix(opt, "to="):
+				f.to = opt[len("to="):]
+			default:
+				return errors.Errorf("unknown edge option %q", opt)
+			}
+		}
+		opts = nil
+		elem, err := structElem(field.Type)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if _, err := structInfoOf(elem); err != nil {
+			return errors.WithStack(err)
+		}
+		for _, name := range []string{f.from, f.to} {
+			ef, ok := elem.FieldByName(name)
+			if !ok || len(ef.PkgPath) > 0 {
+				return errors.Errorf("missing endpoint field %s in edge struct %v", name, elem)
+			}
+			if isNodeRef(ef.Type) {
+				if _, err := structInfoOf(ef.Type.Elem()); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+		}
+		if info.edges != nil {
+			return errors.Errorf("duplicate edge field %s", info.edges.name)
+		}
+		info.edges = f
+	default:
+		return errors.Errorf("unknown field kind %q", kind)
+	}
+	if len(opts) > 0 {
+		return errors.Errorf("unknown option %q", opts[0])
+	}
+	return nil
+}
+
+// structElem returns the struct element type of the given slice type, which
+// has elements of struct or pointer to struct type.
+func structElem(t reflect.Type) (reflect.Type, error) {
+	if t.Kind() != reflect.Slice {
+		return nil, errors.Errorf("non-slice type %v", t)
+	}
+	elem := t.Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return nil, errors.Errorf("slice of non-struct type %v", t.Elem())
+	}
+	return elem, nil
+}
+
+// isNodeRef reports whether the given edge endpoint type refers to a node
+// struct; i.e. a pointer to a struct with an id field.
+func isNodeRef(t reflect.Type) bool {
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	info, err := structInfoOf(t.Elem())
+	return err == nil && info.id != nil
+}
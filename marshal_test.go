@@ -0,0 +1,368 @@
+package dot_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/graphism/dot"
+	"github.com/pkg/errors"
+)
+
+// Pipeline is a graph of pipeline stages.
+type Pipeline struct {
+	Name    string   `dot:"id"`
+	RankDir string   `dot:"attr=rankdir,omitempty"`
+	Stages  []*Stage `dot:"node"`
+	Flows   []Flow   `dot:"edge,from=Src,to=Dst"`
+	// Ignored fields.
+	Owner string
+	Notes string `dot:"-"`
+}
+
+// Stage is a pipeline stage.
+type Stage struct {
+	Name     string `dot:"id"`
+	Kind     Kind   `dot:"attr=shape"`
+	Workers  int    `dot:"attr=workers,omitempty"`
+	Parallel bool   `dot:"attr=parallel,omitempty"`
+}
+
+// Flow is a flow of data between pipeline stages.
+type Flow struct {
+	Src   *Stage
+	Dst   *Stage
+	Label string  `dot:"attr=label,omitempty"`
+	Rate  float64 `dot:"attr=rate,omitempty"`
+}
+
+// Kind is the kind of a pipeline stage, which is marshalled as a node shape.
+type Kind int
+
+// Pipeline stage kinds.
+const (
+	KindTask Kind = iota
+	KindQueue
+)
+
+func (k Kind) MarshalDOT() (string, error) {
+	switch k {
+	case KindTask:
+		return "box", nil
+	case KindQueue:
+		return "cylinder", nil
+	}
+	return "", errors.Errorf("invalid stage kind %d", int(k))
+}
+
+func (k *Kind) UnmarshalDOT(s string) error {
+	switch s {
+	case "box":
+		*k = KindTask
+	case "cylinder":
+		*k = KindQueue
+	default:
+		return errors.Errorf("invalid stage shape %q", s)
+	}
+	return nil
+}
+
+func TestMarshal(t *testing.T) {
+	read := &Stage{Name: "read", Kind: KindTask}
+	queue := &Stage{Name: "work queue", Kind: KindQueue, Workers: 4}
+	write := &Stage{Name: "node", Kind: KindTask, Parallel: true}
+	p := &Pipeline{
+		Name:    "etl",
+		RankDir: "LR",
+		Stages:  []*Stage{read, queue, write},
+		Flows: []Flow{
+			{Src: read, Dst: queue, Label: `"rows"`},
+			{Src: queue, Dst: write, Rate: 0.5},
+		},
+		Owner: "ignored",
+		Notes: "ignored",
+	}
+	graph, err := dot.Marshal(p)
+	if err != nil {
+		t.Fatalf("unable to marshal pipeline; %+v", err)
+	}
+	want := strings.Join([]string{
+		"digraph etl {",
+		"\tgraph [rankdir=LR]",
+		"\tread [shape=box]",
+		"\t\"work queue\" [shape=cylinder workers=4]",
+		"\t\"node\" [shape=box parallel=true]",
+		"\tread -> \"work queue\" [label=\"\\\"rows\\\"\"]",
+		"\t\"work queue\" -> \"node\" [rate=0.5]",
+		"}",
+	}, "\n")
+	if got := graph.String(); got != want {
+		t.Errorf("graph mismatch; expected `%s`, got `%s`", want, got)
+	}
+}
+
+func TestMarshalStrings(t *testing.T) {
+	// Strings are marshalled losslessly; as text rather than as DOT IDs.
+	names := []string{"a", "_1", "-2.5", "node", "a b", `"q"`, `a "b" c`, "<b>x</b>", "<", "", "caf\u00e9", `\N`, `a\\`, `a\\"b`, `"\\"`, "a\\\\\nb", "a\nb"}
+	var p Pipeline
+	for _, name := range names {
+		p.Stages = append(p.Stages, &Stage{Name: name + "x"})
+	}
+	for i, name := range names {
+		p.Flows = append(p.Flows, Flow{Src: p.Stages[i], Dst: p.Stages[0], Label: name})
+	}
+	graph, err := dot.Marshal(p)
+	if err != nil {
+		t.Fatalf("unable to marshal pipeline; %+v", err)
+	}
+	file, err := dot.ParseString(graph.String())
+	if err != nil {
+		t.Fatalf("unable to parse marshalled pipeline; %v", err)
+	}
+	var q Pipeline
+	if err := dot.Unmarshal(file.Graphs[0], &q); err != nil {
+		t.Fatalf("unable to unmarshal graph; %+v", err)
+	}
+	if len(q.Stages) != len(names) || len(q.Flows) != len(names) {
+		t.Fatalf("number of stages and flows mismatch; expected %d, got %d and %d", len(names), len(q.Stages), len(q.Flows))
+	}
+	for i, name := range names {
+		if got, want := q.Stages[i].Name, name+"x"; got != want {
+			t.Errorf("stage %d name mismatch; expected %q, got %q", i, want, got)
+		}
+		if got := q.Flows[i].Label; got != name {
+			t.Errorf("flow %d label mismatch; expected %q, got %q", i, name, got)
+		}
+	}
+	// Strings with backslashes escaping the closing double quote, a double
+	// quote or a newline cannot be double-quoted losslessly.
+	for _, name := range []string{`a\`, `a\"b`, `x\"`, `a\\\`, "a\\\nb"} {
+		flows := Pipeline{Flows: []Flow{{Src: &Stage{Name: "a"}, Dst: &Stage{Name: "b"}, Label: name}}}
+		if _, err := dot.Marshal(flows); err == nil {
+			t.Errorf("%q: expected label error, got nil", name)
+		}
+		stages := Pipeline{Stages: []*Stage{{Name: name}}}
+		if _, err := dot.Marshal(stages); err == nil {
+			t.Errorf("%q: expected node ID error, got nil", name)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	const src = `digraph etl {
+	rankdir=LR
+	node [shape=box]
+	read
+	"work queue" [shape=cylinder, workers=4]
+	edge [label=data]
+	read -> "work queue" -> "node"
+	"node" [parallel=yes]
+	read -> extra [rate=0.5, label="a \"b\""]
+}`
+	file, err := dot.ParseString(src)
+	if err != nil {
+		t.Fatalf("unable to parse file; %v", err)
+	}
+	var p Pipeline
+	if err := dot.Unmarshal(file.Graphs[0], &p); err != nil {
+		t.Fatalf("unable to unmarshal graph; %+v", err)
+	}
+	if p.Name != "etl" || p.RankDir != "LR" {
+		t.Errorf("graph mismatch; expected etl with rankdir LR, got %q with rankdir %q", p.Name, p.RankDir)
+	}
+	wantStages := []Stage{
+		{Name: "read", Kind: KindTask},
+		{Name: "work queue", Kind: KindQueue, Workers: 4},
+		{Name: "node", Kind: KindTask, Parallel: true},
+		{Name: "extra", Kind: KindTask},
+	}
+	if len(p.Stages) != len(wantStages) {
+		t.Fatalf("number of stages mismatch; expected %d, got %d", len(wantStages), len(p.Stages))
+	}
+	for i, want := range wantStages {
+		if got := *p.Stages[i]; got != want {
+			t.Errorf("stage %d mismatch; expected %+v, got %+v", i, want, got)
+		}
+	}
+	wantFlows := []struct {
+		src, dst int
+		label    string
+		rate     float64
+	}{
+		{src: 0, dst: 1, label: "data"},
+		{src: 1, dst: 2, label: "data"},
+		{src: 0, dst: 3, label: `a "b"`, rate: 0.5},
+	}
+	if len(p.Flows) != len(wantFlows) {
+		t.Fatalf("number of flows mismatch; expected %d, got %d", len(wantFlows), len(p.Flows))
+	}
+	for i, want := range wantFlows {
+		got := p.Flows[i]
+		// Endpoints refer to the unmarshalled stages.
+		if got.Src != p.Stages[want.src] || got.Dst != p.Stages[want.dst] {
+			t.Errorf("flow %d endpoint mismatch; expected %q -> %q, got %q -> %q", i, p.Stages[want.src].Name, p.Stages[want.dst].Name, got.Src.Name, got.Dst.Name)
+		}
+		if got.Label != want.label || got.Rate != want.rate {
+			t.Errorf("flow %d attribute mismatch; expected label %q and rate %v, got label %q and rate %v", i, want.label, want.rate, got.Label, got.Rate)
+		}
+	}
+
+	// Round-trip.
+	graph, err := dot.Marshal(p)
+	if err != nil {
+		t.Fatalf("unable to marshal pipeline; %+v", err)
+	}
+	var q Pipeline
+	if err := dot.Unmarshal(graph, &q); err != nil {
+		t.Fatalf("unable to unmarshal graph; %+v", err)
+	}
+	again, err := dot.Marshal(q)
+	if err != nil {
+		t.Fatalf("unable to marshal pipeline; %+v", err)
+	}
+	if got, want := again.String(), graph.String(); got != want {
+		t.Errorf("round-trip mismatch; expected `%s`, got `%s`", want, got)
+	}
+}
+
+// Graph is a graph with node IDs as edge endpoints.
+type Graph struct {
+	Strict   bool   `dot:"strict"`
+	Directed bool   `dot:"directed"`
+	Nodes    []Node `dot:"node"`
+	Edges    []Edge `dot:"edge"`
+}
+
+// Node is a node with an integer ID.
+type Node struct {
+	ID    int     `dot:"id"`
+	Color *string `dot:"attr=color,omitempty"`
+}
+
+// Edge is an edge between node IDs.
+type Edge struct {
+	From, To int
+	Weight   uint8 `dot:"attr=weight"`
+}
+
+func TestUnmarshalIDs(t *testing.T) {
+	const src = "strict graph { 1 [color=red]; 1 -- 2 [weight=3]; 1 -- 2 }"
+	file, err := dot.ParseString(src)
+	if err != nil {
+		t.Fatalf("unable to parse file; %v", err)
+	}
+	var g Graph
+	if err := dot.Unmarshal(file.Graphs[0], &g); err != nil {
+		t.Fatalf("unable to unmarshal graph; %+v", err)
+	}
+	if !g.Strict || g.Directed {
+		t.Errorf("graph kind mismatch; expected strict undirected graph, got strict=%v directed=%v", g.Strict, g.Directed)
+	}
+	if len(g.Nodes) != 2 || g.Nodes[0].ID != 1 || g.Nodes[0].Color == nil || *g.Nodes[0].Color != "red" || g.Nodes[1].ID != 2 || g.Nodes[1].Color != nil {
+		t.Errorf("nodes mismatch; got %+v", g.Nodes)
+	}
+	want := []Edge{{From: 1, To: 2, Weight: 3}}
+	if len(g.Edges) != 1 || g.Edges[0] != want[0] {
+		t.Errorf("edges mismatch; expected %+v, got %+v", want, g.Edges)
+	}
+	graph, err := dot.Marshal(&g)
+	if err != nil {
+		t.Fatalf("unable to marshal graph; %+v", err)
+	}
+	wantSrc := "strict graph {\n\t1 [color=red]\n\t2\n\t1 -- 2 [weight=3]\n}"
+	if got := graph.String(); got != wantSrc {
+		t.Errorf("graph mismatch; expected `%s`, got `%s`", wantSrc, got)
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	golden := []struct {
+		v    interface{}
+		want string
+	}{
+		{
+			v:    42,
+			want: "unable to marshal int; expected struct or pointer to struct",
+		},
+		{
+			v: struct {
+				Color []string `dot:"attr=color"`
+			}{Color: []string{"red"}},
+			want: "unable to marshal attributes of graph; unable to marshal attribute color; unsupported type []string",
+		},
+		{
+			v: struct {
+				Nodes []Node `dot:"nodes"`
+			}{},
+			want: `invalid struct tag "nodes" of field struct { Nodes []dot_test.Node "dot:\"nodes\"" }.Nodes; unknown field kind "nodes"`,
+		},
+		{
+			v: struct {
+				Edges []Edge `dot:"edge,from=Src"`
+			}{},
+			want: `invalid struct tag "edge,from=Src" of field struct { Edges []dot_test.Edge "dot:\"edge,from=Src\"" }.Edges; missing endpoint field Src in edge struct dot_test.Edge`,
+		},
+		{
+			v: struct {
+				Edges []Flow `dot:"edge,from=Src,to=Dst"`
+			}{Edges: []Flow{{Dst: &Stage{Name: "a"}}}},
+			want: "unable to marshal edge 0 of Edges; unable to marshal source node Src; nil node",
+		},
+		{
+			v:    Pipeline{Stages: []*Stage{{Name: "a", Kind: 7}}},
+			want: "unable to marshal node 0 of Stages; unable to marshal attributes of node \"a\"; unable to marshal attribute shape; invalid stage kind 7",
+		},
+		{
+			v:    Pipeline{Stages: []*Stage{{Name: `C:\`}}},
+			want: `unable to marshal node 0 of Stages; unable to marshal ID of node; unable to quote "C:\\"; odd number of backslashes preceding double quote, newline or end of string`,
+		},
+	}
+	for _, g := range golden {
+		_, err := dot.Marshal(g.v)
+		if err == nil {
+			t.Errorf("%T: expected error, got nil", g.v)
+			continue
+		}
+		if got := err.Error(); got != g.want {
+			t.Errorf("%T: error mismatch; expected `%v`, got `%v`", g.v, g.want, got)
+		}
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		{
+			in:   "digraph { a [shape=circle] }",
+			want: `unable to unmarshal attributes of node "a"; unable to unmarshal attribute shape; invalid stage shape "circle"`,
+		},
+		{
+			in:   "digraph { a [shape=box, workers=many] }",
+			want: `unable to unmarshal attributes of node "a"; unable to unmarshal attribute workers; unable to parse "many" as int`,
+		},
+		{
+			in:   "digraph { a -> b [rate=fast] }",
+			want: `unable to unmarshal attributes of edge from "a" to "b"; unable to unmarshal attribute rate; unable to parse "fast" as float64`,
+		},
+	}
+	for _, g := range golden {
+		file, err := dot.ParseString(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", g.in, err)
+			continue
+		}
+		var p Pipeline
+		err = dot.Unmarshal(file.Graphs[0], &p)
+		if err == nil {
+			t.Errorf("%q: expected error, got nil", g.in)
+			continue
+		}
+		if got := err.Error(); got != g.want {
+			t.Errorf("%q: error mismatch; expected `%v`, got `%v`", g.in, g.want, got)
+		}
+	}
+	if err := dot.Unmarshal(nil, Pipeline{}); err == nil {
+		t.Errorf("expected error for non-pointer value, got nil")
+	}
+}
@@ -0,0 +1,482 @@
+// Package mermaid implements conversion of Graphviz DOT graphs to Mermaid
+// flowcharts.
+//
+// Graph, node and edge attributes are mapped onto their Mermaid counterparts
+// where available; the rank direction of the graph, node shapes and colours,
+// and edge labels, styles and colours. Subgraphs with IDs beginning with
+// "cluster" are mapped onto Mermaid subgraphs. Attributes and subgraphs which
+// cannot be represented in Mermaid are reported as warnings.
+//
+// ref: https://mermaid.js.org/syntax/flowchart.html
+package mermaid
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/internal/enc"
+	"github.com/pkg/errors"
+)
+
+// A Warning reports a DOT feature which cannot be represented in Mermaid.
+type Warning struct {
+	// Graph component of the unrepresentable feature; e.g. `node "A"`.
+	Component string
+	// Description of the unrepresentable feature.
+	Msg string
+}
+
+// String returns the string representation of the warning.
+func (w *Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Component, w.Msg)
+}
+
+// Marshal returns the Mermaid flowchart of the given graph, and warnings for
+// the DOT features which could not be represented.
+func Marshal(graph *ast.Graph) ([]byte, []*Warning, error) {
+	g, err := dot.Resolve(graph)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	c := newConverter(g)
+	c.convert()
+	return c.buf.Bytes(), c.warnings, nil
+}
+
+// A converter keeps track of Mermaid node IDs and style statements.
+type converter struct {
+	// Resolved graph.
+	g *dot.Graph
+	// Output buffer.
+	buf *bytes.Buffer
+	// Warnings of unrepresentable features.
+	warnings []*Warning
+	// ids maps from node to Mermaid node ID.
+	ids map[*dot.Node]string
+	// subIDs maps from cluster to Mermaid subgraph ID.
+	subIDs map[*dot.Subgraph]string
+	// used tracks the Mermaid IDs in use by nodes and subgraphs.
+	used map[string]bool
+	// home maps from node to the cluster in which the node is placed; or nil if
+	// placed in the root graph.
+	home map[*dot.Node]*dot.Subgraph
+	// Style statements, output after nodes and edges.
+	styles []string
+}
+
+// newConverter returns a new converter for the given resolved graph.
+func newConverter(g *dot.Graph) *converter {
+	c := &converter{
+		g:      g,
+		buf:    new(bytes.Buffer),
+		ids:    make(map[*dot.Node]string),
+		subIDs: make(map[*dot.Subgraph]string),
+		used:   make(map[string]bool),
+		home:   make(map[*dot.Node]*dot.Subgraph),
+	}
+	// Node IDs which are valid Mermaid IDs are kept, and take precedence over
+	// generated IDs.
+	for _, n := range g.Nodes {
+		if id := enc.Unquote(n.ID); isMermaidID(id) {
+			c.ids[n] = id
+			c.used[id] = true
+		}
+	}
+	// Place each node in the innermost cluster containing it, and assign
+	// unique IDs to clusters.
+	var walk func(subs []*dot.Subgraph)
+	walk = func(subs []*dot.Subgraph) {
+		for _, sub := range subs {
+			if isCluster(sub) {
+				c.subIDs[sub] = c.unique(mermaidID(enc.Unquote(sub.ID)))
+				for _, n := range sub.Nodes {
+					c.home[n] = sub
+				}
+			}
+			walk(sub.Subgraphs)
+		}
+	}
+	walk(g.Subgraphs)
+	for i, n := range g.Nodes {
+		if _, ok := c.ids[n]; !ok {
+			c.ids[n] = c.unique(fmt.Sprintf("n%d", i))
+		}
+	}
+	return c
+}
+
+// unique returns a Mermaid ID based on the given ID which is not in use by any
+// node or subgraph, and marks it as used.
+func (c *converter) unique(id string) string {
+	for i := 1; c.used[id]; i++ {
+		if cand := fmt.Sprintf("%s_%d", id, i); !c.used[cand] {
+			id = cand
+		}
+	}
+	c.used[id] = true
+	return id
+}
+
+// convert outputs the Mermaid flowchart of the graph.
+func (c *converter) convert() {
+	dir := "TB"
+	for _, attr := range c.g.Attrs {
+		key, val := enc.Unquote(attr.Key), enc.Unquote(attr.Val)
+		switch key {
+		case "rankdir":
+			switch strings.ToUpper(val) {
+			case "TB", "LR", "BT", "RL":
+				dir = strings.ToUpper(val)
+			default:
+				c.warn("graph", "invalid rankdir %q", val)
+			}
+		case "label":
+			// The title is output as a YAML double-quoted string, the escape
+			// sequences of which are a superset of those of Go string literals.
+			fmt.Fprintf(c.buf, "---\ntitle: %q\n---\n", text(c.g.Label()))
+		default:
+			c.warnAttr("graph", key, val)
+		}
+	}
+	fmt.Fprintf(c.buf, "flowchart %s\n", dir)
+	for _, n := range c.g.Nodes {
+		if c.home[n] == nil {
+			c.node("\t", n)
+		}
+	}
+	c.subgraphs("\t", c.g.Subgraphs)
+	for i, e := range c.g.Edges {
+		c.edge(i, e)
+	}
+	for _, style := range c.styles {
+		fmt.Fprintf(c.buf, "\t%s\n", style)
+	}
+}
+
+// subgraphs outputs the given subgraphs, with the given indentation. Clusters
+// are output as Mermaid subgraphs, and the contents of other subgraphs are
+// output in place.
+func (c *converter) subgraphs(indent string, subs []*dot.Subgraph) {
+	for _, sub := range subs {
+		if !isCluster(sub) {
+			component := "anonymous subgraph"
+			if len(sub.ID) > 0 {
+				component = fmt.Sprintf("subgraph %s", sub.ID)
+			}
+			for _, attr := range sub.Attrs {
+				c.warnAttr(component, enc.Unquote(attr.Key), enc.Unquote(attr.Val))
+			}
+			c.subgraphs(indent, sub.Subgraphs)
+			continue
+		}
+		c.cluster(indent, sub)
+	}
+}
+
+// cluster outputs the given cluster as a Mermaid subgraph, with the given
+// indentation.
+func (c *converter) cluster(indent string, sub *dot.Subgraph) {
+	id := c.subIDs[sub]
+	component := fmt.Sprintf("subgraph %s", sub.ID)
+	if orig := enc.Unquote(sub.ID); !isMermaidID(orig) {
+		c.warn(component, "subgraph ID %q is not a valid Mermaid ID; output as %s", orig, id)
+	} else if id != orig {
+		c.warn(component, "subgraph ID %q already in use; output as %s", orig, id)
+	}
+	var title, dir string
+	var style []string
+	for _, attr := range sub.Attrs {
+		key, val := enc.Unquote(attr.Key), enc.Unquote(attr.Val)
+		switch key {
+		case "label":
+			title = text(c.g.SubgraphLabel(sub))
+		case "rankdir":
+			dir = strings.ToUpper(val)
+		case "bgcolor", "fillcolor":
+			style = append(style, "fill:"+val)
+		case "color", "pencolor":
+			style = append(style, "stroke:"+val)
+		case "fontcolor":
+			style = append(style, "color:"+val)
+		default:
+			c.warnAttr(component, key, val)
+		}
+	}
+	if len(title) > 0 {
+		fmt.Fprintf(c.buf, "%ssubgraph %s [\"%s\"]\n", indent, id, escape(title))
+	} else {
+		fmt.Fprintf(c.buf, "%ssubgraph %s\n", indent, id)
+	}
+	if len(dir) > 0 {
+		fmt.Fprintf(c.buf, "%s\tdirection %s\n", indent, dir)
+	}
+	for _, n := range sub.Nodes {
+		if c.home[n] == sub {
+			c.node(indent+"\t", n)
+		}
+	}
+	c.subgraphs(indent+"\t", sub.Subgraphs)
+	fmt.Fprintf(c.buf, "%send\n", indent)
+	if len(style) > 0 {
+		c.styles = append(c.styles, fmt.Sprintf("style %s %s", id, strings.Join(style, ",")))
+	}
+}
+
+// shapes maps from Graphviz node shape to Mermaid node shape delimiters.
+var shapes = map[string][2]string{
+	"box":           {"[", "]"},
+	"rect":          {"[", "]"},
+	"rectangle":     {"[", "]"},
+	"square":        {"[", "]"},
+	"ellipse":       {"([", "])"},
+	"oval":          {"([", "])"},
+	"circle":        {"((", "))"},
+	"doublecircle":  {"(((", ")))"},
+	"diamond":       {"{", "}"},
+	"hexagon":       {"{{", "}}"},
+	"parallelogram": {"[/", "/]"},
+	"trapezium":     {"[/", `\]`},
+	"invtrapezium":  {`[\`, "/]"},
+	"cylinder":      {"[(", ")]"},
+	"cds":           {">", "]"},
+}
+
+// node outputs the given node, with the given indentation.
+func (c *converter) node(indent string, n *dot.Node) {
+	component := fmt.Sprintf("node %s", n.ID)
+	id := c.ids[n]
+	label := text(c.g.NodeLabel(n))
+	shape := "ellipse"
+	var rounded, filled bool
+	var fillcolor, color string
+	var style []string
+	for _, attr := range n.Attrs {
+		key, val := enc.Unquote(attr.Key), enc.Unquote(attr.Val)
+		switch key {
+		case "label":
+			if enc.IsHTML(attr.Val) {
+				c.warn(component, "HTML-like label %s is output as plain text", attr.Val)
+			}
+		case "shape":
+			shape = val
+		case "style":
+			for _, s := range strings.Split(val, ",") {
+				switch s = strings.TrimSpace(s); s {
+				case "filled":
+					filled = true
+				case "rounded":
+					rounded = true
+				case "dashed":
+					style = append(style, "stroke-dasharray:5 5")
+				case "dotted":
+					style = append(style, "stroke-dasharray:1 3")
+				case "bold":
+					style = append(style, "stroke-width:2px")
+				case "solid":
+					// default style.
+				default:
+					c.warn(component, "unsupported style %q", s)
+				}
+			}
+		case "fillcolor":
+			fillcolor = val
+		case "color":
+			color = val
+			style = append(style, "stroke:"+val)
+		case "fontcolor":
+			style = append(style, "color:"+val)
+		case "penwidth":
+			style = append(style, "stroke-width:"+val+"px")
+		default:
+			c.warnAttr(component, key, val)
+		}
+	}
+	// Nodes are only filled if style=filled. The fill colour defaults to the
+	// colour of the node, or light grey if neither is set.
+	if filled {
+		switch {
+		case len(fillcolor) > 0:
+			style = append(style, "fill:"+fillcolor)
+		case len(color) > 0:
+			style = append(style, "fill:"+color)
+		default:
+			style = append(style, "fill:lightgrey")
+		}
+	}
+	delims, ok := shapes[strings.ToLower(shape)]
+	if !ok {
+		c.warn(component, "unsupported shape %q; output as box", shape)
+		delims = shapes["box"]
+	}
+	if rounded && delims == shapes["box"] {
+		delims = [2]string{"(", ")"}
+	}
+	fmt.Fprintf(c.buf, "%s%s%s\"%s\"%s\n", indent, id, delims[0], escape(label), delims[1])
+	if len(style) > 0 {
+		c.styles = append(c.styles, fmt.Sprintf("style %s %s", id, strings.Join(style, ",")))
+	}
+}
+
+// edge outputs the given edge, with the given index.
+func (c *converter) edge(index int, e *dot.Edge) {
+	component := fmt.Sprintf("edge %s -> %s", e.From.ID, e.To.ID)
+	if !c.g.Directed {
+		component = fmt.Sprintf("edge %s -- %s", e.From.ID, e.To.ID)
+	}
+	if e.FromPort != nil || e.ToPort != nil {
+		c.warn(component, "ports are not supported")
+	}
+	from, to := c.ids[e.From], c.ids[e.To]
+	label := text(c.g.EdgeLabel(e))
+	// Line kind: "-" (normal), "." (dotted) or "=" (thick).
+	line := "-"
+	head, tail := c.g.Directed, false
+	var style []string
+	for _, attr := range e.Attrs {
+		key, val := enc.Unquote(attr.Key), enc.Unquote(attr.Val)
+		switch key {
+		case "label":
+			if enc.IsHTML(attr.Val) {
+				c.warn(component, "HTML-like label %s is output as plain text", attr.Val)
+			}
+		case "style":
+			for _, s := range strings.Split(val, ",") {
+				switch s = strings.TrimSpace(s); s {
+				case "dashed", "dotted":
+					line = "."
+				case "bold":
+					line = "="
+				case "solid":
+					// default style.
+				case "invis":
+					style = append(style, "stroke-width:0")
+				default:
+					c.warn(component, "unsupported style %q", s)
+				}
+			}
+		case "dir":
+			switch val {
+			case "forward":
+				head, tail = true, false
+			case "back":
+				head, tail = false, true
+			case "both":
+				head, tail = true, true
+			case "none":
+				head, tail = false, false
+			default:
+				c.warn(component, "invalid dir %q", val)
+			}
+		case "color":
+			style = append(style, "stroke:"+val)
+		case "penwidth":
+			style = append(style, "stroke-width:"+val+"px")
+		default:
+			c.warnAttr(component, key, val)
+		}
+	}
+	if tail && !head {
+		// Mermaid has no left-pointing arrows; reverse the edge.
+		from, to = to, from
+		head, tail = true, false
+	}
+	fmt.Fprintf(c.buf, "\t%s %s %s\n", from, arrow(line, head, tail, label), to)
+	if len(style) > 0 {
+		c.styles = append(c.styles, fmt.Sprintf("linkStyle %d %s", index, strings.Join(style, ",")))
+	}
+}
+
+// arrow returns the Mermaid link of the given line kind, arrowheads and label.
+func arrow(line string, head, tail bool, label string) string {
+	var link string
+	switch line {
+	case ".":
+		link = "-.-"
+		if head {
+			link = "-.->"
+		}
+	case "=":
+		link = "==="
+		if head {
+			link = "==>"
+		}
+	default:
+		link = "---"
+		if head {
+			link = "-->"
+		}
+	}
+	if tail {
+		link = "<" + link
+	}
+	if len(label) > 0 {
+		link += fmt.Sprintf("|\"%s\"|", escape(label))
+	}
+	return link
+}
+
+// text returns the text of the given label lines, separated by line breaks.
+func text(lines []dot.Line) string {
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		texts[i] = line.Text
+	}
+	return strings.Join(texts, "<br/>")
+}
+
+// escape escapes the double quotes of the given Mermaid text.
+func escape(s string) string {
+	return strings.Replace(s, `"`, "#quot;", -1)
+}
+
+// warn records a warning of the given graph component.
+func (c *converter) warn(component, format string, args ...interface{}) {
+	c.warnings = append(c.warnings, &Warning{Component: component, Msg: fmt.Sprintf(format, args...)})
+}
+
+// warnAttr records a warning of an unsupported attribute of the given graph
+// component.
+func (c *converter) warnAttr(component, key, val string) {
+	c.warn(component, "unsupported attribute %s=%q", key, val)
+}
+
+// isCluster reports whether the given subgraph is a cluster.
+func isCluster(sub *dot.Subgraph) bool {
+	return strings.HasPrefix(enc.Unquote(sub.ID), "cluster")
+}
+
+// isMermaidID reports whether s is a valid Mermaid node ID.
+func isMermaidID(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	switch strings.ToLower(s) {
+	case "end", "graph", "flowchart", "subgraph", "direction", "style", "class", "classdef", "click", "linkstyle":
+		return false
+	}
+	for _, r := range s {
+		if !isMermaidIDChar(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// mermaidID returns the given ID with invalid characters of Mermaid IDs
+// replaced by underscores.
+func mermaidID(s string) string {
+	return strings.Map(func(r rune) rune {
+		if isMermaidIDChar(r) {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// isMermaidIDChar reports whether r is a valid character of Mermaid node IDs.
+func isMermaidIDChar(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '_'
+}
@@ -0,0 +1,145 @@
+package mermaid_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/mermaid"
+)
+
+func TestMarshal(t *testing.T) {
+	golden := []struct {
+		in       string
+		out      string
+		warnings []string
+	}{
+		{
+			in:  "testdata/cluster.dot",
+			out: "testdata/cluster.mmd",
+			warnings: []string{
+				`node "end": unsupported attribute fontsize="20"`,
+				`node "my node": unsupported shape "record"; output as box`,
+				`anonymous subgraph: unsupported attribute rank="same"`,
+				`edge start -> b0: ports are not supported`,
+			},
+		},
+		{
+			in:  "../internal/testdata/attr_stmt.dot",
+			out: "testdata/attr_stmt.mmd",
+			warnings: []string{
+				`graph: unsupported attribute bgcolor="transparent"`,
+				`edge A -> B: unsupported attribute minlen="2"`,
+			},
+		},
+	}
+	for _, g := range golden {
+		file, err := dot.ParseFile(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", g.in, err)
+			continue
+		}
+		buf, warnings, err := mermaid.Marshal(file.Graphs[0])
+		if err != nil {
+			t.Errorf("%q: unable to convert graph; %v", g.in, err)
+			continue
+		}
+		want, err := ioutil.ReadFile(g.out)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", g.in, err)
+			continue
+		}
+		if !bytes.Equal(buf, want) {
+			t.Errorf("%q: flowchart mismatch; expected `%s`, got `%s`", g.in, want, buf)
+		}
+		if len(warnings) != len(g.warnings) {
+			t.Errorf("%q: number of warnings mismatch; expected %d, got %d (%v)", g.in, len(g.warnings), len(warnings), warnings)
+			continue
+		}
+		for i, w := range warnings {
+			if got := w.String(); got != g.warnings[i] {
+				t.Errorf("%q: warning mismatch; expected `%s`, got `%s`", g.in, g.warnings[i], got)
+			}
+		}
+	}
+}
+
+func TestMarshalIDs(t *testing.T) {
+	golden := []struct {
+		in       string
+		want     string
+		warnings []string
+	}{
+		{
+			// Generated node IDs avoid node IDs in use.
+			in:   `digraph { "a b" -> n0 }`,
+			want: "flowchart TB\n\tn0_1([\"a b\"])\n\tn0([\"n0\"])\n\tn0_1 --> n0\n",
+		},
+		{
+			// Cluster IDs avoid node IDs in use, and each other.
+			in:   `digraph { cluster_x; n1; subgraph cluster_x { a } subgraph "cluster x" { "b c" } }`,
+			want: "flowchart TB\n\tcluster_x([\"cluster_x\"])\n\tn1([\"n1\"])\n\tsubgraph cluster_x_1\n\t\ta([\"a\"])\n\tend\n\tsubgraph cluster_x_2\n\t\tn3([\"b c\"])\n\tend\n",
+			warnings: []string{
+				`subgraph cluster_x: subgraph ID "cluster_x" already in use; output as cluster_x_1`,
+				`subgraph "cluster x": subgraph ID "cluster x" is not a valid Mermaid ID; output as cluster_x_2`,
+			},
+		},
+	}
+	for _, g := range golden {
+		file, err := dot.ParseString(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", g.in, err)
+			continue
+		}
+		buf, warnings, err := mermaid.Marshal(file.Graphs[0])
+		if err != nil {
+			t.Errorf("%q: unable to convert graph; %v", g.in, err)
+			continue
+		}
+		if got := string(buf); got != g.want {
+			t.Errorf("%q: flowchart mismatch; expected %q, got %q", g.in, g.want, got)
+		}
+		var got []string
+		for _, w := range warnings {
+			got = append(got, w.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(g.warnings, "\n") {
+			t.Errorf("%q: warnings mismatch; expected %q, got %q", g.in, g.warnings, got)
+		}
+	}
+}
+
+func TestMarshalLabels(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		{
+			// Titles are quoted YAML strings.
+			in:   `digraph { label="a: b # c" }`,
+			want: "---\ntitle: \"a: b # c\"\n---\nflowchart TB\n",
+		},
+		{
+			// Escape sequences of edge labels.
+			in:   `digraph G { a -> b [label="\E (\T to \H in \G)"] }`,
+			want: "flowchart TB\n\ta([\"a\"])\n\tb([\"b\"])\n\ta -->|\"a->b (a to b in G)\"| b\n",
+		},
+	}
+	for _, g := range golden {
+		file, err := dot.ParseString(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", g.in, err)
+			continue
+		}
+		buf, _, err := mermaid.Marshal(file.Graphs[0])
+		if err != nil {
+			t.Errorf("%q: unable to convert graph; %v", g.in, err)
+			continue
+		}
+		if got := string(buf); got != g.want {
+			t.Errorf("%q: flowchart mismatch; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package dot
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// === [ Parse options ] =======================================================
+
+// ParseOptions specifies options of the parser. The zero value specifies no
+// resource limits other than the default maximum nesting depth and numbers of
+// resolved nodes and edges, and the default semantic checking.
+type ParseOptions struct {
+	// Charset of the DOT file; UTF-8 or latin1, or any of their aliases as
+	// recognized by Graphviz (e.g. ISO-8859-1). If empty, the DOT file is UTF-8
+	// encoded if starting with a byte order mark, and otherwise encoded as
+	// specified by the charset attribute of the top-level graph attribute
+	// statements of its first graph; UTF-8 by default.
+	Charset string
+
+	// Maximum size in bytes of the DOT file; or 0 for no limit.
+	MaxSize int64
+	// Maximum nesting depth of subgraphs; or 0 for DefaultMaxDepth. Subgraphs
+	// of graphs are at depth 1.
+	MaxDepth int
+	// Maximum number of statements, including the statements of subgraphs; or
+	// 0 for no limit.
+	MaxStmts int
+	// Maximum length in bytes of attribute values, including any quotes; or 0
+	// for no limit.
+	MaxAttrLen int
+	// Maximum number of nodes of each graph, as resolved by the semantic
+	// checker, counting the nodes of each subgraph, which include the nodes of
+	// its nested subgraphs; or 0 for DefaultMaxNodes.
+	MaxNodes int
+	// Maximum number of edges of each graph, as resolved by the semantic
+	// checker, counting the edges of each subgraph, which include the edges of
+	// its nested subgraphs, and the edges between each pair of nodes of
+	// subgraph endpoints (e.g. 4 edges of {a b} -> {c d}); or 0 for
+	// DefaultMaxEdges.
+	MaxEdges int
+
+	// Semantic checking of the DOT file.
+	Check CheckMode
+
+	// If non-nil, the spans of the graphs, subgraphs, nodes, edges and
+	// attributes of the AST are recorded in Positions.
+	Positions Positions
+}
+
+// Positions maps from nodes of an AST to their spans in the DOT file, as
+// transcoded to UTF-8. The span of a graph or subgraph is the span of its ID;
+// or of its first token if anonymous. The span of an edge is the span of its
+// edge operator.
+type Positions map[interface{}]Span
+
+// A Span is a range of bytes of a DOT file.
+type Span struct {
+	// Byte offset of the span.
+	Offset int
+	// Length in bytes of the span.
+	Len int
+}
+
+// DefaultMaxDepth is the default maximum nesting depth of subgraphs, which
+// bounds the stack use of the recursive-descent parser.
+const DefaultMaxDepth = 10000
+
+// DefaultMaxNodes and DefaultMaxEdges are the default maximum numbers of nodes
+// and edges of each graph, as resolved by the semantic checker, which bound the
+// time and memory of semantic checking.
+const (
+	DefaultMaxNodes = 1 << 20
+	DefaultMaxEdges = 1 << 20
+)
+
+// resolveLimits returns the resource limits of resolving graphs for the
+// semantic checker.
+func (opts ParseOptions) resolveLimits() resolveLimits {
+	limits := resolveLimits{maxNodes: opts.MaxNodes, maxEdges: opts.MaxEdges}
+	if limits.maxNodes == 0 {
+		limits.maxNodes = DefaultMaxNodes
+	}
+	if limits.maxEdges == 0 {
+		limits.maxEdges = DefaultMaxEdges
+	}
+	return limits
+}
+
+// CheckMode specifies the semantic checking of parsed DOT files.
+type CheckMode uint8
+
+// Semantic checking modes.
+const (
+	// Fail at errors of the semantic checker.
+	CheckErrors CheckMode = iota
+	// Skip semantic checking.
+	CheckNone
+	// Fail at errors and warnings of the semantic checker.
+	CheckStrict
+)
+
+// A LimitError reports a DOT file exceeding a resource limit of the parser.
+type LimitError struct {
+	// Name of the limit; as the field of ParseOptions (e.g. MaxDepth).
+	Limit string
+	// Value of the limit.
+	Max int64
+	// Line at which the limit was exceeded; or 0 if not applicable.
+	Line int
+}
+
+// Error returns the string representation of the limit error.
+func (e *LimitError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("DOT file exceeds %s limit of %d", e.Limit, e.Max)
+	}
+	return fmt.Sprintf("DOT file exceeds %s limit of %d at line %d", e.Limit, e.Max, e.Line)
+}
+
+// readChunk specifies the size of reads of DOT files.
+const readChunk = 64 * 1024
+
+// readAll reads from r until the end of the file, the given maximum size is
+// exceeded, or the context is cancelled. A maximum size of 0 specifies no
+// limit.
+func readAll(ctx context.Context, r io.Reader, maxSize int64) ([]byte, error) {
+	var buf []byte
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if len(buf) == cap(buf) {
+			buf = append(buf, make([]byte, readChunk)...)[:len(buf)]
+		}
+		n, err := r.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if maxSize > 0 && int64(len(buf)) > maxSize {
+			return nil, errors.WithStack(&LimitError{Limit: "MaxSize", Max: maxSize})
+		}
+		if err == io.EOF {
+			return buf, nil
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package dot_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/graphism/dot"
+	"github.com/pkg/errors"
+)
+
+func TestParseWithOptions(t *testing.T) {
+	golden := []struct {
+		in   string
+		opts dot.ParseOptions
+		want string
+	}{
+		{
+			in:   "graph { a -- b }",
+			opts: dot.ParseOptions{MaxSize: 16},
+		},
+		{
+			in:   "graph { a -- bc }",
+			opts: dot.ParseOptions{MaxSize: 16},
+			want: "DOT file exceeds MaxSize limit of 16",
+		},
+		{
+			in:   "graph { { { a } } b -- { c } }",
+			opts: dot.ParseOptions{MaxDepth: 2},
+		},
+		{
+			in:   "graph {\n\t{ { {\n\ta } } }\n}",
+			opts: dot.ParseOptions{MaxDepth: 2},
+			want: "DOT file exceeds MaxDepth limit of 2 at line 2",
+		},
+		{
+			in:   "graph { a; { b; c } }",
+			opts: dot.ParseOptions{MaxStmts: 4},
+		},
+		{
+			in:   "graph { a; { b; c }\n d }",
+			opts: dot.ParseOptions{MaxStmts: 4},
+			want: "DOT file exceeds MaxStmts limit of 4 at line 2",
+		},
+		{
+			in:   `graph { label="abc"; a [label=abcde] }`,
+			opts: dot.ParseOptions{MaxAttrLen: 5},
+		},
+		{
+			in:   `graph { a [label="abcd"] }`,
+			opts: dot.ParseOptions{MaxAttrLen: 5},
+			want: "DOT file exceeds MaxAttrLen limit of 5 at line 1",
+		},
+		{
+			in:   `graph { label="abcd" }`,
+			opts: dot.ParseOptions{MaxAttrLen: 5},
+			want: "DOT file exceeds MaxAttrLen limit of 5 at line 1",
+		},
+		{
+			in:   "graph { a -> b }",
+			want: `undirected graph "" contains directed edge from "a" to "b"`,
+		},
+		{
+			in:   "graph { a -> b }",
+			opts: dot.ParseOptions{Check: dot.CheckNone},
+		},
+		{
+			in:   "digraph { a:p -> b }",
+			opts: dot.ParseOptions{Check: dot.CheckStrict},
+			want: `warning: port "p" of node "a" ignored; shape ellipse has no fields`,
+		},
+		{
+			in:   `digraph { a:f2 -> b; a [shape=record label="<f0>x|<f1>y"] }`,
+			opts: dot.ParseOptions{Check: dot.CheckStrict},
+			want: `warning: invalid port "f2" of node "a"; no such record field`,
+		},
+	}
+	for _, g := range golden {
+		_, err := dot.ParseWithOptions(context.Background(), strings.NewReader(g.in), g.opts)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != g.want {
+			t.Errorf("%q: error mismatch; expected `%v`, got `%v`", g.in, g.want, got)
+		}
+	}
+}
+
+func TestParseDeepNesting(t *testing.T) {
+	// Subgraphs nested beyond the default maximum depth are reported as errors,
+	// rather than overflowing the stack of the parser.
+	const n = 1 << 20
+	in := "graph {\n" + strings.Repeat("{", n) + strings.Repeat("}", n) + "\n}"
+	_, err := dot.ParseBytes([]byte(in))
+	want := "DOT file exceeds MaxDepth limit of 10000 at line 2"
+	if err == nil || err.Error() != want {
+		t.Errorf("error mismatch; expected `%v`, got `%v`", want, err)
+	}
+	d := dot.NewDecoder(strings.NewReader(in))
+	if _, err := d.NextGraph(); err != nil {
+		t.Fatalf("unable to decode graph; %v", err)
+	}
+	if _, err := d.Next(); err == nil || err.Error() != want {
+		t.Errorf("error mismatch; expected `%v`, got `%v`", want, err)
+	}
+	// Subgraphs nested at the default maximum depth are parsed.
+	in = "graph {" + strings.Repeat("{", dot.DefaultMaxDepth) + strings.Repeat("}", dot.DefaultMaxDepth) + "}"
+	if _, err := dot.ParseBytes([]byte(in)); err != nil {
+		t.Errorf("unable to parse file; %v", err)
+	}
+}
+
+func TestParseResolveLimits(t *testing.T) {
+	// Deeply nested subgraphs, each with a node, of which the nodes of nested
+	// subgraphs are nodes of the enclosing subgraphs.
+	deep := &strings.Builder{}
+	deep.WriteString("digraph {")
+	for i := 0; i < dot.DefaultMaxDepth-1; i++ {
+		fmt.Fprintf(deep, " { n%d", i)
+	}
+	deep.WriteString(strings.Repeat(" }", dot.DefaultMaxDepth-1) + " }")
+	// Edges between each pair of nodes of subgraph endpoints.
+	cross := &strings.Builder{}
+	cross.WriteString("digraph { {")
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(cross, " a%d", i)
+	}
+	cross.WriteString(" } -> {")
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(cross, " b%d", i)
+	}
+	cross.WriteString(" } }")
+	golden := []struct {
+		in   string
+		opts dot.ParseOptions
+		want string
+	}{
+		{in: deep.String(), want: "DOT file exceeds MaxNodes limit of 1048576"},
+		{in: cross.String(), want: "DOT file exceeds MaxEdges limit of 1048576"},
+		{in: cross.String(), opts: dot.ParseOptions{Check: dot.CheckStrict}, want: "DOT file exceeds MaxEdges limit of 1048576"},
+		{in: "digraph { { a b } -> { c d } }", opts: dot.ParseOptions{MaxEdges: 3}, want: "DOT file exceeds MaxEdges limit of 3"},
+		{in: "digraph { { a b } -> { c d } }", opts: dot.ParseOptions{MaxEdges: 4}, want: ""},
+		{in: "digraph { a { a { a } } b }", opts: dot.ParseOptions{MaxNodes: 3}, want: "DOT file exceeds MaxNodes limit of 3"},
+		{in: "digraph { a { a { a } } }", opts: dot.ParseOptions{MaxNodes: 3}, want: ""},
+		// Limits are not applied without semantic checking.
+		{in: cross.String(), opts: dot.ParseOptions{Check: dot.CheckNone}, want: ""},
+	}
+	for _, g := range golden {
+		_, err := dot.ParseBytesWithOptions([]byte(g.in), g.opts)
+		got := ""
+		if err != nil {
+			got = err.Error()
+			if _, ok := errors.Cause(err).(*dot.LimitError); !ok {
+				t.Errorf("%.40q: error type mismatch; expected *dot.LimitError, got %T", g.in, errors.Cause(err))
+			}
+		}
+		if got != g.want {
+			t.Errorf("%.40q: error mismatch; expected `%v`, got `%v`", g.in, g.want, got)
+		}
+	}
+}
+
+func TestParseWithOptionsCancel(t *testing.T) {
+	// The context is cancelled after reading the DOT file, while parsing.
+	ctx, cancel := context.WithCancel(context.Background())
+	in := "graph {" + strings.Repeat(" a -- b", 10000) + " }"
+	r := &cancelReader{r: strings.NewReader(in), cancel: cancel}
+	_, err := dot.ParseWithOptions(ctx, r, dot.ParseOptions{})
+	if errors.Cause(err) != context.Canceled {
+		t.Errorf("error mismatch; expected %v, got %v", context.Canceled, err)
+	}
+	// The context is cancelled while resolving edges for the semantic checker.
+	var from, to []string
+	for i := 0; i < 1000; i++ {
+		from = append(from, fmt.Sprintf("a%d", i))
+		to = append(to, fmt.Sprintf("b%d", i))
+	}
+	in = fmt.Sprintf("digraph { { %s } -> { %s } }", strings.Join(from, " "), strings.Join(to, " "))
+	_, err = dot.ParseWithOptions(&countdownContext{Context: context.Background(), n: 100}, strings.NewReader(in), dot.ParseOptions{})
+	if errors.Cause(err) != context.Canceled {
+		t.Errorf("error mismatch; expected %v, got %v", context.Canceled, err)
+	}
+}
+
+// A countdownContext is cancelled once its error has been polled a given
+// number of times.
+type countdownContext struct {
+	context.Context
+	// Number of polls before cancellation.
+	n int
+}
+
+func (ctx *countdownContext) Err() error {
+	if ctx.n == 0 {
+		return context.Canceled
+	}
+	ctx.n--
+	return nil
+}
+
+// A cancelReader cancels a context at the end of the file of its underlying
+// reader.
+type cancelReader struct {
+	r      io.Reader
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err == io.EOF {
+		r.cancel()
+	}
+	return n, err
+}
@@ -0,0 +1,630 @@
+package dot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/internal/astx"
+	"github.com/graphism/dot/internal/scanner"
+	"github.com/graphism/dot/internal/token"
+	"github.com/pkg/errors"
+)
+
+// Token types.
+var (
+	tokLBrace   = token.TokMap.Type("{")
+	tokRBrace   = token.TokMap.Type("}")
+	tokStrict   = token.TokMap.Type("strict")
+	tokGraph    = token.TokMap.Type("graphx")
+	tokDigraph  = token.TokMap.Type("digraph")
+	tokSemi     = token.TokMap.Type(";")
+	tokUndirect = token.TokMap.Type("--")
+	tokDirect   = token.TokMap.Type("->")
+	tokNode     = token.TokMap.Type("node")
+	tokEdge     = token.TokMap.Type("edge")
+	tokLBrack   = token.TokMap.Type("[")
+	tokRBrack   = token.TokMap.Type("]")
+	tokComma    = token.TokMap.Type(",")
+	tokEqual    = token.TokMap.Type("=")
+	tokSubgraph = token.TokMap.Type("subgraph")
+	tokColon    = token.TokMap.Type(":")
+	tokID       = token.TokMap.Type("id")
+)
+
+// === [ Syntax errors ] =======================================================
+
+// A SyntaxError is a syntax error of a DOT file.
+type SyntaxError struct {
+	// Byte offset of the erroneous token.
+	Offset int
+	// Length in bytes of the erroneous token.
+	Len int
+	// Line and column of the erroneous token. Columns count runes, and tabs
+	// advance columns by 4.
+	Line, Column int
+	// Error description.
+	Msg string
+}
+
+// Error returns the string representation of the syntax error.
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// === [ Parser ] ==============================================================
+
+// A parser is a recursive-descent parser of Graphviz DOT files, as specified by
+// the grammar of internal/dot.bnf.
+type parser struct {
+	// Context of the parser.
+	ctx context.Context
+	// Scanner of the DOT file.
+	s *scanner.Scanner
+	// Options of the parser.
+	opts ParseOptions
+	// Current token; or nil before the first token is scanned.
+	tok *token.Token
+	// End offset of the previous token.
+	end int
+	// Nesting depth of subgraphs.
+	depth int
+	// Number of statements.
+	nstmts int
+	// Number of parse steps.
+	steps int
+}
+
+// newParser returns a new parser of the given DOT file, using the given
+// context and options.
+func newParser(ctx context.Context, s *scanner.Scanner, opts ParseOptions) *parser {
+	return &parser{ctx: ctx, s: s, opts: opts}
+}
+
+// next advances to the next token.
+func (p *parser) next() {
+	if p.tok != nil {
+		p.end = p.tok.Offset + len(p.tok.Lit)
+	}
+	p.tok = p.s.Scan()
+}
+
+// record records the span of the given AST node, from the given start offset
+// to the end of the previous token, if positions are recorded.
+func (p *parser) record(node interface{}, start int) {
+	if p.opts.Positions != nil {
+		p.opts.Positions[node] = Span{Offset: start, Len: p.end - start}
+	}
+}
+
+// got advances to the next token and returns true if the current token is of
+// the given type, and returns false otherwise.
+func (p *parser) got(typ token.Type) bool {
+	if p.tok.Type == typ {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// pollInterval specifies the number of parse steps between polls of the
+// context of the parser.
+const pollInterval = 1024
+
+// poll returns the error of the context of the parser, which is polled at
+// intervals of parse steps.
+func (p *parser) poll() error {
+	p.steps++
+	if p.steps%pollInterval != 0 {
+		return nil
+	}
+	return errors.WithStack(p.ctx.Err())
+}
+
+// limit returns a limit error for the given limit, exceeded at the current
+// token.
+func (p *parser) limit(name string, max int) error {
+	return errors.WithStack(&LimitError{Limit: name, Max: int64(max), Line: p.tok.Line})
+}
+
+// isEdgeOp reports whether the current token is an edge operator.
+func (p *parser) isEdgeOp() bool {
+	return p.tok.Type == tokUndirect || p.tok.Type == tokDirect
+}
+
+// errorf returns a syntax error at the current token, with the given message.
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return errorAt(p.tok, format, args...)
+}
+
+// unexpected returns a syntax error for the current token, given a description
+// of the expected tokens.
+func (p *parser) unexpected(expected string) error {
+	return p.errorf("unexpected %s; expected %s", describe(p.tok), expected)
+}
+
+// missing returns a syntax error for a missing part of a production, located at
+// the current token.
+func (p *parser) missing(format string, args ...interface{}) error {
+	return p.errorf("missing %s; got %s", fmt.Sprintf(format, args...), describe(p.tok))
+}
+
+// errorAt returns a syntax error at the given token, with the given message.
+func errorAt(tok *token.Token, format string, args ...interface{}) error {
+	return errors.WithStack(&SyntaxError{
+		Offset: tok.Offset,
+		Len:    len(tok.Lit),
+		Line:   tok.Line,
+		Column: tok.Column,
+		Msg:    fmt.Sprintf(format, args...),
+	})
+}
+
+// maxLit specifies the maximum length of token literals in error messages.
+const maxLit = 32
+
+// describe returns a description of the given token.
+func describe(tok *token.Token) string {
+	lit := string(tok.Lit)
+	if len(lit) > maxLit {
+		lit = lit[:maxLit] + "..."
+	}
+	switch tok.Type {
+	case token.EOF:
+		return "end of file"
+	case token.INVALID:
+		switch lit[0] {
+		case '"':
+			if tok.Lit[len(tok.Lit)-1] != '"' {
+				return fmt.Sprintf("unterminated or invalid string %s", lit)
+			}
+		case '<':
+			return fmt.Sprintf("unterminated or invalid HTML string %s", lit)
+		case '#':
+			return "unterminated comment"
+		case '/':
+			if len(lit) > 1 && (lit[1] == '/' || lit[1] == '*') {
+				return "unterminated comment"
+			}
+		}
+		return fmt.Sprintf("invalid token %q", lit)
+	case tokID:
+		return fmt.Sprintf("ID %s", lit)
+	case tokStrict, tokGraph, tokDigraph, tokNode, tokEdge, tokSubgraph:
+		return fmt.Sprintf("keyword %q", lit)
+	}
+	return fmt.Sprintf("%q", lit)
+}
+
+// === [ Files ] ===============================================================
+
+// parseFile parses a DOT file.
+//
+//    File : Graph { Graph }
+func (p *parser) parseFile() (*ast.File, error) {
+	p.next()
+	file := &ast.File{}
+	for {
+		graph, err := p.parseGraph()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		file.Graphs = append(file.Graphs, graph)
+		if p.tok.Type == token.EOF {
+			return file, nil
+		}
+	}
+}
+
+// === [ Graphs ] ==============================================================
+
+// parseGraph parses a graph.
+//
+//    Graph : [ "strict" ] ( "graph" | "digraph" ) [ ID ] "{" [ StmtList ] "}"
+func (p *parser) parseGraph() (*ast.Graph, error) {
+	line := p.tok.Line
+	graph, err := p.parseGraphHeader()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for {
+		stmt, err := p.parseListStmt("graph", line)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if stmt == nil {
+			return graph, nil
+		}
+		graph.Stmts = append(graph.Stmts, stmt)
+	}
+}
+
+// parseGraphHeader parses the header of a graph, up to and including its
+// opening brace.
+func (p *parser) parseGraphHeader() (*ast.Graph, error) {
+	graph := &ast.Graph{}
+	if p.got(tokStrict) {
+		graph.Strict = true
+		if p.tok.Type != tokGraph && p.tok.Type != tokDigraph {
+			return nil, p.unexpected(`"graph" or "digraph" after "strict"`)
+		}
+	}
+	switch p.tok.Type {
+	case tokGraph:
+	case tokDigraph:
+		graph.Directed = true
+	default:
+		return nil, p.unexpected("graph")
+	}
+	start := p.tok.Offset
+	p.next()
+	switch p.tok.Type {
+	case tokID:
+		start = p.tok.Offset
+		id, err := p.parseID("graph ID")
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		graph.ID = id
+		p.record(graph, start)
+		if !p.got(tokLBrace) {
+			return nil, p.missing(`"{" of graph %s`, id)
+		}
+	case tokLBrace:
+		p.record(graph, start)
+		p.next()
+	default:
+		return nil, p.unexpected(`graph ID or "{"`)
+	}
+	return graph, nil
+}
+
+// === [ Statements ] ==========================================================
+
+// parseListStmt parses the next statement of a statement list, and returns nil
+// at the closing brace of the statement list. The statement list belongs to
+// the given graph or subgraph, which is opened at the given line.
+//
+//    StmtList : Stmt [ ";" ] [ StmtList ]
+func (p *parser) parseListStmt(owner string, line int) (ast.Stmt, error) {
+	switch p.tok.Type {
+	case tokRBrace:
+		p.next()
+		return nil, nil
+	case token.EOF:
+		return nil, p.errorf(`unterminated %s opened at line %d; expected "}"`, owner, line)
+	}
+	return p.parseStmt()
+}
+
+// parseStmt parses a statement, and its optional trailing semicolon.
+//
+//    Stmt : NodeStmt | EdgeStmt | AttrStmt | Attr | Subgraph
+func (p *parser) parseStmt() (ast.Stmt, error) {
+	if err := p.poll(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	p.nstmts++
+	if p.opts.MaxStmts > 0 && p.nstmts > p.opts.MaxStmts {
+		return nil, p.limit("MaxStmts", p.opts.MaxStmts)
+	}
+	var stmt ast.Stmt
+	var err error
+	switch p.tok.Type {
+	case tokGraph, tokNode, tokEdge:
+		stmt, err = p.parseAttrStmt()
+	case tokID:
+		stmt, err = p.parseIDStmt()
+	case tokSubgraph, tokLBrace:
+		stmt, err = p.parseSubgraphStmt()
+	default:
+		return nil, p.unexpected(`statement or "}"`)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	p.got(tokSemi)
+	return stmt, nil
+}
+
+// parseIDStmt parses a statement starting with an ID; a node statement, an
+// edge statement or an attribute.
+//
+//    NodeStmt : Node [ AttrList ]
+func (p *parser) parseIDStmt() (ast.Stmt, error) {
+	start := p.tok.Offset
+	id, err := p.parseID("ID")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if p.got(tokEqual) {
+		val, err := p.parseAttrVal(id)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		attr := &ast.Attr{Key: id, Val: val}
+		p.record(attr, start)
+		return attr, nil
+	}
+	node, err := p.parseNode(id, start)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if p.isEdgeOp() {
+		return p.parseEdgeStmt(node)
+	}
+	attrs, err := p.parseOptAttrList()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &ast.NodeStmt{Node: node, Attrs: attrs}, nil
+}
+
+// parseSubgraphStmt parses a statement starting with a subgraph; a subgraph or
+// an edge statement.
+func (p *parser) parseSubgraphStmt() (ast.Stmt, error) {
+	sub, err := p.parseSubgraph()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if p.isEdgeOp() {
+		return p.parseEdgeStmt(sub)
+	}
+	return sub, nil
+}
+
+// --- [ Edge statement ] ------------------------------------------------------
+
+// parseEdgeStmt parses the remainder of an edge statement, following its source
+// vertex.
+//
+//    EdgeStmt : ( Node | Subgraph ) Edge [ AttrList ]
+//    Edge : ( "--" | "->" ) ( Node | Subgraph ) [ Edge ]
+func (p *parser) parseEdgeStmt(from ast.Vertex) (*ast.EdgeStmt, error) {
+	stmt := &ast.EdgeStmt{From: from}
+	to := &stmt.To
+	for p.isEdgeOp() {
+		if err := p.poll(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		edge := &ast.Edge{Directed: p.tok.Type == tokDirect}
+		op := string(p.tok.Lit)
+		start := p.tok.Offset
+		p.next()
+		p.record(edge, start)
+		if p.tok.Type != tokID && p.tok.Type != tokSubgraph && p.tok.Type != tokLBrace {
+			return nil, p.missing("node or subgraph after %q", op)
+		}
+		vertex, err := p.parseVertex()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		edge.Vertex = vertex
+		*to = edge
+		to = &edge.To
+	}
+	attrs, err := p.parseOptAttrList()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	stmt.Attrs = attrs
+	return stmt, nil
+}
+
+// --- [ Attribute statement ] -------------------------------------------------
+
+// parseAttrStmt parses an attribute statement.
+//
+//    AttrStmt : ( "graph" | "node" | "edge" ) AttrList
+func (p *parser) parseAttrStmt() (*ast.AttrStmt, error) {
+	stmt := &ast.AttrStmt{}
+	switch p.tok.Type {
+	case tokGraph:
+		stmt.Kind = ast.KindGraph
+	case tokNode:
+		stmt.Kind = ast.KindNode
+	case tokEdge:
+		stmt.Kind = ast.KindEdge
+	}
+	keyword := string(p.tok.Lit)
+	p.next()
+	if p.tok.Type != tokLBrack {
+		return nil, p.missing("attribute list of %q statement", keyword)
+	}
+	attrs, err := p.parseOptAttrList()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	stmt.Attrs = attrs
+	return stmt, nil
+}
+
+// parseOptAttrList parses an optional attribute list.
+//
+//    AttrList : "[" [ AList ] "]" [ AttrList ]
+//    AList : Attr [ ( ";" | "," ) ] [ AList ]
+func (p *parser) parseOptAttrList() ([]*ast.Attr, error) {
+	var attrs []*ast.Attr
+	for p.tok.Type == tokLBrack {
+		line := p.tok.Line
+		p.next()
+		for !p.got(tokRBrack) {
+			switch p.tok.Type {
+			case tokID:
+			case token.EOF:
+				return nil, p.errorf(`unterminated attribute list opened at line %d; expected "]"`, line)
+			default:
+				return nil, p.unexpected(`attribute or "]"`)
+			}
+			if err := p.poll(); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			attr, err := p.parseAttr()
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			attrs = append(attrs, attr)
+			if !p.got(tokSemi) {
+				p.got(tokComma)
+			}
+		}
+	}
+	return attrs, nil
+}
+
+// --- [ Attribute ] -----------------------------------------------------------
+
+// parseAttr parses an attribute.
+//
+//    Attr : ID "=" ID
+func (p *parser) parseAttr() (*ast.Attr, error) {
+	start := p.tok.Offset
+	key, err := p.parseID("attribute")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !p.got(tokEqual) {
+		return nil, p.missing(`"=" in attribute %s`, key)
+	}
+	val, err := p.parseAttrVal(key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	attr := &ast.Attr{Key: key, Val: val}
+	p.record(attr, start)
+	return attr, nil
+}
+
+// parseAttrVal parses the value of the attribute with the given key.
+func (p *parser) parseAttrVal(key string) (string, error) {
+	if p.opts.MaxAttrLen > 0 && p.tok.Type == tokID && len(p.tok.Lit) > p.opts.MaxAttrLen {
+		return "", p.limit("MaxAttrLen", p.opts.MaxAttrLen)
+	}
+	return p.parseID("value of attribute %s", key)
+}
+
+// --- [ Subgraph ] ------------------------------------------------------------
+
+// parseSubgraph parses a subgraph.
+//
+//    Subgraph : [ "subgraph" [ ID ] ] "{" [ StmtList ] "}"
+func (p *parser) parseSubgraph() (*ast.Subgraph, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	maxDepth := p.opts.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	if p.depth > maxDepth {
+		return nil, p.limit("MaxDepth", maxDepth)
+	}
+	sub := &ast.Subgraph{}
+	line := p.tok.Line
+	owner := "subgraph"
+	start := p.tok.Offset
+	end := p.tok.Offset + len(p.tok.Lit)
+	if p.got(tokSubgraph) {
+		switch p.tok.Type {
+		case tokID:
+			start = p.tok.Offset
+			id, err := p.parseID("subgraph ID")
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			sub.ID = id
+			end = p.end
+			owner = "subgraph " + id
+			if p.tok.Type != tokLBrace {
+				return nil, p.missing(`"{" of subgraph %s`, id)
+			}
+		case tokLBrace:
+		default:
+			return nil, p.unexpected(`subgraph ID or "{"`)
+		}
+	}
+	if p.opts.Positions != nil {
+		p.opts.Positions[sub] = Span{Offset: start, Len: end - start}
+	}
+	p.next()
+	for {
+		stmt, err := p.parseListStmt(owner, line)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if stmt == nil {
+			return sub, nil
+		}
+		sub.Stmts = append(sub.Stmts, stmt)
+	}
+}
+
+// === [ Vertices ] ============================================================
+
+// parseVertex parses a vertex, the first token of which is an ID, "subgraph"
+// or "{".
+//
+//    Vertex : Node | Subgraph
+func (p *parser) parseVertex() (ast.Vertex, error) {
+	if p.tok.Type != tokID {
+		return p.parseSubgraph()
+	}
+	start := p.tok.Offset
+	id, err := p.parseID("node ID")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return p.parseNode(id, start)
+}
+
+// --- [ Node identifier ] -----------------------------------------------------
+
+// parseNode parses the optional port of a node, following its ID at the given
+// start offset.
+//
+//    Node : ID [ Port ]
+//    Port : ":" ID [ ":" ID ]
+func (p *parser) parseNode(id string, start int) (*ast.Node, error) {
+	node := &ast.Node{ID: id}
+	if !p.got(tokColon) {
+		p.record(node, start)
+		return node, nil
+	}
+	portID, err := p.parseID(`port after ":" of node %s`, id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// Note, the compass point is passed as an untyped nil if absent.
+	var compassPoint interface{}
+	var tok *token.Token
+	if p.got(tokColon) {
+		tok = p.tok
+		if compassPoint, err = p.parseID(`compass point after ":" of port %s`, portID); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	port, err := astx.NewPort(portID, compassPoint)
+	if err != nil {
+		return nil, errorAt(tok, "%v", err)
+	}
+	node.Port = port
+	p.record(node, start)
+	return node, nil
+}
+
+// === [ Identifiers ] =========================================================
+
+// parseID parses an ID; the missing part of a production as described by the
+// given format otherwise.
+//
+//    ID : id
+func (p *parser) parseID(format string, args ...interface{}) (string, error) {
+	if p.tok.Type != tokID {
+		return "", p.missing(format, args...)
+	}
+	id, err := astx.NewID(p.tok)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	p.next()
+	return id, nil
+}
@@ -0,0 +1,340 @@
+package dot_test
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/internal/parser"
+	"github.com/graphism/dot/internal/scanner"
+	"github.com/pkg/errors"
+)
+
+func TestParseError(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		{
+			in:   "",
+			want: "syntax error at line 1, column 1: unexpected end of file; expected graph",
+		},
+		{
+			in:   "graph {} }",
+			want: `syntax error at line 1, column 10: unexpected "}"; expected graph`,
+		},
+		{
+			in:   "strict {}",
+			want: `syntax error at line 1, column 8: unexpected "{"; expected "graph" or "digraph" after "strict"`,
+		},
+		{
+			in:   "graph [a=b]",
+			want: `syntax error at line 1, column 7: unexpected "["; expected graph ID or "{"`,
+		},
+		{
+			in:   "graph G a -- b",
+			want: `syntax error at line 1, column 9: missing "{" of graph G; got ID a`,
+		},
+		{
+			in:   "digraph {\n\ta -> b\n\tsubgraph cluster_0 {\n\t\tc\n\n}",
+			want: `syntax error at line 6, column 2: unterminated graph opened at line 1; expected "}"`,
+		},
+		{
+			in:   "digraph {\n\tsubgraph cluster_0 {\n\t\tc\n",
+			want: `syntax error at line 4, column 1: unterminated subgraph cluster_0 opened at line 2; expected "}"`,
+		},
+		{
+			in:   "graph { a;; }",
+			want: `syntax error at line 1, column 11: unexpected ";"; expected statement or "}"`,
+		},
+		{
+			in:   "graph { a = }",
+			want: `syntax error at line 1, column 13: missing value of attribute a; got "}"`,
+		},
+		{
+			in:   "graph { a [color red] }",
+			want: `syntax error at line 1, column 18: missing "=" in attribute color; got ID red`,
+		},
+		{
+			in:   "graph { a [color=] }",
+			want: `syntax error at line 1, column 18: missing value of attribute color; got "]"`,
+		},
+		{
+			in:   "graph { a [color=red, ,] }",
+			want: `syntax error at line 1, column 23: unexpected ","; expected attribute or "]"`,
+		},
+		{
+			in:   "graph {\n a [color=red\n}",
+			want: `syntax error at line 3, column 1: unexpected "}"; expected attribute or "]"`,
+		},
+		{
+			in:   "graph { a [color=red",
+			want: `syntax error at line 1, column 21: unterminated attribute list opened at line 1; expected "]"`,
+		},
+		{
+			in:   "graph { edge }",
+			want: `syntax error at line 1, column 14: missing attribute list of "edge" statement; got "}"`,
+		},
+		{
+			in:   "graph { a -- ; }",
+			want: `syntax error at line 1, column 14: missing node or subgraph after "--"; got ";"`,
+		},
+		{
+			in:   "graph { a: }",
+			want: `syntax error at line 1, column 12: missing port after ":" of node a; got "}"`,
+		},
+		{
+			in:   "graph { a:p: }",
+			want: `syntax error at line 1, column 14: missing compass point after ":" of port p; got "}"`,
+		},
+		{
+			in:   "graph { subgraph [a=b] }",
+			want: `syntax error at line 1, column 18: unexpected "["; expected subgraph ID or "{"`,
+		},
+		{
+			in:   "graph { subgraph s a }",
+			want: `syntax error at line 1, column 20: missing "{" of subgraph s; got ID a`,
+		},
+		{
+			in:   `graph { a [label="unterminated] }`,
+			want: `syntax error at line 1, column 18: missing value of attribute label; got unterminated or invalid string "unterminated] }`,
+		},
+		{
+			in:   "graph { a /* unterminated }",
+			want: `syntax error at line 1, column 11: unexpected unterminated comment; expected statement or "}"`,
+		},
+	}
+	for _, g := range golden {
+		_, err := dot.ParseString(g.in)
+		if err == nil {
+			t.Errorf("%q: expected error, got nil", g.in)
+			continue
+		}
+		if _, ok := errors.Cause(err).(*dot.SyntaxError); !ok {
+			t.Errorf("%q: error type mismatch; expected *dot.SyntaxError, got %T", g.in, errors.Cause(err))
+		}
+		if got := err.Error(); got != g.want {
+			t.Errorf("%q: error mismatch; expected `%v`, got `%v`", g.in, g.want, got)
+		}
+	}
+}
+
+func TestParseCorpus(t *testing.T) {
+	// Parse all files of testdata directories, and compare against the
+	// gocc-generated parser.
+	var paths []string
+	walk := func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && filepath.Base(filepath.Dir(path)) == "testdata" {
+			paths = append(paths, path)
+		}
+		return nil
+	}
+	if err := filepath.Walk(".", walk); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", path, err)
+			continue
+		}
+		compare(t, path, buf)
+	}
+}
+
+func TestParseRandom(t *testing.T) {
+	// Parse random graphs generated from the DOT grammar, with and without
+	// mutations, and compare against the gocc-generated parser.
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		g := &generator{r: r}
+		g.graph()
+		if r.Intn(2) == 0 {
+			g.mutate()
+		}
+		src := strings.Join(g.toks, " ")
+		compare(t, src, []byte(src))
+	}
+}
+
+// compare reports mismatches between the recursive-descent parser and the
+// gocc-generated parser for the given input. Files accepted by the gocc parser
+// and the semantic checker are expected to be parsed into the same AST.
+func compare(t *testing.T, name string, src []byte) {
+	got, err := dot.ParseBytes(src)
+	want, ok := goccParse(src)
+	switch {
+	case err != nil && ok:
+		t.Errorf("%q: unable to parse file; %v", name, err)
+	case err == nil && !ok:
+		t.Errorf("%q: expected error, got nil", name)
+	case err == nil && got.String() != want.String():
+		t.Errorf("%q: file mismatch; expected `%v`, got `%v`", name, want, got)
+	}
+}
+
+// goccParse parses the given DOT file using the gocc-generated parser, and
+// reports whether it is accepted by the parser and the semantic checker.
+func goccParse(src []byte) (*ast.File, bool) {
+	p := parser.NewParser()
+	res, err := p.Parse(scanner.New(src))
+	if err != nil {
+		return nil, false
+	}
+	file := res.(*ast.File)
+	for _, diag := range dot.Check(file) {
+		if diag.Severity == dot.SeverityError {
+			return nil, false
+		}
+	}
+	return file, true
+}
+
+// A generator generates random DOT files from the DOT grammar, as token
+// sequences.
+type generator struct {
+	r *rand.Rand
+	// Tokens of the DOT file.
+	toks []string
+	// Nesting depth of subgraphs.
+	depth int
+}
+
+// emit emits the given tokens.
+func (g *generator) emit(toks ...string) {
+	g.toks = append(g.toks, toks...)
+}
+
+// maybe reports whether to generate an optional part of a production.
+func (g *generator) maybe() bool {
+	return g.r.Intn(2) == 0
+}
+
+func (g *generator) graph() {
+	if g.maybe() {
+		g.emit("strict")
+	}
+	g.emit([]string{"graph", "digraph"}[g.r.Intn(2)])
+	if g.maybe() {
+		g.id()
+	}
+	g.body()
+}
+
+func (g *generator) body() {
+	g.emit("{")
+	for n := g.r.Intn(4); n > 0; n-- {
+		g.stmt()
+		if g.maybe() {
+			g.emit(";")
+		}
+	}
+	g.emit("}")
+}
+
+func (g *generator) stmt() {
+	switch g.r.Intn(5) {
+	case 0:
+		g.node()
+		g.attrList(true)
+	case 1:
+		g.vertex()
+		for n := 1 + g.r.Intn(2); n > 0; n-- {
+			g.emit([]string{"--", "->"}[g.r.Intn(2)])
+			g.vertex()
+		}
+		g.attrList(true)
+	case 2:
+		g.emit([]string{"graph", "node", "edge"}[g.r.Intn(3)])
+		g.attrList(false)
+	case 3:
+		g.attr()
+	case 4:
+		g.subgraph()
+	}
+}
+
+func (g *generator) attrList(opt bool) {
+	if opt && g.maybe() {
+		return
+	}
+	for n := 1 + g.r.Intn(2); n > 0; n-- {
+		g.emit("[")
+		for m := g.r.Intn(3); m > 0; m-- {
+			g.attr()
+			if g.maybe() {
+				g.emit([]string{";", ","}[g.r.Intn(2)])
+			}
+		}
+		g.emit("]")
+	}
+}
+
+func (g *generator) attr() {
+	g.id()
+	g.emit("=")
+	g.id()
+}
+
+func (g *generator) subgraph() {
+	if g.depth > 2 {
+		g.emit("{", "}")
+		return
+	}
+	if g.maybe() {
+		g.emit("subgraph")
+		if g.maybe() {
+			g.id()
+		}
+	}
+	g.depth++
+	g.body()
+	g.depth--
+}
+
+func (g *generator) vertex() {
+	if g.r.Intn(4) == 0 {
+		g.subgraph()
+		return
+	}
+	g.node()
+}
+
+func (g *generator) node() {
+	g.id()
+	switch g.r.Intn(4) {
+	case 0:
+		g.emit(":")
+		g.id()
+	case 1:
+		g.emit(":")
+		g.id()
+		g.emit(":", []string{"n", "sw", "_", "x"}[g.r.Intn(4)])
+	}
+}
+
+func (g *generator) id() {
+	g.emit([]string{"a", "b", "n", `"c d"`, "-1.5", "<<b>e</b>>", "nodes"}[g.r.Intn(7)])
+}
+
+// mutate deletes, duplicates or replaces a random token.
+func (g *generator) mutate() {
+	i := g.r.Intn(len(g.toks))
+	switch g.r.Intn(3) {
+	case 0:
+		g.toks = append(g.toks[:i], g.toks[i+1:]...)
+	case 1:
+		g.toks = append(g.toks[:i+1], g.toks[i:]...)
+	case 2:
+		toks := []string{"{", "}", "[", "]", "=", ";", ",", ":", "--", "->", "a", "graph", "node", "subgraph", "strict"}
+		g.toks[i] = toks[g.r.Intn(len(toks))]
+	}
+}
@@ -0,0 +1,577 @@
+// Package plain implements decoding and encoding of the Graphviz plain and
+// plain-ext output formats.
+//
+// The plain format is a line-based description of a graph layout, as specified
+// by the following grammar.
+//
+//    graph scale width height
+//    node name x y width height label style shape color fillcolor
+//    edge tail head n x1 y1 .. xn yn [label xl yl] style color
+//    stop
+//
+// The plain-ext format extends the plain format with port names of edge
+// endpoints; e.g. "tail:port". All coordinates and sizes are in inches, with
+// the lower left corner of the drawing at the origin. The scale is recorded
+// but not applied when converting to and from points.
+//
+// ref: https://graphviz.org/docs/outputs/plain/
+package plain
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/internal/enc"
+	"github.com/pkg/errors"
+)
+
+// Points per inch.
+const ppi = 72.0
+
+// A Graph is a graph layout in plain format.
+type Graph struct {
+	// Scale of the graph.
+	Scale float64
+	// Width and height of the drawing.
+	Width, Height float64
+	// Nodes of the graph.
+	Nodes []*Node
+	// Edges of the graph.
+	Edges []*Edge
+}
+
+// A Node is a node layout in plain format.
+type Node struct {
+	// Node name.
+	Name string
+	// Center of the node.
+	X, Y float64
+	// Width and height of the node.
+	Width, Height float64
+	// Label text.
+	Label string
+	// Style, shape, colour and fill colour of the node.
+	Style, Shape, Color, FillColor string
+}
+
+// An Edge is an edge layout in plain format.
+type Edge struct {
+	// Tail and head node names.
+	Tail, Head string
+	// Tail and head port names (plain-ext); or empty if none.
+	TailPort, HeadPort string
+	// Control points of the B-spline of the edge.
+	Points []Point
+	// Label text; or empty if none.
+	Label string
+	// Center of the label.
+	LabelX, LabelY float64
+	// Style and colour of the edge.
+	Style, Color string
+}
+
+// A Point is a point in inches.
+type Point struct {
+	X, Y float64
+}
+
+// === [ Decoding ] ============================================================
+
+// Unmarshal parses the given graph layout in plain or plain-ext format.
+func Unmarshal(data []byte) (*Graph, error) {
+	g := &Graph{}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	hasGraph, stop := false, false
+	for line := 1; s.Scan(); line++ {
+		fields, err := split(s.Text())
+		if err != nil {
+			return nil, errors.Errorf("line %d: %v", line, err)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if stop {
+			return nil, errors.Errorf("line %d: unexpected %q after stop statement", line, fields[0].String())
+		}
+		switch fields[0].String() {
+		case "graph":
+			if hasGraph {
+				return nil, errors.Errorf("line %d: duplicate graph statement", line)
+			}
+			hasGraph = true
+			err = parseGraph(g, fields[1:])
+		case "node":
+			var n *Node
+			if n, err = parseNode(fields[1:]); err == nil {
+				g.Nodes = append(g.Nodes, n)
+			}
+		case "edge":
+			var e *Edge
+			if e, err = parseEdge(fields[1:]); err == nil {
+				g.Edges = append(g.Edges, e)
+			}
+		case "stop":
+			stop = true
+		default:
+			err = errors.Errorf("invalid statement %q; expected graph, node, edge or stop", fields[0].String())
+		}
+		if err != nil {
+			return nil, errors.Errorf("line %d: %v", line, err)
+		}
+		if !hasGraph {
+			return nil, errors.Errorf("line %d: missing graph statement", line)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !hasGraph {
+		return nil, errors.New("missing graph statement")
+	}
+	return g, nil
+}
+
+// parseGraph parses the fields of a graph statement into g.
+func parseGraph(g *Graph, fields []field) error {
+	if len(fields) != 3 {
+		return errors.Errorf("invalid number of fields in graph statement; expected 3, got %d", len(fields))
+	}
+	return parseFloats(fields, &g.Scale, &g.Width, &g.Height)
+}
+
+// parseNode parses the fields of a node statement.
+func parseNode(fields []field) (*Node, error) {
+	if len(fields) != 10 {
+		return nil, errors.Errorf("invalid number of fields in node statement; expected 10, got %d", len(fields))
+	}
+	n := &Node{
+		Name:      fields[0].String(),
+		Label:     fields[5].String(),
+		Style:     fields[6].String(),
+		Shape:     fields[7].String(),
+		Color:     fields[8].String(),
+		FillColor: fields[9].String(),
+	}
+	if err := parseFloats(fields[1:5], &n.X, &n.Y, &n.Width, &n.Height); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return n, nil
+}
+
+// parseEdge parses the fields of an edge statement.
+func parseEdge(fields []field) (*Edge, error) {
+	if len(fields) < 3 {
+		return nil, errors.Errorf("invalid number of fields in edge statement; expected at least 3, got %d", len(fields))
+	}
+	e := &Edge{}
+	e.Tail, e.TailPort = endpoint(fields[0])
+	e.Head, e.HeadPort = endpoint(fields[1])
+	n, err := strconv.Atoi(fields[2].String())
+	if err != nil || n < 0 {
+		return nil, errors.Errorf("invalid point count %q", fields[2].String())
+	}
+	fields = fields[3:]
+	if len(fields) < 2*n {
+		return nil, errors.Errorf("invalid number of points in edge statement; expected %d, got %d", n, len(fields)/2)
+	}
+	e.Points = make([]Point, n)
+	for i := range e.Points {
+		if err := parseFloats(fields[2*i:2*i+2], &e.Points[i].X, &e.Points[i].Y); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	fields = fields[2*n:]
+	switch len(fields) {
+	case 2:
+	case 5:
+		e.Label = fields[0].String()
+		if err := parseFloats(fields[1:3], &e.LabelX, &e.LabelY); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		fields = fields[3:]
+	default:
+		return nil, errors.Errorf("invalid number of trailing fields in edge statement; expected 2 or 5, got %d", len(fields))
+	}
+	e.Style, e.Color = fields[0].String(), fields[1].String()
+	return e, nil
+}
+
+// endpoint returns the node name and port of the given plain-ext edge
+// endpoint.
+func endpoint(f field) (string, string) {
+	return f[0], strings.Join(f[1:], ":")
+}
+
+// parseFloats parses the given fields as floating-point values.
+func parseFloats(fields []field, xs ...*float64) error {
+	for i, x := range xs {
+		v, err := strconv.ParseFloat(fields[i].String(), 64)
+		if err != nil {
+			return errors.Errorf("invalid number %q", fields[i].String())
+		}
+		*x = v
+	}
+	return nil
+}
+
+// A field is a whitespace-separated field of a line, consisting of one or more
+// colon-separated parts; e.g. "tail:port". Parts are unquoted.
+type field []string
+
+// String returns the string representation of the field, with its parts
+// joined by colons.
+func (f field) String() string {
+	return strings.Join(f, ":")
+}
+
+// split splits the given line into whitespace-separated fields.
+func split(line string) ([]field, error) {
+	var fields []field
+	var f field
+	for i := 0; i < len(line); {
+		c := line[i]
+		if c == ' ' || c == '\t' || c == '\r' {
+			if f != nil {
+				fields = append(fields, f)
+				f = nil
+			}
+			i++
+			continue
+		}
+		// Parse part.
+		end := i
+		switch c {
+		case '"':
+			for end++; end < len(line) && line[end] != '"'; end++ {
+				if line[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(line) {
+				return nil, errors.New("unterminated quoted string")
+			}
+			end++
+		case '<':
+			depth := 0
+			for ; end < len(line); end++ {
+				if line[end] == '<' {
+					depth++
+				} else if line[end] == '>' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			if end >= len(line) {
+				return nil, errors.New("unterminated HTML string")
+			}
+			end++
+		default:
+			for end < len(line) && strings.IndexByte(" \t\r:", line[end]) == -1 {
+				end++
+			}
+		}
+		f = append(f, enc.Unquote(line[i:end]))
+		i = end
+		if i < len(line) && line[i] == ':' {
+			i++
+			if i == len(line) || line[i] == ' ' || line[i] == '\t' {
+				// Trailing colon; empty part.
+				f = append(f, "")
+			}
+		}
+	}
+	if f != nil {
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// === [ Encoding ] ============================================================
+
+// Marshal returns the plain output of the given laid out graph.
+func Marshal(graph *ast.Graph) ([]byte, error) {
+	return marshal(graph, false)
+}
+
+// MarshalExt returns the plain-ext output of the given laid out graph.
+func MarshalExt(graph *ast.Graph) ([]byte, error) {
+	return marshal(graph, true)
+}
+
+// marshal returns the plain or plain-ext output of the given laid out graph.
+func marshal(graph *ast.Graph, ext bool) ([]byte, error) {
+	g, err := dot.Resolve(graph)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	layout, err := FromGraph(g)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return layout.Encode(ext), nil
+}
+
+// FromGraph returns the plain layout of the given laid out graph. Edges
+// without position are omitted, as in the output of Graphviz.
+func FromGraph(g *dot.Graph) (*Graph, error) {
+	v, ok := g.Attrs.Get("bb")
+	if !ok {
+		return nil, errors.New("missing bounding box of graph; graph must be laid out")
+	}
+	bb, err := parsePoints(enc.Unquote(v))
+	if err != nil || len(bb) != 2 {
+		return nil, errors.Errorf("invalid bounding box %q of graph", v)
+	}
+	layout := &Graph{
+		Scale:  1,
+		Width:  (bb[1].X - bb[0].X) / ppi,
+		Height: (bb[1].Y - bb[0].Y) / ppi,
+	}
+	for _, n := range g.Nodes {
+		name := enc.Unquote(n.ID)
+		v, ok := n.Attrs.Get("pos")
+		if !ok {
+			return nil, errors.Errorf("missing position of node %s; graph must be laid out", n.ID)
+		}
+		pos, err := parsePoints(enc.Unquote(v))
+		if err != nil || len(pos) != 1 {
+			return nil, errors.Errorf("invalid position %q of node %s", v, n.ID)
+		}
+		node := &Node{
+			Name:      name,
+			X:         pos[0].X / ppi,
+			Y:         pos[0].Y / ppi,
+			Width:     0.75,
+			Height:    0.5,
+			Label:     strings.Replace(getAttr(n.Attrs, "label", `\N`), `\N`, name, -1),
+			Style:     getAttr(n.Attrs, "style", "solid"),
+			Shape:     getAttr(n.Attrs, "shape", "ellipse"),
+			Color:     getAttr(n.Attrs, "color", "black"),
+			FillColor: getAttr(n.Attrs, "fillcolor", getAttr(n.Attrs, "color", "lightgrey")),
+		}
+		if v, ok := n.Attrs.Get("width"); ok {
+			if node.Width, err = strconv.ParseFloat(enc.Unquote(v), 64); err != nil {
+				return nil, errors.Errorf("invalid width %q of node %s", v, n.ID)
+			}
+		}
+		if v, ok := n.Attrs.Get("height"); ok {
+			if node.Height, err = strconv.ParseFloat(enc.Unquote(v), 64); err != nil {
+				return nil, errors.Errorf("invalid height %q of node %s", v, n.ID)
+			}
+		}
+		layout.Nodes = append(layout.Nodes, node)
+	}
+	for _, e := range g.Edges {
+		v, ok := e.Attrs.Get("pos")
+		if !ok {
+			continue
+		}
+		// Control points of all splines, omitting arrowhead end points.
+		var fields []string
+		for _, f := range strings.Fields(strings.Replace(enc.Unquote(v), ";", " ", -1)) {
+			if !strings.HasPrefix(f, "e,") && !strings.HasPrefix(f, "s,") {
+				fields = append(fields, f)
+			}
+		}
+		pts, err := parsePoints(strings.Join(fields, ","))
+		if err != nil {
+			return nil, errors.Errorf("invalid position %q of edge %s -> %s", v, e.From.ID, e.To.ID)
+		}
+		edge := &Edge{
+			Tail:     enc.Unquote(e.From.ID),
+			TailPort: portName(e.FromPort),
+			Head:     enc.Unquote(e.To.ID),
+			HeadPort: portName(e.ToPort),
+			Style:    getAttr(e.Attrs, "style", "solid"),
+			Color:    getAttr(e.Attrs, "color", "black"),
+		}
+		for _, p := range pts {
+			edge.Points = append(edge.Points, Point{X: p.X / ppi, Y: p.Y / ppi})
+		}
+		label, hasLabel := e.Attrs.Get("label")
+		if lp, ok := e.Attrs.Get("lp"); ok && hasLabel {
+			p, err := parsePoints(enc.Unquote(lp))
+			if err != nil || len(p) != 1 {
+				return nil, errors.Errorf("invalid label position %q of edge %s -> %s", lp, e.From.ID, e.To.ID)
+			}
+			edge.Label = enc.Unquote(label)
+			edge.LabelX, edge.LabelY = p[0].X/ppi, p[0].Y/ppi
+		}
+		layout.Edges = append(layout.Edges, edge)
+	}
+	return layout, nil
+}
+
+// Encode returns the plain or plain-ext representation of the graph layout.
+func (g *Graph) Encode(ext bool) []byte {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "graph %s %s %s\n", formatFloat(g.Scale), formatFloat(g.Width), formatFloat(g.Height))
+	for _, n := range g.Nodes {
+		fmt.Fprintf(buf, "node %s %s %s %s %s %s %s %s %s %s\n", enc.Quote(n.Name), formatFloat(n.X), formatFloat(n.Y), formatFloat(n.Width), formatFloat(n.Height), enc.Quote(n.Label), enc.Quote(n.Style), enc.Quote(n.Shape), enc.Quote(n.Color), enc.Quote(n.FillColor))
+	}
+	for _, e := range g.Edges {
+		tail, head := enc.Quote(e.Tail), enc.Quote(e.Head)
+		if ext {
+			tail, head = formatEndpoint(e.Tail, e.TailPort), formatEndpoint(e.Head, e.HeadPort)
+		}
+		fmt.Fprintf(buf, "edge %s %s %d", tail, head, len(e.Points))
+		for _, p := range e.Points {
+			fmt.Fprintf(buf, " %s %s", formatFloat(p.X), formatFloat(p.Y))
+		}
+		if e.Label != "" {
+			fmt.Fprintf(buf, " %s %s %s", enc.Quote(e.Label), formatFloat(e.LabelX), formatFloat(e.LabelY))
+		}
+		fmt.Fprintf(buf, " %s %s\n", enc.Quote(e.Style), enc.Quote(e.Color))
+	}
+	buf.WriteString("stop\n")
+	return buf.Bytes()
+}
+
+// formatEndpoint returns the plain-ext representation of the given edge
+// endpoint.
+func formatEndpoint(name, port string) string {
+	if port == "" {
+		return enc.Quote(name)
+	}
+	var parts []string
+	for _, part := range strings.Split(port, ":") {
+		parts = append(parts, enc.Quote(part))
+	}
+	return enc.Quote(name) + ":" + strings.Join(parts, ":")
+}
+
+// portName returns the port name of the given port, including the compass
+// point if present; or the empty string if none.
+func portName(port *ast.Port) string {
+	if port == nil {
+		return ""
+	}
+	var parts []string
+	if port.ID != "" {
+		parts = append(parts, enc.Unquote(port.ID))
+	}
+	if port.CompassPoint != ast.CompassPointDefault {
+		parts = append(parts, port.CompassPoint.String())
+	}
+	return strings.Join(parts, ":")
+}
+
+// getAttr returns the unquoted value of the attribute with the given key; or
+// the default value if not present.
+func getAttr(attrs dot.Attrs, key, def string) string {
+	if v, ok := attrs.Get(key); ok {
+		return enc.Unquote(v)
+	}
+	return def
+}
+
+// parsePoints parses the given comma-separated list of coordinates into
+// points. Coordinates may be suffixed by an exclamation mark.
+func parsePoints(s string) ([]Point, error) {
+	parts := strings.Split(strings.Replace(s, "!", "", -1), ",")
+	if len(parts)%2 != 0 {
+		return nil, errors.Errorf("invalid number of coordinates in %q", s)
+	}
+	pts := make([]Point, len(parts)/2)
+	for i := range pts {
+		x, err := strconv.ParseFloat(strings.TrimSpace(parts[2*i]), 64)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		y, err := strconv.ParseFloat(strings.TrimSpace(parts[2*i+1]), 64)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		pts[i] = Point{X: x, Y: y}
+	}
+	return pts, nil
+}
+
+// formatFloat returns the string representation of x, rounded to five
+// decimals.
+func formatFloat(x float64) string {
+	x = math.Round(x*1e5) / 1e5
+	if x == 0 {
+		// Avoid negative zero.
+		x = 0
+	}
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
+
+// === [ Merging ] =============================================================
+
+// Merge returns a copy of the given graph with the coordinates of the given
+// layout merged in; as the bb attribute of the graph, the pos, width and height
+// attributes of nodes, and the pos and lp attributes of edges.
+//
+// Edges of the layout are matched with edges of the graph between the same
+// nodes, in order of appearance.
+func Merge(graph *ast.Graph, layout *Graph) (*ast.Graph, error) {
+	g, err := dot.Resolve(graph)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	bb := []Point{{}, {X: layout.Width, Y: layout.Height}}
+	g.Attrs.Set("bb", enc.Quote(formatPoints(bb)))
+	for _, node := range layout.Nodes {
+		n, ok := g.Node(enc.Quote(node.Name))
+		if !ok {
+			return nil, errors.Errorf("unable to locate node %q of layout in graph", node.Name)
+		}
+		n.Attrs.Set("pos", enc.Quote(formatPoints([]Point{{X: node.X, Y: node.Y}})))
+		n.Attrs.Set("width", enc.Quote(formatFloat(node.Width)))
+		n.Attrs.Set("height", enc.Quote(formatFloat(node.Height)))
+	}
+	// Edges between each pair of nodes, in order of appearance.
+	edges := make(map[[2]*dot.Node][]*dot.Edge)
+	for _, e := range g.Edges {
+		key := [2]*dot.Node{e.From, e.To}
+		edges[key] = append(edges[key], e)
+	}
+	for _, edge := range layout.Edges {
+		from, ok := g.Node(enc.Quote(edge.Tail))
+		if !ok {
+			return nil, errors.Errorf("unable to locate node %q of layout in graph", edge.Tail)
+		}
+		to, ok := g.Node(enc.Quote(edge.Head))
+		if !ok {
+			return nil, errors.Errorf("unable to locate node %q of layout in graph", edge.Head)
+		}
+		key := [2]*dot.Node{from, to}
+		if len(edges[key]) == 0 && !g.Directed {
+			key = [2]*dot.Node{to, from}
+		}
+		if len(edges[key]) == 0 {
+			return nil, errors.Errorf("unable to locate edge %q -> %q of layout in graph", edge.Tail, edge.Head)
+		}
+		e := edges[key][0]
+		edges[key] = edges[key][1:]
+		var parts []string
+		for _, p := range edge.Points {
+			parts = append(parts, formatPoints([]Point{p}))
+		}
+		e.Attrs.Set("pos", enc.Quote(strings.Join(parts, " ")))
+		if edge.Label != "" {
+			e.Attrs.Set("lp", enc.Quote(formatPoints([]Point{{X: edge.LabelX, Y: edge.LabelY}})))
+		}
+	}
+	return g.AST(), nil
+}
+
+// formatPoints returns the given points in inches as comma-separated
+// coordinates in points.
+func formatPoints(pts []Point) string {
+	var parts []string
+	for _, p := range pts {
+		parts = append(parts, formatFloat(p.X*ppi), formatFloat(p.Y*ppi))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,142 @@
+package plain_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/plain"
+)
+
+func TestUnmarshal(t *testing.T) {
+	// plain-ext -> plain-ext round trip.
+	const path = "testdata/graph.plain"
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%q: unable to read file; %v", path, err)
+	}
+	layout, err := plain.Unmarshal(want)
+	if err != nil {
+		t.Fatalf("%q: unable to decode file; %v", path, err)
+	}
+	if len(layout.Nodes) != 3 || len(layout.Edges) != 3 {
+		t.Fatalf("%q: graph size mismatch; expected 3 nodes and 3 edges, got %d nodes and %d edges", path, len(layout.Nodes), len(layout.Edges))
+	}
+	if e := layout.Edges[1]; e.Tail != "a" || e.TailPort != "p:s" || e.Head != "c d" || e.HeadPort != "" {
+		t.Errorf("%q: edge endpoint mismatch; expected a:p:s -> \"c d\", got %s:%s -> %s:%s", path, e.Tail, e.TailPort, e.Head, e.HeadPort)
+	}
+	if got := layout.Encode(true); !bytes.Equal(got, want) {
+		t.Errorf("%q: plain-ext mismatch; expected `%s`, got `%s`", path, want, got)
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		{
+			in:   "node a 1 1 1 1 a solid ellipse black lightgrey\n",
+			want: "line 1: missing graph statement",
+		},
+		{
+			in:   "graph 1 1\n",
+			want: "line 1: invalid number of fields in graph statement; expected 3, got 2",
+		},
+		{
+			in:   "graph 1 1 1\nnode a x 1 1 1 a solid ellipse black lightgrey\n",
+			want: `line 2: invalid number "x"`,
+		},
+		{
+			in:   "graph 1 1 1\nedge a b 2 0 0 1 1 solid\n",
+			want: "line 2: invalid number of trailing fields in edge statement; expected 2 or 5, got 1",
+		},
+		{
+			in:   "graph 1 1 1\nnode \"a 1 1 1 1 a solid ellipse black lightgrey\n",
+			want: "line 2: unterminated quoted string",
+		},
+		{
+			in:   "graph 1 1 1\nstop\nnode a 1 1 1 1 a solid ellipse black lightgrey\n",
+			want: `line 3: unexpected "node" after stop statement`,
+		},
+	}
+	for _, g := range golden {
+		_, err := plain.Unmarshal([]byte(g.in))
+		if err == nil {
+			t.Errorf("%q: expected error, got nil", g.in)
+			continue
+		}
+		if got := err.Error(); got != g.want {
+			t.Errorf("%q: error mismatch; expected `%s`, got `%s`", g.in, g.want, got)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	file, err := dot.ParseFile("testdata/graph.dot")
+	if err != nil {
+		t.Fatalf("unable to parse file; %v", err)
+	}
+	buf, err := ioutil.ReadFile("testdata/graph.plain")
+	if err != nil {
+		t.Fatalf("unable to read file; %v", err)
+	}
+	layout, err := plain.Unmarshal(buf)
+	if err != nil {
+		t.Fatalf("unable to decode file; %v", err)
+	}
+	graph, err := plain.Merge(file.Graphs[0], layout)
+	if err != nil {
+		t.Fatalf("unable to merge layout; %v", err)
+	}
+	want, err := ioutil.ReadFile("testdata/graph.golden")
+	if err != nil {
+		t.Fatalf("unable to read file; %v", err)
+	}
+	if got := graph.String(); got != string(bytes.TrimSpace(want)) {
+		t.Errorf("graph mismatch; expected `%s`, got `%s`", want, got)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	golden := []struct {
+		in  string
+		out string
+		ext bool
+	}{
+		{
+			in:  "testdata/graph.golden",
+			out: "testdata/graph.golden.plain",
+		},
+		{
+			in:  "testdata/graph.golden",
+			out: "testdata/graph.plain",
+			ext: true,
+		},
+	}
+	for _, g := range golden {
+		file, err := dot.ParseFile(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", g.in, err)
+			continue
+		}
+		marshal := plain.Marshal
+		if g.ext {
+			marshal = plain.MarshalExt
+		}
+		got, err := marshal(file.Graphs[0])
+		if err != nil {
+			t.Errorf("%q: unable to encode graph; %v", g.in, err)
+			continue
+		}
+		want, err := ioutil.ReadFile(g.out)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", g.in, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%q: plain mismatch; expected `%s`, got `%s`", g.in, want, got)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// Package record implements parsing and printing of Graphviz record labels.
+//
+// Record labels, as used by nodes of record and Mrecord shape, are specified
+// by the following grammar.
+//
+//    rlabel  : field ( '|' field )*
+//    field   : fieldId | '{' rlabel '}'
+//    fieldId : [ '<' string '>' ] [ string ]
+//
+// The characters '{', '}', '|', '<' and '>', and spaces, may be escaped with
+// a backslash. Unescaped runs of spaces are collapsed into a single space.
+//
+// ref: https://graphviz.org/doc/info/shapes.html#record
+package record
+
+import (
+	"strings"
+
+	"github.com/graphism/dot/internal/enc"
+	"github.com/pkg/errors"
+)
+
+// === [ Labels ] ==============================================================
+
+// A Label is a record label.
+type Label struct {
+	// Record fields.
+	Fields []*Field
+}
+
+// String returns the string representation of the label, as an unquoted
+// record label.
+func (l *Label) String() string {
+	return formatFields(l.Fields)
+}
+
+// Ports returns the port names of the fields of the label, in pre-order.
+func (l *Label) Ports() []string {
+	var ports []string
+	walk(l.Fields, func(f *Field) {
+		if f.Port != "" {
+			ports = append(ports, f.Port)
+		}
+	})
+	return ports
+}
+
+// Field returns the first field of the label with the given port name, and a
+// boolean value indicating if such a field exists.
+func (l *Label) Field(port string) (*Field, bool) {
+	var field *Field
+	walk(l.Fields, func(f *Field) {
+		if field == nil && f.Port == port {
+			field = f
+		}
+	})
+	return field, field != nil
+}
+
+// walk invokes visit for each field, and nested field, in pre-order.
+func walk(fields []*Field, visit func(f *Field)) {
+	for _, f := range fields {
+		visit(f)
+		walk(f.Fields, visit)
+	}
+}
+
+// === [ Fields ] ==============================================================
+
+// A Field is a field of a record label; either a text field or a list of
+// nested fields.
+type Field struct {
+	// Port name; or empty if none.
+	Port string
+	// Field text; with the escape sequences of label text (e.g. \N and \l)
+	// intact.
+	Text string
+	// Nested fields; or nil if text field. Nested fields are laid out in the
+	// opposite direction of their parent.
+	Fields []*Field
+}
+
+// String returns the string representation of the field.
+func (f *Field) String() string {
+	if f.Fields != nil {
+		return "{" + formatFields(f.Fields) + "}"
+	}
+	var parts []string
+	if f.Port != "" {
+		parts = append(parts, "<"+escape(f.Port)+">")
+	}
+	if f.Text != "" {
+		parts = append(parts, escape(f.Text))
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatFields returns the string representation of the given fields,
+// separated by '|'.
+func formatFields(fields []*Field) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = f.String()
+	}
+	return strings.Join(parts, "|")
+}
+
+// escape escapes the special characters of the given field text or port name,
+// and the spaces which would otherwise be collapsed.
+func escape(s string) string {
+	buf := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case strings.IndexByte(special, c) != -1:
+			buf = append(buf, '\\')
+		case c == ' ' && (i == 0 || i == len(s)-1 || s[i-1] == ' '):
+			buf = append(buf, '\\')
+		}
+		buf = append(buf, c)
+	}
+	return string(buf)
+}
+
+// special specifies the special characters of record labels.
+const special = "{}|<>"
+
+// === [ Parsing ] =============================================================
+
+// Parse parses the given record label. The label may be quoted, as in the
+// value of an attribute.
+func Parse(s string) (*Label, error) {
+	if enc.IsQuoted(s) {
+		s = enc.Unquote(s)
+	}
+	p := &parser{s: s}
+	fields, err := p.fields()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &Label{Fields: fields}, nil
+}
+
+// A parser tracks the position within a record label.
+type parser struct {
+	// Record label.
+	s string
+	// Current position.
+	pos int
+	// Nesting depth of fields.
+	depth int
+}
+
+// fields parses a list of fields separated by '|'.
+func (p *parser) fields() ([]*Field, error) {
+	var fields []*Field
+	for {
+		f, err := p.field()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		fields = append(fields, f)
+		if p.pos < len(p.s) && p.s[p.pos] == '|' {
+			p.pos++
+			continue
+		}
+		return fields, nil
+	}
+}
+
+// field parses a text field or a list of nested fields enclosed in braces.
+func (p *parser) field() (*Field, error) {
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == '{' {
+		start := p.pos
+		p.pos++
+		p.depth++
+		fields, err := p.fields()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		p.depth--
+		if p.pos == len(p.s) {
+			return nil, errors.Errorf("offset %d: missing '}' of nested fields", start)
+		}
+		p.pos++
+		p.skipSpace()
+		if p.pos < len(p.s) && p.s[p.pos] != '|' && p.s[p.pos] != '}' {
+			return nil, errors.Errorf("offset %d: unexpected %q after nested fields; expected '|' or '}'", p.pos, p.s[p.pos])
+		}
+		return &Field{Fields: fields}, nil
+	}
+	f := &Field{}
+	hasPort := false
+	for {
+		if text := p.text(); text != "" {
+			if f.Text != "" {
+				f.Text += " "
+			}
+			f.Text += text
+		}
+		if p.pos == len(p.s) {
+			return f, nil
+		}
+		switch c := p.s[p.pos]; c {
+		case '|':
+			return f, nil
+		case '}':
+			if p.depth == 0 {
+				return nil, errors.Errorf("offset %d: unexpected '}'", p.pos)
+			}
+			return f, nil
+		case '<':
+			if hasPort {
+				return nil, errors.Errorf("offset %d: unexpected '<'; field already has a port", p.pos)
+			}
+			start := p.pos
+			p.pos++
+			f.Port = p.text()
+			if p.pos == len(p.s) || p.s[p.pos] != '>' {
+				return nil, errors.Errorf("offset %d: unterminated port name", start)
+			}
+			p.pos++
+			hasPort = true
+		default:
+			return nil, errors.Errorf("offset %d: unexpected %q in text field", p.pos, c)
+		}
+	}
+}
+
+// text parses text up to the next unescaped special character. Escaped special
+// characters and spaces are unescaped, while other escape sequences are kept.
+// Unescaped runs of spaces are collapsed into a single space, and leading and
+// trailing unescaped spaces are removed.
+func (p *parser) text() string {
+	var buf []byte
+	space := false
+	for ; p.pos < len(p.s); p.pos++ {
+		c := p.s[p.pos]
+		if strings.IndexByte(special, c) != -1 {
+			break
+		}
+		if c == ' ' {
+			space = len(buf) > 0
+			continue
+		}
+		if space {
+			buf = append(buf, ' ')
+			space = false
+		}
+		if c == '\\' && p.pos+1 < len(p.s) {
+			p.pos++
+			if strings.IndexByte(special+" ", p.s[p.pos]) == -1 {
+				buf = append(buf, '\\')
+			}
+			c = p.s[p.pos]
+		}
+		buf = append(buf, c)
+	}
+	return string(buf)
+}
+
+// skipSpace skips space characters.
+func (p *parser) skipSpace() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
@@ -0,0 +1,138 @@
+package record_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/graphism/dot/record"
+)
+
+func TestParse(t *testing.T) {
+	golden := []struct {
+		in    string
+		want  []*record.Field
+		s     string
+		ports []string
+	}{
+		{
+			in: `"<f0> left|<f1> mid\ dle|<f2> right"`,
+			want: []*record.Field{
+				{Port: "f0", Text: "left"},
+				{Port: "f1", Text: "mid dle"},
+				{Port: "f2", Text: "right"},
+			},
+			s:     `<f0> left|<f1> mid dle|<f2> right`,
+			ports: []string{"f0", "f1", "f2"},
+		},
+		{
+			in: `hello\nworld |{ b |{c|<here> d|e}| f}| g | h`,
+			want: []*record.Field{
+				{Text: `hello\nworld`},
+				{Fields: []*record.Field{
+					{Text: "b"},
+					{Fields: []*record.Field{
+						{Text: "c"},
+						{Port: "here", Text: "d"},
+						{Text: "e"},
+					}},
+					{Text: "f"},
+				}},
+				{Text: "g"},
+				{Text: "h"},
+			},
+			s:     `hello\nworld|{b|{c|<here> d|e}|f}|g|h`,
+			ports: []string{"here"},
+		},
+		{
+			in: `\{\}\|\<\>\ x  \ y|text <p>||`,
+			want: []*record.Field{
+				{Text: "{}|<> x  y"},
+				{Port: "p", Text: "text"},
+				{},
+				{},
+			},
+			s:     `\{\}\|\<\> x \ y|<p> text||`,
+			ports: []string{"p"},
+		},
+		{
+			in:   ``,
+			want: []*record.Field{{}},
+		},
+	}
+	for _, g := range golden {
+		label, err := record.Parse(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to parse record label; %v", g.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(label.Fields, g.want) {
+			t.Errorf("%q: fields mismatch; expected %v, got %v", g.in, g.want, label.Fields)
+		}
+		if got := label.String(); got != g.s {
+			t.Errorf("%q: string representation mismatch; expected %q, got %q", g.in, g.s, got)
+		}
+		if ports := label.Ports(); !reflect.DeepEqual(ports, g.ports) {
+			t.Errorf("%q: ports mismatch; expected %q, got %q", g.in, g.ports, ports)
+		}
+		for _, port := range g.ports {
+			if f, ok := label.Field(port); !ok || f.Port != port {
+				t.Errorf("%q: unable to locate field with port %q", g.in, port)
+			}
+		}
+		// The string representation parses to the same fields.
+		label, err = record.Parse(g.s)
+		if err != nil {
+			t.Errorf("%q: unable to parse string representation; %v", g.s, err)
+			continue
+		}
+		if !reflect.DeepEqual(label.Fields, g.want) {
+			t.Errorf("%q: round trip mismatch; expected %v, got %v", g.s, g.want, label.Fields)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		{
+			in:   `a|{b|c`,
+			want: "offset 2: missing '}' of nested fields",
+		},
+		{
+			in:   `a}|b`,
+			want: "offset 1: unexpected '}'",
+		},
+		{
+			in:   `a {b}`,
+			want: "offset 2: unexpected '{' in text field",
+		},
+		{
+			in:   `{a} b`,
+			want: "offset 4: unexpected 'b' after nested fields; expected '|' or '}'",
+		},
+		{
+			in:   `<p> a <q> b`,
+			want: "offset 6: unexpected '<'; field already has a port",
+		},
+		{
+			in:   `<p a`,
+			want: "offset 0: unterminated port name",
+		},
+		{
+			in:   `a > b`,
+			want: "offset 2: unexpected '>' in text field",
+		},
+	}
+	for _, g := range golden {
+		_, err := record.Parse(g.in)
+		if err == nil {
+			t.Errorf("%q: expected error, got nil", g.in)
+			continue
+		}
+		if got := err.Error(); got != g.want {
+			t.Errorf("%q: error mismatch; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+}
@@ -0,0 +1,876 @@
+// Package svg implements rendering of laid out Graphviz DOT graphs to SVG.
+//
+// The graph must have been laid out, either by the layout package or by
+// Graphviz (dot -Tdot); positions and sizes are taken from the bb and lp
+// attributes of the graph and its clusters, the pos, width and height
+// attributes of nodes, and the pos and lp attributes of edges. Coordinates are
+// in points, with the y-axis pointing upwards.
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/color"
+	"github.com/graphism/dot/internal/enc"
+	"github.com/graphism/dot/record"
+	"github.com/pkg/errors"
+)
+
+// Rendering parameters, in points.
+const (
+	// Points per inch.
+	ppi = 72.0
+	// Padding around the drawing.
+	pad = 4.0
+	// Default font size.
+	defaultFontSize = 14.0
+	// Horizontal node label margin.
+	marginX = 0.11 * ppi
+)
+
+// Render returns the SVG drawing of the given laid out graph.
+func Render(graph *ast.Graph) ([]byte, error) {
+	g, err := dot.Resolve(graph)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return RenderGraph(g)
+}
+
+// RenderGraph returns the SVG drawing of the given laid out graph.
+func RenderGraph(g *dot.Graph) ([]byte, error) {
+	r := &renderer{g: g, buf: &bytes.Buffer{}}
+	if err := r.render(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return r.buf.Bytes(), nil
+}
+
+// A renderer keeps track of the output buffer and graph component IDs.
+type renderer struct {
+	// Resolved graph.
+	g *dot.Graph
+	// Output buffer.
+	buf *bytes.Buffer
+	// Number of rendered clusters, nodes and edges; used for element IDs.
+	nclusters, nnodes, nedges int
+}
+
+// render renders the graph.
+func (r *renderer) render() error {
+	v, ok := r.g.Attrs.Get("bb")
+	if !ok {
+		return errors.New("missing bounding box of graph; graph must be laid out")
+	}
+	bb, err := parseBox(enc.Unquote(v))
+	if err != nil {
+		return errors.Errorf("invalid bounding box %q of graph; %v", v, err)
+	}
+	w, h := bb.max.x-bb.min.x+2*pad, bb.max.y-bb.min.y+2*pad
+	r.printf("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"no\"?>\n")
+	r.printf("<svg width=\"%spt\" height=\"%spt\" viewBox=\"0.00 0.00 %.2f %.2f\" xmlns=\"http://www.w3.org/2000/svg\" xmlns:xlink=\"http://www.w3.org/1999/xlink\">\n", formatFloat(w), formatFloat(h), w, h)
+	// Flip the y-axis, and translate the drawing to the origin.
+	r.printf("<g id=\"graph0\" class=\"graph\" transform=\"translate(%s %s)\">\n", formatFloat(pad-bb.min.x), formatFloat(pad+bb.max.y))
+	r.printf("<title>%s</title>\n", escape(enc.Unquote(r.g.ID)))
+	bg := paint("fill", r.g.Attrs, "white", "bgcolor")
+	outer := box{min: bb.min.sub(point{x: pad, y: pad}), max: bb.max.add(point{x: pad, y: pad})}
+	r.printf("<polygon%s stroke=\"none\" points=\"%s\"/>\n", bg, polygon(outer.corners()))
+	if err := r.label(r.g.Attrs, r.g.ID); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := r.clusters(r.g.Subgraphs); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, n := range r.g.Nodes {
+		if err := r.node(n); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for _, e := range r.g.Edges {
+		if err := r.edge(e); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	r.printf("</g>\n")
+	r.printf("</svg>\n")
+	return nil
+}
+
+// printf writes formatted output to the output buffer.
+func (r *renderer) printf(format string, args ...interface{}) {
+	fmt.Fprintf(r.buf, format, args...)
+}
+
+// === [ Clusters ] ============================================================
+
+// clusters renders the given subgraphs and their nested subgraphs with
+// bounding boxes, in pre-order.
+func (r *renderer) clusters(subs []*dot.Subgraph) error {
+	for _, sub := range subs {
+		if v, ok := sub.Attrs.Get("bb"); ok {
+			bb, err := parseBox(enc.Unquote(v))
+			if err != nil {
+				return errors.Errorf("invalid bounding box %q of subgraph %s; %v", v, sub.ID, err)
+			}
+			styles := parseStyle(sub.Attrs)
+			if !styles["invis"] {
+				r.nclusters++
+				r.printf("<g id=\"clust%d\" class=\"cluster\">\n", r.nclusters)
+				r.printf("<title>%s</title>\n", escape(enc.Unquote(sub.ID)))
+				fill := paint("fill", sub.Attrs, "none", "bgcolor")
+				if styles["filled"] {
+					fill = paint("fill", sub.Attrs, "lightgrey", "fillcolor", "color", "bgcolor")
+				}
+				attrs := fill + paint("stroke", sub.Attrs, "black", "pencolor", "color") + strokeStyle(sub.Attrs, styles)
+				if styles["rounded"] {
+					r.printf("<path%s d=\"%s\"/>\n", attrs, roundedPath(bb))
+				} else {
+					r.printf("<polygon%s points=\"%s\"/>\n", attrs, polygon(bb.corners()))
+				}
+				if err := r.label(sub.Attrs, sub.ID); err != nil {
+					return errors.WithStack(err)
+				}
+				r.printf("</g>\n")
+			}
+		}
+		if err := r.clusters(sub.Subgraphs); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// === [ Nodes ] ===============================================================
+
+// node renders the given node.
+func (r *renderer) node(n *dot.Node) error {
+	styles := parseStyle(n.Attrs)
+	if styles["invis"] {
+		return nil
+	}
+	name := enc.Unquote(n.ID)
+	v, ok := n.Attrs.Get("pos")
+	if !ok {
+		return errors.Errorf("missing position of node %s; graph must be laid out", n.ID)
+	}
+	c, err := parsePoint(enc.Unquote(v))
+	if err != nil {
+		return errors.Errorf("invalid position %q of node %s; %v", v, n.ID, err)
+	}
+	w, h := 0.75*ppi, 0.5*ppi
+	if v, ok := n.Attrs.Get("width"); ok {
+		if w, err = parseFloat(enc.Unquote(v)); err != nil {
+			return errors.Errorf("invalid width %q of node %s; %v", v, n.ID, err)
+		}
+		w *= ppi
+	}
+	if v, ok := n.Attrs.Get("height"); ok {
+		if h, err = parseFloat(enc.Unquote(v)); err != nil {
+			return errors.Errorf("invalid height %q of node %s; %v", v, n.ID, err)
+		}
+		h *= ppi
+	}
+	b := box{min: point{x: c.x - w/2, y: c.y - h/2}, max: point{x: c.x + w/2, y: c.y + h/2}}
+	shape, _ := n.Attrs.Get("shape")
+	shape = strings.ToLower(enc.Unquote(shape))
+
+	r.nnodes++
+	r.printf("<g id=\"node%d\" class=\"node\">\n", r.nnodes)
+	r.printf("<title>%s</title>\n", escape(name))
+	defaultFill := "lightgrey"
+	if shape == "point" {
+		defaultFill = "black"
+		styles["filled"] = true
+	}
+	fill := ` fill="none"`
+	if styles["filled"] {
+		fill = paint("fill", n.Attrs, defaultFill, "fillcolor", "color")
+	}
+	stroke := paint("stroke", n.Attrs, "black", "color")
+	attrs := fill + stroke + strokeStyle(n.Attrs, styles)
+	switch shape {
+	case "", "ellipse", "oval", "point":
+		r.printf("<ellipse%s cx=\"%s\" cy=\"%s\" rx=\"%s\" ry=\"%s\"/>\n", attrs, formatFloat(c.x), formatFloat(-c.y), formatFloat(w/2), formatFloat(h/2))
+	case "circle", "doublecircle":
+		rad := math.Min(w, h) / 2
+		r.printf("<ellipse%s cx=\"%s\" cy=\"%s\" rx=\"%s\" ry=\"%s\"/>\n", attrs, formatFloat(c.x), formatFloat(-c.y), formatFloat(rad), formatFloat(rad))
+		if shape == "doublecircle" {
+			attrs := ` fill="none"` + stroke + strokeStyle(n.Attrs, styles)
+			r.printf("<ellipse%s cx=\"%s\" cy=\"%s\" rx=\"%s\" ry=\"%s\"/>\n", attrs, formatFloat(c.x), formatFloat(-c.y), formatFloat(rad+4), formatFloat(rad+4))
+		}
+	case "diamond":
+		pts := []point{{x: c.x, y: b.max.y}, {x: b.max.x, y: c.y}, {x: c.x, y: b.min.y}, {x: b.min.x, y: c.y}}
+		r.printf("<polygon%s points=\"%s\"/>\n", attrs, polygon(pts))
+	case "plaintext", "plain", "none":
+		if styles["filled"] {
+			r.printf("<polygon%s stroke=\"none\" points=\"%s\"/>\n", fill, polygon(b.corners()))
+		}
+	default:
+		// Boxes, records and shapes without specific support.
+		if styles["rounded"] || shape == "mrecord" {
+			r.printf("<path%s d=\"%s\"/>\n", attrs, roundedPath(b))
+		} else {
+			r.printf("<polygon%s points=\"%s\"/>\n", attrs, polygon(b.corners()))
+		}
+	}
+	label, err := n.Record()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if label != nil {
+		rankdir, _ := r.g.Attrs.Get("rankdir")
+		rankdir = strings.ToUpper(enc.Unquote(rankdir))
+		horizontal := rankdir != "LR" && rankdir != "RL"
+		r.fields(n, label.Fields, b, horizontal, stroke)
+		r.printf("</g>\n")
+		return nil
+	}
+	if shape != "point" {
+		text := textLines(n.Attrs, `\N`, map[byte]string{'N': name, 'G': enc.Unquote(r.g.ID)})
+		r.text(n.Attrs, text, c, w-2*marginX)
+	}
+	r.printf("</g>\n")
+	return nil
+}
+
+// fields renders the given record fields within box b, laid out horizontally
+// or vertically. Nested fields are laid out in the opposite direction.
+// Separator lines are drawn with the given stroke attributes.
+func (r *renderer) fields(n *dot.Node, fields []*record.Field, b box, horizontal bool, stroke string) {
+	total := 0.0
+	for _, f := range fields {
+		total += fieldWeight(f, horizontal)
+	}
+	pos := b.min.x
+	if !horizontal {
+		pos = b.max.y
+	}
+	for i, f := range fields {
+		fb := b
+		if horizontal {
+			fb.min.x = pos
+			fb.max.x = pos + (b.max.x-b.min.x)*fieldWeight(f, horizontal)/total
+			pos = fb.max.x
+		} else {
+			fb.max.y = pos
+			fb.min.y = pos - (b.max.y-b.min.y)*fieldWeight(f, horizontal)/total
+			pos = fb.min.y
+		}
+		if i > 0 {
+			var from, to point
+			if horizontal {
+				from, to = point{x: fb.min.x, y: fb.min.y}, point{x: fb.min.x, y: fb.max.y}
+			} else {
+				from, to = point{x: fb.min.x, y: fb.max.y}, point{x: fb.max.x, y: fb.max.y}
+			}
+			r.printf("<polyline fill=\"none\"%s points=\"%s\"/>\n", stroke, polyline([]point{from, to}))
+		}
+		if f.Fields != nil {
+			r.fields(n, f.Fields, fb, !horizontal, stroke)
+			continue
+		}
+		attrs := dot.Attrs{{Key: "label", Val: enc.Quote(f.Text)}}
+		for _, key := range []string{"fontname", "fontsize", "fontcolor"} {
+			if v, ok := n.Attrs.Get(key); ok {
+				attrs.Set(key, v)
+			}
+		}
+		text := textLines(attrs, "", map[byte]string{'N': enc.Unquote(n.ID), 'G': enc.Unquote(r.g.ID)})
+		r.text(attrs, text, fb.center(), fb.max.x-fb.min.x-2*marginX)
+	}
+}
+
+// --- [ Records ] -------------------------------------------------------------
+
+// fieldWeight returns the relative size of the field along the given
+// direction, within fields laid out in the same direction.
+func fieldWeight(f *record.Field, horizontal bool) float64 {
+	return fieldSize(f, horizontal, !horizontal)
+}
+
+// fieldSize returns the relative size of the field along the given direction,
+// where its nested fields are laid out horizontally or vertically.
+func fieldSize(f *record.Field, horizontal, nestedHorizontal bool) float64 {
+	if f.Fields == nil {
+		if horizontal {
+			return math.Max(1, float64(len([]rune(f.Text))))
+		}
+		return 1
+	}
+	total := 0.0
+	for _, g := range f.Fields {
+		size := fieldSize(g, horizontal, !nestedHorizontal)
+		if horizontal == nestedHorizontal {
+			total += size
+		} else {
+			total = math.Max(total, size)
+		}
+	}
+	return total
+}
+
+// === [ Edges ] ===============================================================
+
+// edge renders the given edge.
+func (r *renderer) edge(e *dot.Edge) error {
+	styles := parseStyle(e.Attrs)
+	if styles["invis"] {
+		return nil
+	}
+	var splines []*spline
+	if v, ok := e.Attrs.Get("pos"); ok {
+		var err error
+		if splines, err = parseSplines(enc.Unquote(v)); err != nil {
+			return errors.Errorf("invalid position %q of edge %s -> %s; %v", v, e.From.ID, e.To.ID, err)
+		}
+	}
+	from, to := enc.Unquote(e.From.ID), enc.Unquote(e.To.ID)
+	op := "--"
+	if r.g.Directed {
+		op = "->"
+	}
+	r.nedges++
+	r.printf("<g id=\"edge%d\" class=\"edge\">\n", r.nedges)
+	r.printf("<title>%s</title>\n", escape(from+op+to))
+	stroke := paint("stroke", e.Attrs, "black", "color")
+	attrs := stroke + strokeStyle(e.Attrs, styles)
+	for _, s := range splines {
+		if len(s.pts) == 0 {
+			continue
+		}
+		d := "M" + svgPoint(s.pts[0])
+		for i := 1; i+2 < len(s.pts); i += 3 {
+			d += "C" + svgPoint(s.pts[i]) + " " + svgPoint(s.pts[i+1]) + " " + svgPoint(s.pts[i+2])
+		}
+		r.printf("<path fill=\"none\"%s d=\"%s\"/>\n", attrs, d)
+		// Arrowheads are drawn solid, and filled with the edge colour.
+		fill := paint("fill", e.Attrs, "black", "color")
+		arrowAttrs := stroke + strokeWidth(e.Attrs, styles)
+		if s.end != nil {
+			name, _ := e.Attrs.Get("arrowhead")
+			r.arrow(enc.Unquote(name), s.pts[len(s.pts)-1], *s.end, fill, arrowAttrs)
+		}
+		if s.start != nil {
+			name, _ := e.Attrs.Get("arrowtail")
+			r.arrow(enc.Unquote(name), s.pts[0], *s.start, fill, arrowAttrs)
+		}
+	}
+	names := map[byte]string{
+		'E': from + op + to,
+		'T': from,
+		'H': to,
+		'G': enc.Unquote(r.g.ID),
+	}
+	for _, key := range [][2]string{{"label", "lp"}, {"headlabel", "head_lp"}, {"taillabel", "tail_lp"}, {"xlabel", "xlp"}} {
+		v, ok := e.Attrs.Get(key[0])
+		if !ok {
+			continue
+		}
+		attrs := e.Attrs
+		if key[0] != "label" {
+			attrs = dot.Attrs{{Key: "label", Val: v}}
+			for _, k := range []string{"fontname", "fontsize", "fontcolor"} {
+				if v, ok := e.Attrs.Get(k); ok {
+					attrs.Set(k, v)
+				}
+			}
+		}
+		if err := r.labelAt(attrs, key[1], names, e.Attrs); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	r.printf("</g>\n")
+	return nil
+}
+
+// arrowheads lists the supported arrowhead shapes.
+var arrowheads = []string{"box", "crow", "curve", "diamond", "dot", "icurve", "inv", "none", "normal", "tee", "vee"}
+
+// arrow renders an arrowhead of the given name, from the base point to the tip
+// point. Solid arrowheads are drawn with the given fill attributes.
+//
+// Only the first shape of multi-shape arrowheads is drawn, and half arrowheads
+// are drawn in full.
+func (r *renderer) arrow(name string, base, tip point, fill, attrs string) {
+	if name == "empty" {
+		name = "onormal"
+	} else if name == "" || name == "invempty" {
+		name = "normal"
+	}
+	for len(name) > 1 && strings.IndexByte("olr", name[0]) != -1 {
+		if name[0] == 'o' {
+			fill = ` fill="none"`
+		}
+		name = name[1:]
+	}
+	for _, arrowhead := range arrowheads {
+		if strings.HasPrefix(name, arrowhead) {
+			name = arrowhead
+			break
+		}
+	}
+	d := tip.sub(base)
+	length := math.Hypot(d.x, d.y)
+	if length == 0 {
+		return
+	}
+	u := d.scale(1 / length)
+	nrm := point{x: -u.y, y: u.x}.scale(length * 0.35)
+	mid := base.add(d.scale(0.5))
+	var pts []point
+	switch name {
+	case "none":
+		r.printf("<polyline fill=\"none\"%s points=\"%s\"/>\n", attrs, polyline([]point{base, tip}))
+		return
+	case "inv":
+		pts = []point{base, tip.add(nrm), tip.sub(nrm)}
+	case "dot":
+		r.printf("<ellipse%s%s cx=\"%s\" cy=\"%s\" rx=\"%s\" ry=\"%s\"/>\n", fill, attrs, formatFloat(mid.x), formatFloat(-mid.y), formatFloat(length/2), formatFloat(length/2))
+		return
+	case "diamond":
+		pts = []point{tip, mid.add(nrm), base, mid.sub(nrm)}
+	case "box":
+		half := d.scale(0.5)
+		n := point{x: -half.y, y: half.x}
+		pts = []point{mid.add(n), tip.add(n), tip.sub(n), mid.sub(n)}
+	case "tee":
+		head := base.add(d.scale(0.75))
+		pts = []point{head.add(nrm.scale(1.5)), tip.add(nrm.scale(1.5)), tip.sub(nrm.scale(1.5)), head.sub(nrm.scale(1.5))}
+		r.printf("<polyline fill=\"none\"%s points=\"%s\"/>\n", attrs, polyline([]point{base, head}))
+	case "vee":
+		pts = []point{tip, base.add(nrm), base.add(d.scale(0.3)), base.sub(nrm)}
+	case "crow":
+		pts = []point{base, tip.add(nrm), tip.sub(d.scale(0.3)), tip.sub(nrm)}
+	default:
+		pts = []point{tip, base.add(nrm), base.sub(nrm)}
+	}
+	r.printf("<polygon%s%s points=\"%s\"/>\n", fill, attrs, polygon(pts))
+}
+
+// === [ Labels ] ==============================================================
+
+// A line of label text.
+type line struct {
+	// Line text.
+	text string
+	// Justification; 'n' for centered, 'l' for left and 'r' for right
+	// justified.
+	just byte
+}
+
+// label renders the label of the graph or cluster with the given attributes
+// and ID, if any, at the position specified by the lp attribute.
+func (r *renderer) label(attrs dot.Attrs, id string) error {
+	if _, ok := attrs.Get("label"); !ok {
+		return nil
+	}
+	names := map[byte]string{'G': enc.Unquote(id)}
+	return r.labelAt(attrs, "lp", names, attrs)
+}
+
+// labelAt renders the label of the given attributes at the position specified
+// by the attribute with the given key of the component attributes.
+func (r *renderer) labelAt(attrs dot.Attrs, key string, names map[byte]string, component dot.Attrs) error {
+	v, ok := component.Get(key)
+	if !ok {
+		// Labels without position are not rendered.
+		return nil
+	}
+	p, err := parsePoint(enc.Unquote(v))
+	if err != nil {
+		return errors.Errorf("invalid label position %q; %v", v, err)
+	}
+	r.text(attrs, textLines(attrs, "", names), p, 0)
+	return nil
+}
+
+// text renders the given lines of text centered at c. Left and right justified
+// lines are aligned within the given width; or the width of the widest line if
+// zero.
+func (r *renderer) text(attrs dot.Attrs, lines []line, c point, width float64) {
+	if len(lines) == 0 {
+		return
+	}
+	fontsize := defaultFontSize
+	if v, ok := attrs.Get("fontsize"); ok {
+		if size, err := parseFloat(enc.Unquote(v)); err == nil && size > 0 {
+			fontsize = size
+		}
+	}
+	if width <= 0 {
+		for _, l := range lines {
+			// Average character width of proportional fonts, relative to font
+			// size.
+			width = math.Max(width, float64(len([]rune(l.text)))*fontsize*0.55)
+		}
+	}
+	fontname, _ := attrs.Get("fontname")
+	font := fmt.Sprintf(" font-family=\"%s\" font-size=\"%.2f\"", escape(fontFamily(enc.Unquote(fontname))), fontsize)
+	if _, ok := attrs.Get("fontcolor"); ok {
+		font += paint("fill", attrs, "black", "fontcolor")
+	}
+	lineHeight := fontsize * 1.2
+	for i, l := range lines {
+		x, anchor := c.x, "middle"
+		switch l.just {
+		case 'l':
+			x, anchor = c.x-width/2, "start"
+		case 'r':
+			x, anchor = c.x+width/2, "end"
+		}
+		// Baseline of the line, in SVG coordinates.
+		y := -c.y + (float64(i)-float64(len(lines)-1)/2)*lineHeight + 0.3*fontsize
+		r.printf("<text text-anchor=\"%s\" x=\"%s\" y=\"%s\"%s>%s</text>\n", anchor, formatFloat(x), formatFloat(y), font, escape(l.text))
+	}
+}
+
+// textLines returns the lines of the label of the given attributes, with
+// escape sequences expanded using the given names. The default label is used
+// if the attributes have no label.
+func textLines(attrs dot.Attrs, def string, names map[byte]string) []line {
+	label, ok := attrs.Get("label")
+	if !ok {
+		label = def
+	}
+	if enc.IsHTML(label) {
+		return []line{{text: stripTags(label), just: 'n'}}
+	}
+	s := enc.Unquote(label)
+	var lines []line
+	var buf []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\n' {
+			lines = append(lines, line{text: string(buf), just: 'n'})
+			buf = buf[:0]
+			continue
+		}
+		if c != '\\' || i+1 == len(s) {
+			buf = append(buf, c)
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'n', 'l', 'r':
+			lines = append(lines, line{text: string(buf), just: c})
+			buf = buf[:0]
+		case 'N', 'G', 'E', 'T', 'H':
+			buf = append(buf, names[c]...)
+		case 'L':
+			// Object-dependent line number; not rendered.
+		default:
+			buf = append(buf, c)
+		}
+	}
+	if len(buf) > 0 {
+		lines = append(lines, line{text: string(buf), just: 'n'})
+	}
+	return lines
+}
+
+// stripTags returns the text content of the given HTML-like label.
+func stripTags(s string) string {
+	buf := make([]rune, 0, len(s))
+	depth := 0
+	for _, r := range s[1 : len(s)-1] {
+		switch {
+		case r == '<':
+			depth++
+		case r == '>':
+			depth--
+		case depth == 0:
+			buf = append(buf, r)
+		}
+	}
+	return strings.TrimSpace(string(buf))
+}
+
+// fontFamily returns the SVG font family of the given Graphviz font name.
+func fontFamily(name string) string {
+	switch strings.ToLower(strings.SplitN(name, "-", 2)[0]) {
+	case "", "times":
+		return "Times,serif"
+	case "helvetica", "arial", "sans":
+		return "Helvetica,sans-Serif"
+	case "courier", "monospace":
+		return "Courier,monospace"
+	}
+	return name
+}
+
+// === [ Styles ] ==============================================================
+
+// parseStyle returns the set of styles of the style attribute of the given
+// attributes.
+func parseStyle(attrs dot.Attrs) map[string]bool {
+	styles := make(map[string]bool)
+	v, _ := attrs.Get("style")
+	for _, style := range strings.Split(enc.Unquote(v), ",") {
+		if style = strings.TrimSpace(style); style != "" {
+			styles[strings.ToLower(style)] = true
+		}
+	}
+	return styles
+}
+
+// strokeStyle returns the SVG stroke width and dash attributes of the given
+// attributes and styles.
+func strokeStyle(attrs dot.Attrs, styles map[string]bool) string {
+	s := strokeWidth(attrs, styles)
+	switch {
+	case styles["dashed"]:
+		s += ` stroke-dasharray="5,2"`
+	case styles["dotted"]:
+		s += ` stroke-dasharray="1,5"`
+	}
+	return s
+}
+
+// strokeWidth returns the SVG stroke width attribute of the given attributes
+// and styles; or the empty string if default.
+func strokeWidth(attrs dot.Attrs, styles map[string]bool) string {
+	width := 1.0
+	if styles["bold"] {
+		width = 2
+	}
+	if v, ok := attrs.Get("penwidth"); ok {
+		if w, err := parseFloat(enc.Unquote(v)); err == nil && w >= 0 {
+			width = w
+		}
+	}
+	if width == 1 {
+		return ""
+	}
+	return fmt.Sprintf(` stroke-width="%s"`, formatFloat(width))
+}
+
+// paint returns the SVG paint attributes of the given kind (fill or stroke)
+// for the first colour attribute present among the given keys; or the default
+// colour if none.
+//
+// Only the first colour of colour lists is used.
+func paint(kind string, attrs dot.Attrs, def string, keys ...string) string {
+	c, opacity := def, 1.0
+	scheme, _ := attrs.Get("colorscheme")
+	for _, key := range keys {
+		v, ok := attrs.Get(key)
+		if !ok {
+			continue
+		}
+		s := strings.TrimSpace(enc.Unquote(v))
+		// Colour list; "red;0.3:blue".
+		s = strings.SplitN(strings.SplitN(s, ":", 2)[0], ";", 2)[0]
+		if s != "" {
+			c, opacity = svgColor(s, enc.Unquote(scheme))
+			break
+		}
+	}
+	if opacity == 1 {
+		return fmt.Sprintf(` %s="%s"`, kind, escape(c))
+	}
+	return fmt.Sprintf(` %s="%s" %s-opacity="%.6f"`, kind, escape(c), kind, opacity)
+}
+
+// svgColor returns the SVG colour and opacity of the given Graphviz colour,
+// with colour names resolved in the given colour scheme. Colours with an SVG
+// colour name are output by name. Invalid colours are output as is.
+func svgColor(s, scheme string) (string, float64) {
+	c, err := color.Parse(s, scheme)
+	if err != nil {
+		return strings.ToLower(s), 1
+	}
+	if c.A == 0 {
+		return "transparent", 1
+	}
+	if name := strings.ToLower(strings.Replace(s, " ", "", -1)); !strings.ContainsAny(name, "#/,") {
+		if svgc, err := color.Parse(name, "svg"); err == nil && svgc == c {
+			return name, 1
+		}
+	}
+	hex := color.Format(c)
+	if c.A == 0xFF {
+		return hex, 1
+	}
+	return hex[:7], float64(c.A) / 255
+}
+
+// === [ Geometry ] ============================================================
+
+// A point in points, with the y-axis pointing upwards.
+type point struct {
+	x, y float64
+}
+
+// add returns p+q.
+func (p point) add(q point) point {
+	return point{x: p.x + q.x, y: p.y + q.y}
+}
+
+// sub returns p-q.
+func (p point) sub(q point) point {
+	return point{x: p.x - q.x, y: p.y - q.y}
+}
+
+// scale returns p scaled by s.
+func (p point) scale(s float64) point {
+	return point{x: p.x * s, y: p.y * s}
+}
+
+// svgPoint returns the given point in SVG coordinates, as "x,y".
+func svgPoint(p point) string {
+	return formatFloat(p.x) + "," + formatFloat(-p.y)
+}
+
+// A box is an axis-aligned rectangle.
+type box struct {
+	min, max point
+}
+
+// center returns the center of the box.
+func (b box) center() point {
+	return b.min.add(b.max).scale(0.5)
+}
+
+// corners returns the corners of the box, counter-clockwise from the lower
+// left corner.
+func (b box) corners() []point {
+	return []point{b.min, {x: b.max.x, y: b.min.y}, b.max, {x: b.min.x, y: b.max.y}}
+}
+
+// polygon returns the SVG points of the closed polygon with the given
+// vertices.
+func polygon(pts []point) string {
+	return polyline(append(pts, pts[0]))
+}
+
+// polyline returns the SVG points of the polyline through the given points.
+func polyline(pts []point) string {
+	ss := make([]string, len(pts))
+	for i, p := range pts {
+		ss[i] = svgPoint(p)
+	}
+	return strings.Join(ss, " ")
+}
+
+// roundedPath returns the SVG path data of the given box with rounded corners.
+func roundedPath(b box) string {
+	rad := math.Min(12, math.Min(b.max.x-b.min.x, b.max.y-b.min.y)/4)
+	x0, x1 := formatFloat(b.min.x), formatFloat(b.max.x)
+	y0, y1 := formatFloat(-b.max.y), formatFloat(-b.min.y)
+	rx0, rx1 := formatFloat(b.min.x+rad), formatFloat(b.max.x-rad)
+	ry0, ry1 := formatFloat(-b.max.y+rad), formatFloat(-b.min.y-rad)
+	return fmt.Sprintf("M%s,%s L%s,%s Q%s,%s %s,%s L%s,%s Q%s,%s %s,%s L%s,%s Q%s,%s %s,%s L%s,%s Q%s,%s %s,%s Z",
+		rx0, y0, rx1, y0, x1, y0, x1, ry0,
+		x1, ry1, x1, y1, rx1, y1,
+		rx0, y1, x0, y1, x0, ry1,
+		x0, ry0, x0, y0, rx0, y0)
+}
+
+// A spline is a sequence of cubic Bézier curves, with optional arrowheads.
+type spline struct {
+	// Control points; 3n+1 points for n curves.
+	pts []point
+	// Arrowhead end points at start and end; or nil if none.
+	start, end *point
+}
+
+// parseSplines parses the given edge position; a semicolon-separated list of
+// splines of the form "[e,x,y] [s,x,y] x,y x,y ...".
+func parseSplines(s string) ([]*spline, error) {
+	var splines []*spline
+	for _, part := range strings.Split(s, ";") {
+		sp := &spline{}
+		for _, f := range strings.Fields(part) {
+			switch {
+			case strings.HasPrefix(f, "e,"):
+				p, err := parsePoint(f[2:])
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				sp.end = &p
+			case strings.HasPrefix(f, "s,"):
+				p, err := parsePoint(f[2:])
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				sp.start = &p
+			default:
+				p, err := parsePoint(f)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				sp.pts = append(sp.pts, p)
+			}
+		}
+		if len(sp.pts) > 0 && len(sp.pts)%3 != 1 {
+			return nil, errors.Errorf("invalid number of spline control points; expected 3n+1, got %d", len(sp.pts))
+		}
+		splines = append(splines, sp)
+	}
+	return splines, nil
+}
+
+// parsePoint parses the given point of the form "x,y", optionally followed by
+// a third coordinate or an exclamation mark.
+func parsePoint(s string) (point, error) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "!")
+	parts := strings.Split(s, ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return point{}, errors.Errorf("invalid point %q; expected x,y", s)
+	}
+	x, err := parseFloat(parts[0])
+	if err != nil {
+		return point{}, errors.WithStack(err)
+	}
+	y, err := parseFloat(parts[1])
+	if err != nil {
+		return point{}, errors.WithStack(err)
+	}
+	return point{x: x, y: y}, nil
+}
+
+// parseBox parses the given box of the form "llx,lly,urx,ury".
+func parseBox(s string) (box, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return box{}, errors.Errorf("invalid box %q; expected llx,lly,urx,ury", s)
+	}
+	var xs [4]float64
+	for i, part := range parts {
+		x, err := parseFloat(part)
+		if err != nil {
+			return box{}, errors.WithStack(err)
+		}
+		xs[i] = x
+	}
+	return box{min: point{x: xs[0], y: xs[1]}, max: point{x: xs[2], y: xs[3]}}, nil
+}
+
+// parseFloat parses the given floating-point value.
+func parseFloat(s string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(s), 64)
+}
+
+// formatFloat returns the string representation of x, rounded to two decimals.
+func formatFloat(x float64) string {
+	x = math.Round(x*100) / 100
+	if x == 0 {
+		// Avoid negative zero.
+		x = 0
+	}
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
+
+// escape returns s with XML special characters escaped.
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// escaper escapes XML special characters.
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
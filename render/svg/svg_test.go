@@ -0,0 +1,68 @@
+package svg_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/layout"
+	"github.com/graphism/dot/render/svg"
+)
+
+func TestRender(t *testing.T) {
+	golden := []struct {
+		in  string
+		out string
+		// Lay out graph before rendering.
+		layout bool
+	}{
+		// Graph laid out by Graphviz.
+		{
+			in:  "testdata/graphviz.dot",
+			out: "testdata/graphviz.svg",
+		},
+		{
+			in:     "testdata/shapes.dot",
+			out:    "testdata/shapes.svg",
+			layout: true,
+		},
+	}
+	for _, g := range golden {
+		file, err := dot.ParseFile(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", g.in, err)
+			continue
+		}
+		graph := file.Graphs[0]
+		if g.layout {
+			if graph, err = layout.LayoutGraph(graph); err != nil {
+				t.Errorf("%q: unable to lay out graph; %v", g.in, err)
+				continue
+			}
+		}
+		buf, err := svg.Render(graph)
+		if err != nil {
+			t.Errorf("%q: unable to render graph; %v", g.in, err)
+			continue
+		}
+		want, err := ioutil.ReadFile(g.out)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", g.in, err)
+			continue
+		}
+		if !bytes.Equal(buf, want) {
+			t.Errorf("%q: SVG mismatch; expected `%s`, got `%s`", g.in, want, buf)
+		}
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	file, err := dot.ParseString("digraph { a -> b }")
+	if err != nil {
+		t.Fatalf("unable to parse graph; %v", err)
+	}
+	if _, err := svg.Render(file.Graphs[0]); err == nil {
+		t.Errorf("expected error for graph without layout, got nil")
+	}
+}
@@ -1,60 +1,342 @@
 package dot
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/color"
+	"github.com/graphism/dot/htmllabel"
+	"github.com/graphism/dot/internal/enc"
 	"github.com/pkg/errors"
 )
 
-// check validates the semantics of the given DOT file.
-func check(file *ast.File) error {
+// === [ Diagnostics ] =========================================================
+
+// A Diagnostic is a semantic problem of a DOT file.
+type Diagnostic struct {
+	// Severity of the problem.
+	Severity Severity
+	// Problem description.
+	Msg string
+	// Graph of the problem.
+	Graph *ast.Graph
+	// AST node of the problem (e.g. *ast.Attr or *ast.Node); or nil if unknown.
+	// The span of the node is recorded by the Positions option of the parser.
+	Node interface{}
+}
+
+// String returns the string representation of the diagnostic.
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("%v: %s", d.Severity, d.Msg)
+}
+
+// Severity specifies the severity of a diagnostic.
+type Severity uint8
+
+// Diagnostic severities.
+const (
+	// Invalid DOT file.
+	SeverityError Severity = iota
+	// Valid DOT file, which is likely not interpreted as intended (e.g.
+	// ignored by Graphviz).
+	SeverityWarning
+	// Valid DOT file, with noteworthy semantics.
+	SeverityInfo
+)
+
+// String returns the string representation of the severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	}
+	return fmt.Sprintf("Severity(%d)", uint8(s))
+}
+
+// A Config specifies the severity of configurable diagnostics.
+type Config struct {
+	// Severity of graphs with the same ID as a preceding graph of the DOT file.
+	DuplicateGraph Severity
+	// Severity of subgraphs with the same ID as a preceding subgraph of the
+	// same scope, which reopen the preceding subgraph.
+	DuplicateSubgraph Severity
+}
+
+// DefaultConfig is the default configuration of diagnostics.
+var DefaultConfig = Config{
+	DuplicateGraph:    SeverityWarning,
+	DuplicateSubgraph: SeverityInfo,
+}
+
+// Check validates the semantics of the given DOT file, and returns the
+// diagnostics of its graphs, using the default configuration. The checking of
+// a graph stops at its first error.
+func Check(file *ast.File) []*Diagnostic {
+	return CheckConfig(file, DefaultConfig)
+}
+
+// CheckConfig validates the semantics of the given DOT file, and returns the
+// diagnostics of its graphs, using the given configuration. The checking of a
+// graph stops at its first error.
+func CheckConfig(file *ast.File, config Config) []*Diagnostic {
+	// Without resource limits and cancellation, all errors are reported as
+	// diagnostics.
+	diags, _ := checkConfig(context.Background(), file, config, resolveLimits{})
+	return diags
+}
+
+// === [ Checker ] =============================================================
+
+// checkConfig validates the semantics of the given DOT file, and returns the
+// diagnostics of its graphs, using the given context, configuration and
+// resource limits. Errors of the context and of exceeded limits are returned,
+// rather than reported as diagnostics.
+func checkConfig(ctx context.Context, file *ast.File, config Config, limits resolveLimits) ([]*Diagnostic, error) {
+	var diags []*Diagnostic
+	graphs := make(map[string]bool)
 	for _, graph := range file.Graphs {
-		// TODO: Check graph.ID for duplicates?
-		if err := checkGraph(graph); err != nil {
+		c := newChecker(config)
+		c.checkGraphID(graphs, graph)
+		err := c.checkGraph(ctx, graph, limits)
+		if err != nil {
+			if _, ok := errors.Cause(err).(*LimitError); ok || ctx.Err() != nil {
+				return nil, errors.WithStack(err)
+			}
+			var node interface{}
+			if e, ok := errors.Cause(err).(*checkError); ok {
+				node = e.node
+			}
+			c.report(node, SeverityError, "%v", err)
+		}
+		for _, diag := range c.diags {
+			diag.Graph = graph
+		}
+		diags = append(diags, c.diags...)
+	}
+	return diags, nil
+}
+
+// check validates the semantics of the given DOT file, using the default
+// configuration and the given context and resource limits.
+func check(ctx context.Context, file *ast.File, limits resolveLimits) error {
+	graphs := make(map[string]bool)
+	for _, graph := range file.Graphs {
+		c := newChecker(DefaultConfig)
+		c.checkGraphID(graphs, graph)
+		if err := c.checkGraph(ctx, graph, limits); err != nil {
 			return errors.WithStack(err)
 		}
+		for _, diag := range c.diags {
+			if diag.Severity == SeverityError {
+				return errors.New(diag.Msg)
+			}
+		}
+	}
+	return nil
+}
+
+// checkStrict validates the semantics of the given DOT file, using the default
+// configuration and the given context and resource limits, and reports warnings
+// as errors.
+func checkStrict(ctx context.Context, file *ast.File, limits resolveLimits) error {
+	diags, err := checkConfig(ctx, file, DefaultConfig, limits)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, diag := range diags {
+		switch diag.Severity {
+		case SeverityError:
+			return errors.New(diag.Msg)
+		case SeverityWarning:
+			return errors.New(diag.String())
+		}
 	}
 	return nil
 }
 
-// check validates the semantics of the given graph.
-func checkGraph(graph *ast.Graph) error {
+// A checker validates the semantics of a graph.
+type checker struct {
+	// Configuration of diagnostics.
+	config Config
+	// Resolved graph.
+	graph *Graph
+	// Diagnostics reported; other than the error which stops the checking.
+	diags []*Diagnostic
+	// Current subgraph scope; or nil if root graph.
+	sub *ast.Subgraph
+	// subgraphs maps from enclosing subgraph scope and unquoted subgraph ID to
+	// the first subgraph of the scope with the ID.
+	subgraphs map[subgraphKey]*ast.Subgraph
+}
+
+// A subgraphKey identifies a named subgraph by its enclosing subgraph scope and
+// unquoted subgraph ID.
+type subgraphKey struct {
+	// Enclosing subgraph scope; or nil if root graph.
+	parent *ast.Subgraph
+	// Unquoted subgraph ID.
+	id string
+}
+
+// newChecker returns a new checker using the given configuration.
+func newChecker(config Config) *checker {
+	return &checker{
+		config:    config,
+		subgraphs: make(map[subgraphKey]*ast.Subgraph),
+	}
+}
+
+// warnf reports a warning diagnostic of the given AST node with the given
+// message.
+func (c *checker) warnf(node interface{}, format string, args ...interface{}) {
+	c.report(node, SeverityWarning, format, args...)
+}
+
+// report reports a diagnostic of the given AST node and severity with the given
+// message.
+func (c *checker) report(node interface{}, severity Severity, format string, args ...interface{}) {
+	c.diags = append(c.diags, &Diagnostic{Severity: severity, Msg: fmt.Sprintf(format, args...), Node: node})
+}
+
+// errorf returns an error of the given AST node with the given message, which
+// stops the checking.
+func errorf(node interface{}, format string, args ...interface{}) error {
+	return errors.WithStack(&checkError{node: node, msg: fmt.Sprintf(format, args...)})
+}
+
+// A checkError is an error of the checker, located at an AST node.
+type checkError struct {
+	// AST node of the error.
+	node interface{}
+	// Error message.
+	msg string
+}
+
+// Error returns the error message.
+func (e *checkError) Error() string {
+	return e.msg
+}
+
+// checkGraphID reports the given graph if its ID is present in graphs, the IDs
+// of the preceding graphs of the DOT file, and adds the ID otherwise.
+func (c *checker) checkGraphID(graphs map[string]bool, graph *ast.Graph) {
+	id := enc.Unquote(graph.ID)
+	if id == "" {
+		return
+	}
+	if graphs[id] {
+		c.report(graph, c.config.DuplicateGraph, "duplicate graph ID %q", graph.ID)
+		return
+	}
+	graphs[id] = true
+}
+
+// checkGraph validates the semantics of the given graph, using the given
+// context and resource limits of resolving the graph.
+func (c *checker) checkGraph(ctx context.Context, graph *ast.Graph, limits resolveLimits) error {
+	// Statements are checked against the resolved graph, as the attributes of
+	// a node (e.g. its shape and label) may be specified anywhere in the graph.
+	g, err := resolve(ctx, graph, true, limits)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	c.graph = g
 	for _, stmt := range graph.Stmts {
-		if err := checkStmt(graph, stmt); err != nil {
+		if err := c.checkStmt(stmt); err != nil {
 			return errors.WithStack(err)
 		}
 	}
+	c.checkColors()
 	return nil
 }
 
+// checkColors reports invalid colour attributes of the graph, and its
+// subgraphs, nodes and edges. Colour names are resolved in the colour scheme
+// in scope of each component. Invalid colours are reported as warnings, as
+// Graphviz ignores them.
+func (c *checker) checkColors() {
+	graph := c.graph
+	scheme := c.checkColorAttrs(fmt.Sprintf("graph %q", graph.ID), graph.Attrs, "")
+	for _, sub := range graph.Subgraphs {
+		c.checkSubgraphColors(sub, scheme)
+	}
+	for _, n := range graph.Nodes {
+		c.checkColorAttrs(fmt.Sprintf("node %q", n.ID), n.Attrs, "")
+	}
+	for _, e := range graph.Edges {
+		c.checkColorAttrs(fmt.Sprintf("edge from %q to %q", e.From.ID, e.To.ID), e.Attrs, "")
+	}
+}
+
+// checkSubgraphColors reports invalid colour attributes of the given subgraph,
+// and its nested subgraphs, where the given colour scheme is inherited from
+// the enclosing graph.
+func (c *checker) checkSubgraphColors(sub *Subgraph, scheme string) {
+	scheme = c.checkColorAttrs(fmt.Sprintf("subgraph %q", sub.ID), sub.Attrs, scheme)
+	for _, child := range sub.Subgraphs {
+		c.checkSubgraphColors(child, scheme)
+	}
+}
+
+// checkColorAttrs reports invalid colour attributes of the named component, in
+// the colour scheme of its attributes; or the given inherited colour scheme if
+// not present or invalid. The colour scheme in effect is returned.
+func (c *checker) checkColorAttrs(name string, attrs Attrs, scheme string) string {
+	for _, attr := range attrs {
+		if enc.Unquote(attr.Key) != "colorscheme" {
+			continue
+		}
+		if color.IsScheme(enc.Unquote(attr.Val)) {
+			scheme = attr.Val
+		} else {
+			c.warnf(c.graph.src(attr), "invalid colour scheme %s of %s", attr.Val, name)
+		}
+	}
+	for _, attr := range attrs {
+		if !color.IsKey(attr.Key) {
+			continue
+		}
+		if _, err := color.ParseList(attr.Val, enc.Unquote(scheme)); err != nil {
+			c.warnf(c.graph.src(attr), "invalid %s %s of %s; %v", enc.Unquote(attr.Key), attr.Val, name, err)
+		}
+	}
+	return scheme
+}
+
 // check validates the semantics of the given statement.
-func checkStmt(graph *ast.Graph, stmt ast.Stmt) error {
+func (c *checker) checkStmt(stmt ast.Stmt) error {
 	switch stmt := stmt.(type) {
 	case *ast.NodeStmt:
-		return checkNodeStmt(graph, stmt)
+		return c.checkNodeStmt(stmt)
 	case *ast.EdgeStmt:
-		return checkEdgeStmt(graph, stmt)
+		return c.checkEdgeStmt(stmt)
 	case *ast.AttrStmt:
-		return checkAttrStmt(graph, stmt)
+		return c.checkAttrStmt(stmt)
 	case *ast.Attr:
 		// TODO: Verify that the attribute is indeed of graph component kind.
-		return checkAttr(graph, ast.KindGraph, stmt)
+		return c.checkAttr(ast.KindGraph, stmt)
 	case *ast.Subgraph:
-		return checkSubgraph(graph, stmt)
+		return c.checkSubgraph(stmt)
 	default:
-		panic(fmt.Sprintf("support for statement of type %T not yet implemented", stmt))
+		return errorf(nil, "support for statement of type %T not yet implemented", stmt)
 	}
 }
 
 // checkNodeStmt validates the semantics of the given node statement.
-func checkNodeStmt(graph *ast.Graph, stmt *ast.NodeStmt) error {
-	if err := checkNode(graph, stmt.Node); err != nil {
+func (c *checker) checkNodeStmt(stmt *ast.NodeStmt) error {
+	if stmt.Node.Port != nil {
+		c.warnf(stmt.Node, "port %q of node %q ignored in node statement", portAttr(stmt.Node.Port), stmt.Node.ID)
+	} else if err := c.checkNode(stmt.Node); err != nil {
 		return errors.WithStack(err)
 	}
 	for _, attr := range stmt.Attrs {
 		// TODO: Verify that the attribute is indeed of node component kind.
-		if err := checkAttr(graph, ast.KindNode, attr); err != nil {
+		if err := c.checkAttr(ast.KindNode, attr); err != nil {
 			return errors.WithStack(err)
 		}
 	}
@@ -62,38 +344,73 @@ func checkNodeStmt(graph *ast.Graph, stmt *ast.NodeStmt) error {
 }
 
 // checkEdgeStmt validates the semantics of the given edge statement.
-func checkEdgeStmt(graph *ast.Graph, stmt *ast.EdgeStmt) error {
+func (c *checker) checkEdgeStmt(stmt *ast.EdgeStmt) error {
 	// TODO: if graph.Strict, check for multi-edges.
-	if err := checkVertex(graph, stmt.From); err != nil {
+	if err := c.checkVertex(stmt.From); err != nil {
 		return errors.WithStack(err)
 	}
 	for _, attr := range stmt.Attrs {
 		// TODO: Verify that the attribute is indeed of edge component kind.
-		if err := checkAttr(graph, ast.KindEdge, attr); err != nil {
+		if err := c.checkAttr(ast.KindEdge, attr); err != nil {
 			return errors.WithStack(err)
 		}
 	}
-	return checkEdge(graph, stmt.From, stmt.To)
+	return c.checkEdge(stmt.From, stmt.To, stmt.Attrs)
 }
 
-// checkEdge validates the semantics of the given edge.
-func checkEdge(graph *ast.Graph, from ast.Vertex, to *ast.Edge) error {
-	if !graph.Directed && to.Directed {
-		return errors.Errorf("undirected graph %q contains directed edge from %q to %q", graph.ID, from, to.Vertex)
+// checkEdge validates the semantics of the given edge, with the attributes of
+// its edge statement.
+func (c *checker) checkEdge(from ast.Vertex, to *ast.Edge, attrs []*ast.Attr) error {
+	if !c.graph.Directed && to.Directed {
+		return errorf(to, "undirected graph %q contains directed edge from %q to %q", c.graph.ID, from, to.Vertex)
 	}
-	if err := checkVertex(graph, to.Vertex); err != nil {
+	if err := c.checkVertex(to.Vertex); err != nil {
 		return errors.WithStack(err)
 	}
+	c.checkEdgePort(from, to.Vertex, "tailport", from, attrs)
+	c.checkEdgePort(from, to.Vertex, "headport", to.Vertex, attrs)
 	if to.To != nil {
-		return checkEdge(graph, to.Vertex, to.To)
+		return c.checkEdge(to.Vertex, to.To, attrs)
 	}
 	return nil
 }
 
+// checkEdgePort reports conflicts between the port of the given endpoint of an
+// edge, and the port attribute with the given key (tailport or headport) of
+// its edge statement. Ports of endpoints take precedence over port attributes.
+func (c *checker) checkEdgePort(from, to ast.Vertex, key string, endpoint ast.Vertex, attrs []*ast.Attr) {
+	node, ok := endpoint.(*ast.Node)
+	if !ok || node.Port == nil {
+		return
+	}
+	port := portAttr(node.Port)
+	for _, attr := range attrs {
+		if enc.Unquote(attr.Key) != key {
+			continue
+		}
+		if val := enc.Unquote(attr.Val); val != port {
+			c.warnf(attr, "%s %q of edge from %q to %q overridden by port %q of node %q", key, val, from, to, port, node.ID)
+		}
+	}
+}
+
+// portAttr returns the port attribute value (as of tailport and headport)
+// equivalent to the given port; "id", "id:compass" or "compass".
+func portAttr(port *ast.Port) string {
+	id := enc.Unquote(port.ID)
+	switch {
+	case id == "":
+		return port.CompassPoint.String()
+	case port.CompassPoint == ast.CompassPointDefault:
+		return id
+	}
+	return id + ":" + port.CompassPoint.String()
+}
+
 // checkAttrStmt validates the semantics of the given attribute statement.
-func checkAttrStmt(graph *ast.Graph, stmt *ast.AttrStmt) error {
+func (c *checker) checkAttrStmt(stmt *ast.AttrStmt) error {
 	for _, attr := range stmt.Attrs {
-		if err := checkAttr(graph, stmt.Kind, attr); err != nil {
+		if err := c.checkAttr(stmt.Kind, attr); err != nil {
 			return errors.WithStack(err)
 		}
 	}
@@ -102,7 +419,7 @@ func checkAttrStmt(graph *ast.Graph, stmt *ast.AttrStmt) error {
 
 // checkAttr validates the semantics of the given attribute for the given
 // component kind.
-func checkAttr(graph *ast.Graph, kind ast.Kind, attr *ast.Attr) error {
+func (c *checker) checkAttr(kind ast.Kind, attr *ast.Attr) error {
 	switch kind {
 	case ast.KindGraph:
 		// TODO: Validate key-value pairs for graphs.
@@ -114,17 +431,31 @@ func checkAttr(graph *ast.Graph, kind ast.Kind, attr *ast.Attr) error {
 		// TODO: Validate key-value pairs for edges.
 		return nil
 	default:
-		panic(fmt.Sprintf("support for component kind %v not yet supported", kind))
+		return errorf(attr, "support for graph component kind %d not yet implemented", uint(kind))
 	}
 }
 
 // checkSubgraph validates the semantics of the given subgraph.
-func checkSubgraph(graph *ast.Graph, subgraph *ast.Subgraph) error {
-	// TODO: Check subgraph.ID for duplicates?
+func (c *checker) checkSubgraph(subgraph *ast.Subgraph) error {
+	// Subgraphs are identified by their enclosing subgraph scope and ID, as
+	// Graphviz only reopens subgraphs of the same scope. Anonymous subgraphs are
+	// never reopened.
+	parent := c.sub
+	defer func() { c.sub = parent }()
+	c.sub = subgraph
+	if subgraph.ID != "" {
+		key := subgraphKey{parent: parent, id: enc.Unquote(subgraph.ID)}
+		if prev, ok := c.subgraphs[key]; ok {
+			c.report(subgraph, c.config.DuplicateSubgraph, "subgraph %q reopened; statements extend the preceding subgraph with the same ID", subgraph.ID)
+			c.sub = prev
+		} else {
+			c.subgraphs[key] = subgraph
+		}
+	}
 	for _, stmt := range subgraph.Stmts {
 		// TODO: Refine handling of subgraph statements?
 		//    checkSubgraphStmt(graph, subgraph, stmt)
-		if err := checkStmt(graph, stmt); err != nil {
+		if err := c.checkStmt(stmt); err != nil {
 			return errors.WithStack(err)
 		}
 	}
@@ -132,20 +463,60 @@ func checkSubgraph(graph *ast.Graph, subgraph *ast.Subgraph) error {
 }
 
 // checkVertex validates the semantics of the given vertex.
-func checkVertex(graph *ast.Graph, vertex ast.Vertex) error {
+func (c *checker) checkVertex(vertex ast.Vertex) error {
 	switch vertex := vertex.(type) {
 	case *ast.Node:
-		return checkNode(graph, vertex)
+		return c.checkNode(vertex)
 	case *ast.Subgraph:
-		return checkSubgraph(graph, vertex)
+		return c.checkSubgraph(vertex)
 	default:
-		panic(fmt.Sprintf("support for vertex of type %T not yet supported", vertex))
+		return errorf(nil, "support for vertex of type %T not yet implemented", vertex)
 	}
 }
 
 // checNode validates the semantics of the given node.
-func checkNode(graph *ast.Graph, node *ast.Node) error {
+func (c *checker) checkNode(node *ast.Node) error {
 	// TODO: Check node.ID for duplicates?
-	// TODO: Validate node.Port.
+	if node.Port != nil && len(node.Port.InvalidCompassPoint) > 0 {
+		c.warnf(node, "invalid compass point %q of port %q of node %q ignored; expected n, ne, e, se, s, sw, w, nw, c or _", node.Port.InvalidCompassPoint, node.Port.ID, node.ID)
+	}
+	if node.Port == nil || node.Port.ID == "" {
+		return nil
+	}
+	n, ok := c.graph.Node(node.ID)
+	if !ok {
+		return errorf(node, "unable to locate node %q", node.ID)
+	}
+	port := enc.Unquote(node.Port.ID)
+	if v, ok := n.Attrs.Get("label"); ok && enc.IsHTML(v) {
+		l, err := htmllabel.Parse(v)
+		if err != nil {
+			// Malformed HTML-like labels are not validated by the checker.
+			return nil
+		}
+		for _, p := range l.Ports() {
+			if p == port {
+				return nil
+			}
+		}
+		c.warnf(node, "invalid port %q of node %q; no such PORT in HTML-like label", node.Port.ID, node.ID)
+		return nil
+	}
+	label, err := n.Record()
+	if err != nil {
+		c.warnf(node, "%v", err)
+		return nil
+	}
+	if label == nil {
+		shape, _ := n.Attrs.Get("shape")
+		if shape == "" {
+			shape = "ellipse"
+		}
+		c.warnf(node, "port %q of node %q ignored; shape %s has no fields", node.Port.ID, node.ID, shape)
+		return nil
+	}
+	if _, ok := label.Field(port); !ok {
+		c.warnf(node, "invalid port %q of node %q; no such record field", node.Port.ID, node.ID)
+	}
 	return nil
 }
@@ -0,0 +1,264 @@
+package dot_test
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/ast"
+)
+
+func TestCheck(t *testing.T) {
+	golden := []struct {
+		in   string
+		want []string
+	}{
+		{
+			in: `digraph { a:n -> b:p:s }`,
+			want: []string{
+				`warning: port "p" of node "b" ignored; shape ellipse has no fields`,
+			},
+		},
+		{
+			in: `digraph { a:p [label=x] }`,
+			want: []string{
+				`warning: port "p" of node "a" ignored in node statement`,
+			},
+		},
+		{
+			in: `digraph { node [shape=record]; a:f0:n -> b [tailport="f1:s" headport=e]; a [label="<f0>x|<f1>y"] }`,
+			want: []string{
+				`warning: tailport "f1:s" of edge from "a:f0:n" to "b" overridden by port "f0:n" of node "a"`,
+			},
+		},
+		{
+			in: `digraph { a:f1 -> b:c; a [shape=Mrecord label="<f0>x|{<f1>y|z}"] }`,
+		},
+		{
+			in: `digraph { a:f2 -> b; a [shape=record label="<f0>x|{<f1>y|z}"] }`,
+			want: []string{
+				`warning: invalid port "f2" of node "a"; no such record field`,
+			},
+		},
+		{
+			in: `digraph { a:p1 -> a:p2; a [label=<<TABLE><TR><TD PORT="p1">x</TD></TR></TABLE>>] }`,
+			want: []string{
+				`warning: invalid port "p2" of node "a"; no such PORT in HTML-like label`,
+			},
+		},
+		{
+			in: `digraph { a:f0 -> b; a [shape=record label="<f0>x|{y"] }`,
+			want: []string{
+				`warning: invalid record label "<f0>x|{y" of node "a"; offset 6: missing '}' of nested fields`,
+			},
+		},
+		{
+			in: `graph G { a } graph "G" { b } graph { c } graph { d }`,
+			want: []string{
+				`warning: duplicate graph ID "\"G\""`,
+			},
+		},
+		{
+			in: `graph { subgraph s { a } subgraph t { subgraph s { b } } subgraph "s" { c } { subgraph u {} } { subgraph u {} } }`,
+			want: []string{
+				`info: subgraph "\"s\"" reopened; statements extend the preceding subgraph with the same ID`,
+			},
+		},
+		{
+			in: `digraph { a:p:x -> b:"ne"; a [shape=record label="<p>x"] }`,
+			want: []string{
+				`warning: invalid compass point "x" of port "p" of node "a" ignored; expected n, ne, e, se, s, sw, w, nw, c or _`,
+			},
+		},
+		{
+			in: `digraph { colorscheme=x; a [color=foo]; b [color=""]; a -> b [color="red:bar"]; subgraph s { bgcolor=1 } }`,
+			want: []string{
+				`warning: invalid colour scheme x of graph ""`,
+				`warning: invalid bgcolor 1 of subgraph "s"; unknown colour "1" in colour scheme "x11"`,
+				`warning: invalid color foo of node "a"; unknown colour "foo" in colour scheme "x11"`,
+				`warning: invalid color "" of node "b"; empty colour`,
+				`warning: invalid color "red:bar" of edge from "a" to "b"; unknown colour "bar" in colour scheme "x11"`,
+			},
+		},
+		{
+			in: `digraph { node [colorscheme=blues9]; a [color=3]; subgraph s { colorscheme=accent8; bgcolor=8 } }`,
+		},
+	}
+	for _, g := range golden {
+		file, err := dot.ParseString(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", g.in, err)
+			continue
+		}
+		var got []string
+		for _, diag := range dot.Check(file) {
+			got = append(got, diag.String())
+		}
+		if !reflect.DeepEqual(got, g.want) {
+			t.Errorf("%q: diagnostics mismatch; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	in := `graph G { subgraph s { a } subgraph s { b } } graph G {}`
+	file, err := dot.ParseString(in)
+	if err != nil {
+		t.Fatalf("%q: unable to parse file; %v", in, err)
+	}
+	config := dot.Config{
+		DuplicateGraph:    dot.SeverityError,
+		DuplicateSubgraph: dot.SeverityWarning,
+	}
+	var got []string
+	for _, diag := range dot.CheckConfig(file, config) {
+		got = append(got, diag.String())
+	}
+	want := []string{
+		`warning: subgraph "s" reopened; statements extend the preceding subgraph with the same ID`,
+		`error: duplicate graph ID "G"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%q: diagnostics mismatch; expected %q, got %q", in, want, got)
+	}
+}
+
+func TestResolveSubgraph(t *testing.T) {
+	in := `graph { subgraph s { node [shape=box]; a } subgraph t { subgraph s { b } } subgraph "s" { c -- d } }`
+	file, err := dot.ParseString(in)
+	if err != nil {
+		t.Fatalf("%q: unable to parse file; %v", in, err)
+	}
+	g, err := dot.Resolve(file.Graphs[0])
+	if err != nil {
+		t.Fatalf("%q: unable to resolve graph; %v", in, err)
+	}
+	if len(g.Subgraphs) != 2 {
+		t.Fatalf("%q: number of subgraphs mismatch; expected 2, got %d", in, len(g.Subgraphs))
+	}
+	s := g.Subgraphs[0]
+	var nodes []string
+	for _, n := range s.Nodes {
+		shape, _ := n.Attrs.Get("shape")
+		nodes = append(nodes, n.ID+":"+shape)
+	}
+	wantNodes := []string{"a:box", "c:box", "d:box"}
+	if !reflect.DeepEqual(nodes, wantNodes) {
+		t.Errorf("%q: nodes of reopened subgraph mismatch; expected %q, got %q", in, wantNodes, nodes)
+	}
+	if len(s.Edges) != 1 {
+		t.Errorf("%q: number of edges of reopened subgraph mismatch; expected 1, got %d", in, len(s.Edges))
+	}
+}
+
+func TestCheckError(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		{
+			in:   `graph { a -- b; c -> d }`,
+			want: `undirected graph "" contains directed edge from "c" to "d"`,
+		},
+	}
+	for _, g := range golden {
+		_, err := dot.ParseString(g.in)
+		if err == nil {
+			t.Errorf("%q: expected error, got nil", g.in)
+			continue
+		}
+		if got := err.Error(); got != g.want {
+			t.Errorf("%q: error mismatch; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+}
+
+func TestCheckInvalidAST(t *testing.T) {
+	// Invalid abstract syntax trees are reported as errors, not panics.
+	golden := []struct {
+		graph *ast.Graph
+		want  string
+	}{
+		{
+			graph: &ast.Graph{Stmts: []ast.Stmt{nil}},
+			want:  "error: support for statement of type <nil> not yet implemented",
+		},
+		{
+			graph: &ast.Graph{Stmts: []ast.Stmt{&ast.EdgeStmt{From: nil, To: &ast.Edge{Vertex: &ast.Node{ID: "a"}}}}},
+			want:  "error: support for vertex of type <nil> not yet implemented",
+		},
+		{
+			graph: &ast.Graph{Stmts: []ast.Stmt{&ast.AttrStmt{Kind: 7, Attrs: []*ast.Attr{{Key: "a", Val: "b"}}}}},
+			want:  "error: support for graph component kind 7 not yet implemented",
+		},
+	}
+	for _, g := range golden {
+		diags := dot.Check(&ast.File{Graphs: []*ast.Graph{g.graph}})
+		var got []string
+		for _, diag := range diags {
+			got = append(got, diag.String())
+		}
+		if len(got) != 1 || got[0] != g.want {
+			t.Errorf("diagnostics mismatch; expected [%s], got %q", g.want, got)
+		}
+	}
+}
+
+func TestCheckDeepNesting(t *testing.T) {
+	// Memory use of the checker is linear in the nesting depth of subgraphs.
+	const depth = 5000
+	src := "graph { " + strings.Repeat("subgraph s { ", depth) + strings.Repeat("} ", depth) + "}"
+	file, err := dot.ParseString(src)
+	if err != nil {
+		t.Fatalf("unable to parse file; %v", err)
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	diags := dot.Check(file)
+	runtime.ReadMemStats(&after)
+	if len(diags) != 0 {
+		t.Errorf("diagnostics mismatch; expected none, got %v", diags)
+	}
+	if n := after.TotalAlloc - before.TotalAlloc; n > 16<<20 {
+		t.Errorf("memory use of checker exceeds 16 MiB; got %d bytes", n)
+	}
+}
+
+func TestCheckPositions(t *testing.T) {
+	// Diagnostics are located by the AST node of the problem.
+	golden := []struct {
+		in   string
+		want []string
+	}{
+		{in: `graph { a [color=nocolor] }`, want: []string{"warning: `color=nocolor`"}},
+		{in: `graph { node [fillcolor="#xyz"]; a; b }`, want: []string{"warning: `fillcolor=\"#xyz\"`", "warning: `fillcolor=\"#xyz\"`"}},
+		{in: `graph { colorscheme=nope; a }`, want: []string{"warning: `colorscheme=nope`"}},
+		{in: `graph { a [shape=record label="<p>"]; a:p:x -- b }`, want: []string{"warning: `a:p:x`"}},
+		{in: `graph { a -- b -> c }`, want: []string{"error: `->`"}},
+		{in: `graph { subgraph s { a } subgraph "s" { b } { c } }`, want: []string{"info: `\"s\"`"}},
+		{in: `graph G { a } digraph "G" { b }`, want: []string{"warning: `\"G\"`"}},
+	}
+	for _, g := range golden {
+		positions := make(dot.Positions)
+		file, err := dot.ParseBytesWithOptions([]byte(g.in), dot.ParseOptions{Check: dot.CheckNone, Positions: positions})
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", g.in, err)
+			continue
+		}
+		var got []string
+		for _, diag := range dot.Check(file) {
+			span, ok := positions[diag.Node]
+			if !ok {
+				got = append(got, fmt.Sprintf("%v: unknown position", diag.Severity))
+				continue
+			}
+			got = append(got, fmt.Sprintf("%v: `%s`", diag.Severity, g.in[span.Offset:span.Offset+span.Len]))
+		}
+		if !reflect.DeepEqual(got, g.want) {
+			t.Errorf("%q: diagnostic positions mismatch; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Package tgf implements conversion between Graphviz DOT graphs and the Trivial
+// Graph Format (TGF).
+//
+// A TGF file lists one node per line, consisting of a node ID optionally
+// followed by a label. The node list is terminated by a line containing a
+// single '#' character, and followed by one edge per line, consisting of a
+// source and a destination node ID optionally followed by a label.
+//
+//    1 First node
+//    2 Second node
+//    #
+//    1 2 Edge between the two
+//
+// TGF labels are mapped onto DOT label attributes. Any other attributes are not
+// encoded. As TGF has no notion of edge direction, decoded graphs are directed.
+//
+// ref: https://en.wikipedia.org/wiki/Trivial_Graph_Format
+package tgf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/internal/enc"
+	"github.com/pkg/errors"
+)
+
+// Marshal returns the TGF encoding of the given graph.
+func Marshal(graph *ast.Graph) ([]byte, error) {
+	g, err := dot.Resolve(graph)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// Node names containing whitespace are replaced by unused integer IDs.
+	ids := make(map[*dot.Node]string)
+	used := make(map[string]bool)
+	for _, n := range g.Nodes {
+		name := enc.Unquote(n.ID)
+		if len(name) > 0 && !strings.ContainsAny(name, " \t\r\n") && name != "#" {
+			ids[n] = name
+			used[name] = true
+		}
+	}
+	next := 0
+	for _, n := range g.Nodes {
+		if _, ok := ids[n]; ok {
+			continue
+		}
+		for used[strconv.Itoa(next)] {
+			next++
+		}
+		ids[n] = strconv.Itoa(next)
+		used[ids[n]] = true
+	}
+	buf := new(bytes.Buffer)
+	for _, n := range g.Nodes {
+		label, ok := n.Attrs.Get("label")
+		switch {
+		case ok:
+			writeLine(buf, ids[n], label)
+		case ids[n] != enc.Unquote(n.ID):
+			writeLine(buf, ids[n], n.ID)
+		default:
+			writeLine(buf, ids[n], "")
+		}
+	}
+	buf.WriteString("#\n")
+	for _, e := range g.Edges {
+		label, _ := e.Attrs.Get("label")
+		writeLine(buf, ids[e.From]+" "+ids[e.To], label)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeLine writes a line of the given IDs and optional label to buf. Line
+// breaks of the label are replaced by the \n escape sequence.
+func writeLine(buf *bytes.Buffer, ids, label string) {
+	label = enc.Unquote(label)
+	label = strings.Replace(label, "\r\n", `\n`, -1)
+	label = strings.Replace(label, "\n", `\n`, -1)
+	if len(label) > 0 {
+		fmt.Fprintf(buf, "%s %s\n", ids, label)
+		return
+	}
+	fmt.Fprintf(buf, "%s\n", ids)
+}
+
+// Unmarshal parses the TGF-encoded data into a graph.
+func Unmarshal(data []byte) (*ast.Graph, error) {
+	graph := &ast.Graph{Directed: true}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	edges := false
+	for lineNum := 1; s.Scan(); lineNum++ {
+		line := strings.TrimSpace(s.Text())
+		if len(line) == 0 {
+			continue
+		}
+		if !enc.IsText(line) {
+			return nil, errors.Errorf("line %d: invalid line %q; contains NUL, U+FFFD or invalid UTF-8", lineNum, line)
+		}
+		if line == "#" {
+			if edges {
+				return nil, errors.Errorf("line %d: unexpected '#'; edge list already started", lineNum)
+			}
+			edges = true
+			continue
+		}
+		if !edges {
+			id, label := split(line)
+			stmt := &ast.NodeStmt{Node: &ast.Node{ID: enc.Quote(id)}}
+			if len(label) > 0 {
+				stmt.Attrs = []*ast.Attr{{Key: "label", Val: enc.Quote(label)}}
+			}
+			graph.Stmts = append(graph.Stmts, stmt)
+			continue
+		}
+		from, rest := split(line)
+		to, label := split(rest)
+		if len(to) == 0 {
+			return nil, errors.Errorf("line %d: invalid edge %q; missing destination node", lineNum, line)
+		}
+		stmt := &ast.EdgeStmt{
+			From: &ast.Node{ID: enc.Quote(from)},
+			To:   &ast.Edge{Directed: true, Vertex: &ast.Node{ID: enc.Quote(to)}},
+		}
+		if len(label) > 0 {
+			stmt.Attrs = []*ast.Attr{{Key: "label", Val: enc.Quote(label)}}
+		}
+		graph.Stmts = append(graph.Stmts, stmt)
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return graph, nil
+}
+
+// split splits the line at the first whitespace character, returning the
+// leading field and the trimmed remainder.
+func split(line string) (string, string) {
+	pos := strings.IndexAny(line, " \t")
+	if pos == -1 {
+		return line, ""
+	}
+	return line[:pos], strings.TrimSpace(line[pos+1:])
+}
@@ -0,0 +1,101 @@
+package tgf_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/tgf"
+)
+
+func TestMarshal(t *testing.T) {
+	golden := []struct {
+		in  string
+		out string
+	}{
+		{
+			in:  "testdata/graph.dot",
+			out: "testdata/graph.golden.tgf",
+		},
+		{
+			in:  "testdata/graph.golden",
+			out: "testdata/graph.tgf",
+		},
+	}
+	for _, g := range golden {
+		file, err := dot.ParseFile(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to parse file; %v", g.in, err)
+			continue
+		}
+		buf, err := tgf.Marshal(file.Graphs[0])
+		if err != nil {
+			t.Errorf("%q: unable to encode graph; %v", g.in, err)
+			continue
+		}
+		want, err := ioutil.ReadFile(g.out)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", g.in, err)
+			continue
+		}
+		if !bytes.Equal(buf, want) {
+			t.Errorf("%q: TGF mismatch; expected `%s`, got `%s`", g.in, want, buf)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	golden := []struct {
+		in  string
+		out string
+	}{
+		{
+			in:  "testdata/graph.tgf",
+			out: "testdata/graph.golden",
+		},
+	}
+	for _, g := range golden {
+		buf, err := ioutil.ReadFile(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", g.in, err)
+			continue
+		}
+		graph, err := tgf.Unmarshal(buf)
+		if err != nil {
+			t.Errorf("%q: unable to decode graph; %v", g.in, err)
+			continue
+		}
+		want, err := ioutil.ReadFile(g.out)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %v", g.in, err)
+			continue
+		}
+		got := graph.String()
+		if got != string(bytes.TrimSpace(want)) {
+			t.Errorf("%q: graph mismatch; expected `%s`, got `%s`", g.in, want, got)
+		}
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		{in: "1\n#\n#", want: "line 3: unexpected '#'; edge list already started"},
+		{in: "1\n#\n1", want: `line 3: invalid edge "1"; missing destination node`},
+		// NUL characters are invalid in DOT IDs.
+		{in: "\x00", want: `line 1: invalid line "\x00"; contains NUL, U+FFFD or invalid UTF-8`},
+	}
+	for _, g := range golden {
+		_, err := tgf.Unmarshal([]byte(g.in))
+		if err == nil {
+			t.Errorf("%q: expected error, got nil", g.in)
+			continue
+		}
+		if got := err.Error(); got != g.want {
+			t.Errorf("%q: error mismatch; expected `%v`, got `%v`", g.in, g.want, got)
+		}
+	}
+}
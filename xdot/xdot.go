@@ -0,0 +1,530 @@
+// Package xdot implements decoding and encoding of xdot drawing operations.
+//
+// Graphviz xdot output (dot -Txdot) stores drawing operations in the _draw_,
+// _ldraw_, _hdraw_, _tdraw_, _hldraw_ and _tldraw_ attributes of graphs, nodes
+// and edges. Each attribute holds a sequence of operations, as specified by
+// the following grammar.
+//
+//    E x y w h           filled ellipse
+//    e x y w h           unfilled ellipse
+//    P n x1 y1 ... xn yn filled polygon
+//    p n x1 y1 ... xn yn unfilled polygon
+//    L n x1 y1 ... xn yn polyline
+//    B n x1 y1 ... xn yn B-spline
+//    b n x1 y1 ... xn yn filled B-spline
+//    T x y j w n -text   text
+//    t f                 font characteristics
+//    C n -color          fill colour
+//    c n -color          pen colour
+//    F s n -font         font size and name
+//    S n -style          style
+//    I x y w h n -name   image
+//
+// Strings are prefixed by their length in bytes and a dash.
+//
+// ref: https://graphviz.org/docs/outputs/canon/#xdot
+package xdot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphism/dot/ast"
+	"github.com/graphism/dot/internal/enc"
+	"github.com/pkg/errors"
+)
+
+// Keys lists the keys of attributes holding xdot drawing operations.
+var Keys = []string{"_draw_", "_ldraw_", "_hdraw_", "_tdraw_", "_hldraw_", "_tldraw_"}
+
+// IsKey reports whether key is the key of an attribute holding xdot drawing
+// operations.
+func IsKey(key string) bool {
+	key = enc.Unquote(key)
+	for _, k := range Keys {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
+
+// === [ Operations ] ==========================================================
+
+// An Op is an xdot drawing operation.
+//
+// Op may have one of the following underlying types.
+//
+//    *Ellipse
+//    *Polygon
+//    *Polyline
+//    *BSpline
+//    *Text
+//    *FontChars
+//    *FillColor
+//    *PenColor
+//    *Font
+//    *Style
+//    *Image
+type Op interface {
+	// String returns the xdot representation of the drawing operation.
+	String() string
+	// isOp ensures that only drawing operations can be assigned to the Op
+	// interface.
+	isOp()
+}
+
+// A Point is a point in points, with the y-axis pointing upwards.
+type Point struct {
+	X, Y float64
+}
+
+// An Ellipse is a filled or unfilled ellipse.
+type Ellipse struct {
+	// Filled ellipse.
+	Filled bool
+	// Center of the ellipse.
+	X, Y float64
+	// Horizontal and vertical radius of the ellipse.
+	W, H float64
+}
+
+// A Polygon is a filled or unfilled polygon.
+type Polygon struct {
+	// Filled polygon.
+	Filled bool
+	// Vertices of the polygon.
+	Points []Point
+}
+
+// A Polyline is a sequence of line segments.
+type Polyline struct {
+	// Points of the polyline.
+	Points []Point
+}
+
+// A BSpline is a filled or unfilled B-spline.
+type BSpline struct {
+	// Filled B-spline.
+	Filled bool
+	// Control points of the B-spline.
+	Points []Point
+}
+
+// Align specifies the alignment of text relative to its position.
+type Align int
+
+// Text alignments.
+const (
+	AlignLeft   Align = -1
+	AlignCenter Align = 0
+	AlignRight  Align = 1
+)
+
+// A Text is a line of text.
+type Text struct {
+	// Baseline position of the text.
+	X, Y float64
+	// Alignment of the text relative to its position.
+	Align Align
+	// Width of the text.
+	Width float64
+	// Text.
+	Text string
+}
+
+// FontFlags specifies font characteristics.
+type FontFlags uint
+
+// Font characteristics.
+const (
+	FontBold FontFlags = 1 << iota
+	FontItalic
+	FontUnderline
+	FontSuperscript
+	FontSubscript
+	FontStrikeThrough
+	FontOverline
+)
+
+// FontChars sets the font characteristics of subsequent text.
+type FontChars struct {
+	// Font characteristics.
+	Flags FontFlags
+}
+
+// FillColor sets the fill colour of subsequent drawing operations.
+type FillColor struct {
+	// Colour; a colour name, an RGB(A) value or a gradient.
+	Color string
+}
+
+// PenColor sets the pen colour of subsequent drawing operations.
+type PenColor struct {
+	// Colour; a colour name, an RGB(A) value or a gradient.
+	Color string
+}
+
+// Font sets the font of subsequent text.
+type Font struct {
+	// Font size, in points.
+	Size float64
+	// Font name.
+	Name string
+}
+
+// Style sets the style of subsequent drawing operations; e.g. "dashed" or
+// "setlinewidth(2)".
+type Style struct {
+	// Style.
+	Style string
+}
+
+// An Image is an externally provided image.
+type Image struct {
+	// Lower left corner of the image.
+	X, Y float64
+	// Width and height of the image.
+	W, H float64
+	// Image file name.
+	Name string
+}
+
+// String returns the xdot representation of the ellipse.
+func (op *Ellipse) String() string {
+	c := "e"
+	if op.Filled {
+		c = "E"
+	}
+	return fmt.Sprintf("%s %s %s %s %s", c, formatFloat(op.X), formatFloat(op.Y), formatFloat(op.W), formatFloat(op.H))
+}
+
+// String returns the xdot representation of the polygon.
+func (op *Polygon) String() string {
+	c := "p"
+	if op.Filled {
+		c = "P"
+	}
+	return c + " " + formatPoints(op.Points)
+}
+
+// String returns the xdot representation of the polyline.
+func (op *Polyline) String() string {
+	return "L " + formatPoints(op.Points)
+}
+
+// String returns the xdot representation of the B-spline.
+func (op *BSpline) String() string {
+	c := "B"
+	if op.Filled {
+		c = "b"
+	}
+	return c + " " + formatPoints(op.Points)
+}
+
+// String returns the xdot representation of the text.
+func (op *Text) String() string {
+	return fmt.Sprintf("T %s %s %d %s %s", formatFloat(op.X), formatFloat(op.Y), op.Align, formatFloat(op.Width), formatString(op.Text))
+}
+
+// String returns the xdot representation of the font characteristics.
+func (op *FontChars) String() string {
+	return fmt.Sprintf("t %d", op.Flags)
+}
+
+// String returns the xdot representation of the fill colour.
+func (op *FillColor) String() string {
+	return "C " + formatString(op.Color)
+}
+
+// String returns the xdot representation of the pen colour.
+func (op *PenColor) String() string {
+	return "c " + formatString(op.Color)
+}
+
+// String returns the xdot representation of the font.
+func (op *Font) String() string {
+	return fmt.Sprintf("F %s %s", formatFloat(op.Size), formatString(op.Name))
+}
+
+// String returns the xdot representation of the style.
+func (op *Style) String() string {
+	return "S " + formatString(op.Style)
+}
+
+// String returns the xdot representation of the image.
+func (op *Image) String() string {
+	return fmt.Sprintf("I %s %s %s %s %s", formatFloat(op.X), formatFloat(op.Y), formatFloat(op.W), formatFloat(op.H), formatString(op.Name))
+}
+
+// isOp ensures that only drawing operations can be assigned to the Op
+// interface.
+func (*Ellipse) isOp()   {}
+func (*Polygon) isOp()   {}
+func (*Polyline) isOp()  {}
+func (*BSpline) isOp()   {}
+func (*Text) isOp()      {}
+func (*FontChars) isOp() {}
+func (*FillColor) isOp() {}
+func (*PenColor) isOp()  {}
+func (*Font) isOp()      {}
+func (*Style) isOp()     {}
+func (*Image) isOp()     {}
+
+// === [ Decoding ] ============================================================
+
+// Parse parses the given xdot drawing operations. The operations may be
+// double-quoted, as in the value of an attribute.
+func Parse(s string) ([]Op, error) {
+	p := &parser{s: enc.Unquote(s)}
+	var ops []Op
+	for {
+		p.skipSpace()
+		if p.pos == len(p.s) {
+			return ops, nil
+		}
+		op, err := p.op()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		ops = append(ops, op)
+	}
+}
+
+// A parser tracks the position within xdot drawing operations.
+type parser struct {
+	// Drawing operations.
+	s string
+	// Current position.
+	pos int
+}
+
+// op parses a drawing operation.
+func (p *parser) op() (Op, error) {
+	start := p.pos
+	c := p.s[p.pos]
+	p.pos++
+	// Drawing operations are separated by whitespace.
+	if p.pos < len(p.s) && !isSpace(p.s[p.pos]) {
+		p.pos = start
+		return nil, errors.Errorf("invalid drawing operation at offset %d; unknown operation %q", start, p.word())
+	}
+	var (
+		op  Op
+		err error
+	)
+	switch c {
+	case 'E', 'e':
+		e := &Ellipse{Filled: c == 'E'}
+		err = p.floats(&e.X, &e.Y, &e.W, &e.H)
+		op = e
+	case 'P', 'p':
+		poly := &Polygon{Filled: c == 'P'}
+		poly.Points, err = p.points()
+		op = poly
+	case 'L':
+		line := &Polyline{}
+		line.Points, err = p.points()
+		op = line
+	case 'B', 'b':
+		spline := &BSpline{Filled: c == 'b'}
+		spline.Points, err = p.points()
+		op = spline
+	case 'T':
+		text := &Text{}
+		var align int
+		if err = p.floats(&text.X, &text.Y); err != nil {
+			break
+		}
+		if align, err = p.int(); err != nil {
+			break
+		}
+		if align < -1 || align > 1 {
+			err = errors.Errorf("invalid text alignment %d; expected -1, 0 or 1", align)
+			break
+		}
+		text.Align = Align(align)
+		if err = p.floats(&text.Width); err != nil {
+			break
+		}
+		text.Text, err = p.string()
+		op = text
+	case 't':
+		var flags int
+		if flags, err = p.int(); err == nil && flags < 0 {
+			err = errors.Errorf("invalid font characteristics %d", flags)
+		}
+		op = &FontChars{Flags: FontFlags(flags)}
+	case 'C':
+		fill := &FillColor{}
+		fill.Color, err = p.string()
+		op = fill
+	case 'c':
+		pen := &PenColor{}
+		pen.Color, err = p.string()
+		op = pen
+	case 'F':
+		font := &Font{}
+		if err = p.floats(&font.Size); err != nil {
+			break
+		}
+		font.Name, err = p.string()
+		op = font
+	case 'S':
+		style := &Style{}
+		style.Style, err = p.string()
+		op = style
+	case 'I':
+		img := &Image{}
+		if err = p.floats(&img.X, &img.Y, &img.W, &img.H); err != nil {
+			break
+		}
+		img.Name, err = p.string()
+		op = img
+	default:
+		return nil, errors.Errorf("invalid drawing operation at offset %d; unknown operation %q", start, string(c))
+	}
+	if err != nil {
+		return nil, errors.Errorf("invalid drawing operation %q at offset %d; %v", string(c), start, err)
+	}
+	return op, nil
+}
+
+// points parses a point count followed by points.
+func (p *parser) points() ([]Point, error) {
+	n, err := p.int()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if n < 0 {
+		return nil, errors.Errorf("invalid point count %d", n)
+	}
+	var pts []Point
+	for i := 0; i < n; i++ {
+		var pt Point
+		if err := p.floats(&pt.X, &pt.Y); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		pts = append(pts, pt)
+	}
+	return pts, nil
+}
+
+// floats parses the given number of floating-point values.
+func (p *parser) floats(xs ...*float64) error {
+	for _, x := range xs {
+		word := p.word()
+		if word == "" {
+			return errors.New("unexpected end of drawing operations; expected number")
+		}
+		v, err := strconv.ParseFloat(word, 64)
+		if err != nil {
+			return errors.Errorf("invalid number %q", word)
+		}
+		*x = v
+	}
+	return nil
+}
+
+// int parses an integer value.
+func (p *parser) int() (int, error) {
+	word := p.word()
+	if word == "" {
+		return 0, errors.New("unexpected end of drawing operations; expected integer")
+	}
+	v, err := strconv.Atoi(word)
+	if err != nil {
+		return 0, errors.Errorf("invalid integer %q", word)
+	}
+	return v, nil
+}
+
+// string parses a string of the form "n -bytes", where n is the number of
+// bytes.
+func (p *parser) string() (string, error) {
+	n, err := p.int()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	p.skipSpace()
+	if p.pos == len(p.s) || p.s[p.pos] != '-' {
+		return "", errors.New("invalid string; missing '-' prefix")
+	}
+	p.pos++
+	if n < 0 || p.pos+n > len(p.s) {
+		return "", errors.Errorf("invalid string length %d", n)
+	}
+	s := p.s[p.pos : p.pos+n]
+	p.pos += n
+	return s, nil
+}
+
+// word returns the next sequence of non-whitespace characters.
+func (p *parser) word() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && !isSpace(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// skipSpace skips whitespace characters.
+func (p *parser) skipSpace() {
+	for p.pos < len(p.s) && isSpace(p.s[p.pos]) {
+		p.pos++
+	}
+}
+
+// isSpace reports whether c is a whitespace character.
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r':
+		return true
+	}
+	return false
+}
+
+// === [ Encoding ] ============================================================
+
+// Format returns the xdot representation of the given drawing operations.
+func Format(ops []Op) string {
+	if len(ops) == 0 {
+		return ""
+	}
+	ss := make([]string, len(ops))
+	for i, op := range ops {
+		ss[i] = op.String()
+	}
+	// Graphviz terminates each operation by a space.
+	return strings.Join(ss, " ") + " "
+}
+
+// Attr returns an attribute with the given key, holding the given drawing
+// operations.
+func Attr(key string, ops []Op) *ast.Attr {
+	return &ast.Attr{Key: key, Val: enc.Quote(Format(ops))}
+}
+
+// formatPoints returns the xdot representation of the given points, prefixed
+// by their count.
+func formatPoints(pts []Point) string {
+	ss := make([]string, 0, 1+2*len(pts))
+	ss = append(ss, strconv.Itoa(len(pts)))
+	for _, pt := range pts {
+		ss = append(ss, formatFloat(pt.X), formatFloat(pt.Y))
+	}
+	return strings.Join(ss, " ")
+}
+
+// formatString returns the xdot representation of the given string, prefixed
+// by its length in bytes.
+func formatString(s string) string {
+	return fmt.Sprintf("%d -%s", len(s), s)
+}
+
+// formatFloat returns the string representation of x.
+func formatFloat(x float64) string {
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
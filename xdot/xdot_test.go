@@ -0,0 +1,152 @@
+package xdot_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/graphism/dot"
+	"github.com/graphism/dot/internal/enc"
+	"github.com/graphism/dot/xdot"
+)
+
+func TestParse(t *testing.T) {
+	golden := []struct {
+		in   string
+		want []xdot.Op
+	}{
+		{
+			in: `c 7 -#000000 e 27 90 27 18 `,
+			want: []xdot.Op{
+				&xdot.PenColor{Color: "#000000"},
+				&xdot.Ellipse{X: 27, Y: 90, W: 27, H: 18},
+			},
+		},
+		{
+			in: `"F 14 11 -Times-Roman t 3 T 27 14.3 -1 42 9 -\"b\" | c d "`,
+			want: []xdot.Op{
+				&xdot.Font{Size: 14, Name: "Times-Roman"},
+				&xdot.FontChars{Flags: xdot.FontBold | xdot.FontItalic},
+				&xdot.Text{X: 27, Y: 14.3, Align: xdot.AlignLeft, Width: 42, Text: `"b" | c d`},
+			},
+		},
+		{
+			in: `S 6 -filled C 28 -[0;0.000:#ff0000;1.000:blue] P 3 30.5 46.1 27 36.1 23.5 46.1`,
+			want: []xdot.Op{
+				&xdot.Style{Style: "filled"},
+				&xdot.FillColor{Color: "[0;0.000:#ff0000;1.000:blue]"},
+				&xdot.Polygon{Filled: true, Points: []xdot.Point{{X: 30.5, Y: 46.1}, {X: 27, Y: 36.1}, {X: 23.5, Y: 46.1}}},
+			},
+		},
+		{
+			in: `B 4 27 71.7 27 63.98 27 54.71 27 46.11 b 1 0 0 L 2 -1 -2 3 4 p 0 I 76 0 54 36 9 -image.png`,
+			want: []xdot.Op{
+				&xdot.BSpline{Points: []xdot.Point{{X: 27, Y: 71.7}, {X: 27, Y: 63.98}, {X: 27, Y: 54.71}, {X: 27, Y: 46.11}}},
+				&xdot.BSpline{Filled: true, Points: []xdot.Point{{X: 0, Y: 0}}},
+				&xdot.Polyline{Points: []xdot.Point{{X: -1, Y: -2}, {X: 3, Y: 4}}},
+				&xdot.Polygon{},
+				&xdot.Image{X: 76, W: 54, H: 36, Name: "image.png"},
+			},
+		},
+		{
+			in: ``,
+		},
+	}
+	for _, g := range golden {
+		got, err := xdot.Parse(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to parse drawing operations; %v", g.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, g.want) {
+			t.Errorf("%q: drawing operations mismatch; expected %v, got %v", g.in, g.want, got)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		{
+			in:   `X 1 2`,
+			want: `invalid drawing operation at offset 0; unknown operation "X"`,
+		},
+		{
+			in:   `e 1 2 3`,
+			want: `invalid drawing operation "e" at offset 0; unexpected end of drawing operations; expected number`,
+		},
+		{
+			in:   `c 7 -#00`,
+			want: `invalid drawing operation "c" at offset 0; invalid string length 7`,
+		},
+		{
+			in:   `c 7 #000000`,
+			want: `invalid drawing operation "c" at offset 0; invalid string; missing '-' prefix`,
+		},
+		{
+			in:   `e 1 2 3 4 P 2 1 x`,
+			want: `invalid drawing operation "P" at offset 10; invalid number "x"`,
+		},
+		{
+			in:   `T 0 0 2 10 1 -a`,
+			want: `invalid drawing operation "T" at offset 0; invalid text alignment 2; expected -1, 0 or 1`,
+		},
+		{
+			in:   `ee 1 2 3 4`,
+			want: `invalid drawing operation at offset 0; unknown operation "ee"`,
+		},
+	}
+	for _, g := range golden {
+		_, err := xdot.Parse(g.in)
+		if err == nil {
+			t.Errorf("%q: expected error, got nil", g.in)
+			continue
+		}
+		if got := err.Error(); got != g.want {
+			t.Errorf("%q: error mismatch; expected `%s`, got `%s`", g.in, g.want, got)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	file, err := dot.ParseFile("testdata/graph.xdot")
+	if err != nil {
+		t.Fatalf("unable to parse file; %v", err)
+	}
+	g, err := dot.Resolve(file.Graphs[0])
+	if err != nil {
+		t.Fatalf("unable to resolve graph; %v", err)
+	}
+	attrs := []dot.Attrs{g.Attrs}
+	for _, n := range g.Nodes {
+		attrs = append(attrs, n.Attrs)
+	}
+	for _, e := range g.Edges {
+		attrs = append(attrs, e.Attrs)
+	}
+	count := 0
+	for _, as := range attrs {
+		for _, a := range as {
+			if !xdot.IsKey(a.Key) {
+				continue
+			}
+			count++
+			ops, err := xdot.Parse(a.Val)
+			if err != nil {
+				t.Errorf("%s=%s: unable to parse drawing operations; %v", a.Key, a.Val, err)
+				continue
+			}
+			want := enc.Unquote(a.Val)
+			if got := xdot.Format(ops); got != want {
+				t.Errorf("%s: drawing operations mismatch; expected %q, got %q", a.Key, want, got)
+			}
+			if got := xdot.Attr(a.Key, ops); got.Val != a.Val {
+				t.Errorf("%s: attribute value mismatch; expected %s, got %s", a.Key, a.Val, got.Val)
+			}
+		}
+	}
+	if count != 10 {
+		t.Errorf("drawing attribute count mismatch; expected 10, got %d", count)
+	}
+}